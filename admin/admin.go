@@ -0,0 +1,263 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/bot"
+	"go.uber.org/zap"
+)
+
+// Server is the operator API of the running monster AI:
+//
+//	GET    /status
+//	POST   /pause, /resume
+//	GET    /bots
+//	GET    /bots/ID
+//	GET    /bots/ID/trace
+//	PUT    /bots/ID/override?ticks=N     (JSON bot.BotOverride, N ticks or until removed)
+//	DELETE /bots/ID/override
+//	GET    /levels/disabled
+//	PUT    /levels/LEVEL/disabled?ticks=N
+//	DELETE /levels/LEVEL/disabled
+type Server struct {
+	Dispatcher *bot.BotDispatcher
+	// Requests need the "Authorization: Bearer TOKEN" header when set
+	Token  string
+	Logger *zap.SugaredLogger
+}
+
+type status struct {
+	Tick   int32 `json:"tick"`
+	Paused bool  `json:"paused"`
+}
+
+func New(dispatcher *bot.BotDispatcher, token string, logger *zap.SugaredLogger) *Server {
+	return &Server{
+		Dispatcher: dispatcher,
+		Token:      token,
+		Logger:     logger,
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/pause", s.handlePause)
+	mux.HandleFunc("/resume", s.handlePause)
+	mux.HandleFunc("/bots", s.handleBots)
+	mux.HandleFunc("/bots/", s.handleBot)
+	mux.HandleFunc("/levels/", s.handleLevels)
+	return s.withAuth(mux)
+}
+
+// Serve serves the API in the background
+func (s *Server) Serve(addr string) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		s.Logger.Infow("Serving admin API",
+			"addr", addr,
+		)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.Logger.Errorw("Admin API failed",
+				zap.Error(err),
+			)
+		}
+	}()
+	return server
+}
+
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	paused := r.URL.Path == "/pause"
+	if s.Dispatcher.SetPaused(paused) {
+		s.Logger.Warnw("Monster AI paused by operator",
+			"paused", paused,
+		)
+	}
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) status() status {
+	return status{
+		Tick:   s.Dispatcher.Tick(),
+		Paused: s.Dispatcher.IsPaused(),
+	}
+}
+
+func (s *Server) handleBots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Dispatcher.BotSnapshots())
+}
+
+// handleBot serves /bots/ID, /bots/ID/trace and /bots/ID/override
+func (s *Server) handleBot(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/bots/"), "/")
+	monsterId := parts[0]
+	resource := ""
+	if len(parts) > 1 {
+		resource = parts[1]
+	}
+	if monsterId == "" || len(parts) > 2 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	switch {
+	case resource == "" && r.Method == http.MethodGet:
+		snapshot, found := s.Dispatcher.BotSnapshot(monsterId)
+		if !found {
+			writeError(w, http.StatusNotFound, bot.ErrBotNotTracked.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, snapshot)
+	case resource == "trace" && r.Method == http.MethodGet:
+		trace := s.Dispatcher.LastTrace(monsterId)
+		if trace == nil {
+			writeError(w, http.StatusNotFound, "no decision trace (enable it with the debug override)")
+			return
+		}
+		writeJSON(w, http.StatusOK, trace)
+	case resource == "override" && r.Method == http.MethodPut:
+		override := bot.BotOverride{}
+		if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		untilTick, err := s.untilTick(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		override.UntilTick = untilTick
+		if err := s.Dispatcher.SetBotOverride(monsterId, override); errors.Is(err, bot.ErrBotNotTracked) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.Logger.Warnw("Bot override set by operator",
+			"monsterId", monsterId,
+			"override", override,
+		)
+		writeJSON(w, http.StatusOK, override)
+	case resource == "override" && r.Method == http.MethodDelete:
+		if !s.Dispatcher.RemoveBotOverride(monsterId) {
+			writeError(w, http.StatusNotFound, "monster has no override")
+			return
+		}
+		s.Logger.Warnw("Bot override removed by operator",
+			"monsterId", monsterId,
+		)
+		w.WriteHeader(http.StatusNoContent)
+	case resource == "" || resource == "trace" || resource == "override":
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleLevels serves /levels/disabled and /levels/LEVEL/disabled
+func (s *Server) handleLevels(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/levels/"), "/")
+	if len(parts) == 1 && parts[0] == "disabled" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Dispatcher.DisabledLevels())
+		return
+	}
+	if len(parts) != 2 || parts[1] != "disabled" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	level, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid level")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		untilTick, err := s.untilTick(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.Dispatcher.SetLevelDisabled(int32(level), untilTick)
+		s.Logger.Warnw("Level disabled by operator",
+			"mapLevel", level,
+			"untilTick", untilTick,
+		)
+		writeJSON(w, http.StatusOK, s.Dispatcher.DisabledLevels())
+	case http.MethodDelete:
+		if !s.Dispatcher.EnableLevel(int32(level)) {
+			writeError(w, http.StatusNotFound, "level is not disabled")
+			return
+		}
+		s.Logger.Warnw("Level enabled by operator",
+			"mapLevel", level,
+		)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// untilTick converts the optional ticks parameter to the last tick of the override (0 for no limit)
+func (s *Server) untilTick(r *http.Request) (int32, error) {
+	ticks := r.URL.Query().Get("ticks")
+	if ticks == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(ticks)
+	if err != nil || n < 1 {
+		return 0, errors.New("ticks must be a positive number")
+	}
+	return s.Dispatcher.Tick() + int32(n), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"code":    status,
+		"message": message,
+	})
+}
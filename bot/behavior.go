@@ -0,0 +1,267 @@
+package bot
+
+import (
+	"math"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// Behavior is the high level state of the monster
+// It is persisted in BotState across ticks, re-evaluated before bestSkill(),
+// and re-weights (or constrains) the one-shot skill evaluation
+type Behavior string
+
+const (
+	BehaviorIdle          Behavior = "idle"
+	BehaviorPatrol        Behavior = "patrol"
+	BehaviorEngage        Behavior = "engage"
+	BehaviorFlee          Behavior = "flee"
+	BehaviorKite          Behavior = "kite"
+	BehaviorRegroup       Behavior = "regroup"
+	BehaviorGuard         Behavior = "guard"
+	BehaviorReturnToSpawn Behavior = "return-to-spawn"
+	// Walks home without fighting when the leash breaks and regenerates there
+	BehaviorEvade Behavior = "evade"
+)
+
+// All behaviors (operators can force any of them, see BotOverride)
+var behaviors = []Behavior{BehaviorIdle, BehaviorPatrol, BehaviorEngage, BehaviorFlee, BehaviorKite, BehaviorRegroup, BehaviorGuard, BehaviorReturnToSpawn, BehaviorEvade}
+
+// Behaviors monsters fall back to when there is nothing to fight (see Config.IdleBehavior)
+var idleBehaviors = []Behavior{BehaviorIdle, BehaviorPatrol, BehaviorGuard}
+
+const RejectionBehavior = "rejected by behavior"
+
+func (b *Bot) updateBehavior() {
+	b.updateTicksWithoutHostiles()
+	previous := b.BotState.Behavior
+	next := b.nextBehavior(previous)
+	if b.Override.Behavior != "" {
+		next = b.Override.Behavior
+	}
+	if next != previous {
+		b.Logger.Infow("Behavior changed",
+			"previousBehavior", previous,
+			"behavior", next,
+			"previousBehaviorTicks", b.BotState.BehaviorTicks,
+		)
+		b.BotState.Behavior = next
+		b.BotState.BehaviorTicks = 0
+		b.BotState.BehaviorPosition = nil
+		b.BotState.RestTicks = 0
+		if previous == BehaviorEvade {
+			// Idle monsters may wander for another EvadeIdleTicks
+			b.BotState.TicksWithoutHostiles = 0
+		}
+		if next == BehaviorGuard {
+			b.BotState.BehaviorPosition = b.guardPost()
+		}
+	} else {
+		b.BotState.BehaviorTicks++
+	}
+	b.updateBehaviorPosition()
+}
+
+func (b *Bot) nextBehavior(current Behavior) Behavior {
+	t := b.Tuning.Behavior
+	distances := b.calculateDistancesForPosition(b.Details.Position)
+	vitals := b.getCurrentVitalsRatio()
+
+	// Evading monsters ignore hostiles until they are home
+	if current == BehaviorEvade && !b.homeReached() {
+		return BehaviorEvade
+	}
+	if b.leashBroken() {
+		b.Logger.Infow("Leash broken",
+			"home", b.homePosition(),
+			"leashRadius", b.Config.LeashRadius,
+		)
+		return BehaviorEvade
+	}
+
+	// Disengage only when hostiles get further than they were when engaging
+	engageDistance := t.EngageDistance
+	if isFighting(current) {
+		engageDistance = t.DisengageDistance
+	}
+	inCombat := b.Details.Monster.LastDamageTaken <= t.CombatTicks
+	// Guardians engage players getting close to the guarded object even when they are far from the monster
+	if inCombat || distances.DistanceToClosestHostile <= engageDistance || len(b.guardIntruders()) > 0 {
+		fleeVitals := b.Config.FleeVitals
+		if current == BehaviorFlee {
+			fleeVitals += t.RecoverVitalsMargin
+		}
+		if vitals < fleeVitals {
+			return BehaviorFlee
+		}
+		// Sharp drops mean the monster is being focused, keep fleeing for a few ticks to get out of range
+		if b.Config.FleeLifeDrop > 0 && b.lifeDrop() >= b.Config.FleeLifeDrop {
+			b.Logger.Infow("Sharp life drop",
+				"lifeDrop", b.lifeDrop(),
+				"fleeLifeDrop", b.Config.FleeLifeDrop,
+			)
+			return BehaviorFlee
+		}
+		if current == BehaviorFlee && b.BotState.BehaviorTicks < t.FleeMinTicks {
+			return BehaviorFlee
+		}
+		friendlyInSight := distances.DistanceToClosestFriendly < math.MaxInt32-1
+		if friendlyInSight && vitals < t.RegroupVitals && distances.DistanceToClosestFriendly > t.RegroupDistance {
+			return BehaviorRegroup
+		}
+		if b.kiteRange() > 0 {
+			return BehaviorKite
+		}
+		return BehaviorEngage
+	}
+
+	if current == BehaviorEvade && b.isResting() {
+		return BehaviorEvade
+	}
+	// Patrolling and guarding monsters stay where their idle behavior keeps them
+	if t.EvadeIdleTicks > 0 && b.BotState.TicksWithoutHostiles >= t.EvadeIdleTicks && !b.homeReached() && current != BehaviorPatrol && current != BehaviorGuard {
+		return BehaviorEvade
+	}
+	home := b.homePosition()
+	if home != nil {
+		distanceToHome := manhattanDistance(*b.Details.Position, *home)
+		if current == BehaviorReturnToSpawn && distanceToHome > t.SpawnReachedDistance {
+			return BehaviorReturnToSpawn
+		}
+		if isFighting(current) && distanceToHome > t.ReturnToSpawnDistance {
+			return BehaviorReturnToSpawn
+		}
+	}
+	if current == BehaviorGuard && b.Config.IdleBehavior == BehaviorGuard {
+		return BehaviorGuard
+	}
+	if b.Config.IdleBehavior == "" {
+		return BehaviorIdle
+	}
+	return b.Config.IdleBehavior
+}
+
+func isFighting(behavior Behavior) bool {
+	return behavior == BehaviorEngage || behavior == BehaviorFlee || behavior == BehaviorKite || behavior == BehaviorRegroup
+}
+
+// Sets the position the behavior moves to (via BotState.TargetPosition) or away from
+func (b *Bot) updateBehaviorPosition() {
+	b.BotState.Danger = nil
+	b.BotState.KiteRange = 0
+	switch b.BotState.Behavior {
+	case BehaviorReturnToSpawn, BehaviorEvade:
+		b.BotState.BehaviorPosition = b.homePosition()
+	case BehaviorRegroup:
+		b.BotState.BehaviorPosition = b.closestPosition(b.BotState.Objects.Friendly)
+	case BehaviorFlee:
+		b.BotState.BehaviorPosition = b.closestPosition(b.BotState.Objects.Hostile)
+		b.BotState.Danger = b.dangerMap()
+	case BehaviorKite:
+		b.BotState.BehaviorPosition = b.closestPosition(b.BotState.Objects.Hostile)
+		b.BotState.Danger = b.dangerMap()
+		b.BotState.KiteRange = b.supportKiteRange(b.kiteRange())
+	}
+	switch b.BotState.Behavior {
+	case BehaviorReturnToSpawn, BehaviorEvade, BehaviorRegroup, BehaviorGuard:
+		if b.BotState.BehaviorPosition != nil {
+			b.BotState.TargetPosition = b.BotState.BehaviorPosition
+			b.BotState.TargetPositionTimeout = 1
+		}
+	case BehaviorFlee:
+		// Run to the safest tile (or stay when already there)
+		b.BotState.TargetPosition = b.safestPosition(b.BotState.Danger)
+		b.BotState.TargetPositionTimeout = 1
+	}
+	if b.BotState.Behavior == BehaviorEvade && b.homeReached() {
+		b.BotState.RestTicks++
+	}
+}
+
+// behaviorRejection returns a reason when the current behavior doesn't allow the skill + target combination
+func (b *Bot) behaviorRejection(skill swagger.DungeonsandtrollsSkill, target MapObject) string {
+	if b.BotState.Behavior == BehaviorEvade && (target.Type == MapObjectTypePlayer || target.Type == MapObjectTypeMonster) && b.IsHostile(target) {
+		return RejectionBehavior
+	}
+	anchor := b.BotState.BehaviorPosition
+	if !skill.CasterEffects.Flags.Movement || anchor == nil {
+		return ""
+	}
+	targetPosition := b.getSkillTargetPosition(&skill, &target)
+	if targetPosition == nil {
+		return ""
+	}
+	distanceBefore := manhattanDistance(*b.Details.Position, *anchor)
+	distanceAfter := manhattanDistance(*targetPosition, *anchor)
+	switch b.BotState.Behavior {
+	case BehaviorFlee:
+		// Never move closer to the closest hostile
+		if distanceAfter < distanceBefore {
+			return RejectionBehavior
+		}
+	case BehaviorKite:
+		// Never step inside the kite range
+		if distanceAfter < b.BotState.KiteRange && distanceAfter < distanceBefore {
+			return RejectionBehavior
+		}
+	case BehaviorGuard:
+		if distanceAfter > b.Tuning.Behavior.GuardRadius && distanceAfter > distanceBefore {
+			return RejectionBehavior
+		}
+	case BehaviorReturnToSpawn, BehaviorEvade:
+		if distanceAfter > distanceBefore {
+			return RejectionBehavior
+		}
+	}
+	return ""
+}
+
+func (b *Bot) behaviorWeights() BehaviorWeights {
+	weights := b.Tuning.Behavior.Weights
+	switch b.BotState.Behavior {
+	case BehaviorPatrol:
+		return weights.Patrol
+	case BehaviorEngage:
+		return weights.Engage
+	case BehaviorFlee:
+		return weights.Flee
+	case BehaviorKite:
+		return weights.Kite
+	case BehaviorRegroup:
+		return weights.Regroup
+	case BehaviorGuard:
+		return weights.Guard
+	case BehaviorReturnToSpawn:
+		return weights.ReturnToSpawn
+	case BehaviorEvade:
+		return weights.Evade
+	}
+	return weights.Idle
+}
+
+// Current vitals score relative to full vitals (0-1)
+func (b *Bot) getCurrentVitalsRatio() float32 {
+	maxVitals := b.scoreVitalsFunc(1, 1, 1)
+	if maxVitals <= 0 {
+		return 1
+	}
+	return b.getCurrentVitals() / maxVitals
+}
+
+// Closest reachable position of the objects (other than self)
+func (b *Bot) closestPosition(objects []MapObject) *swagger.DungeonsandtrollsPosition {
+	var closest *swagger.DungeonsandtrollsPosition
+	closestDistance := math.MaxInt32
+	for _, object := range objects {
+		if object.GetId() == b.BotState.Self.GetId() {
+			continue
+		}
+		tileInfo, found := b.BotState.MapExtended.Lookup(*object.MapObjects.Position)
+		if !found || tileInfo.distance >= closestDistance {
+			continue
+		}
+		closest = object.MapObjects.Position
+		closestDistance = tileInfo.distance
+	}
+	return closest
+}
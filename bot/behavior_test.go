@@ -0,0 +1,286 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// setVitals sets life, stamina and mana of the monster to the ratio of the maximum
+func setVitals(monster *swagger.DungeonsandtrollsMonster, ratio float32) {
+	monster.LifePercentage = ratio
+	monster.Attributes.Life = ratio * monster.MaxAttributes.Life
+	monster.Attributes.Stamina = ratio * monster.MaxAttributes.Stamina
+	monster.Attributes.Mana = ratio * monster.MaxAttributes.Mana
+}
+
+// fleeingMonster puts the player next to monster-1 with the vitals ratio
+func fleeingMonster(ratio float32) func(state *swagger.DungeonsandtrollsGameState) {
+	return func(state *swagger.DungeonsandtrollsGameState) {
+		moveCharacter(state, "player-1", makePosition(8, 1))
+		setVitals(findMonster(state, "monster-1"), ratio)
+	}
+}
+
+func TestBehaviorTransitions(t *testing.T) {
+	tests := []struct {
+		name      string
+		monsterId string
+		profiles  string
+		// Changes of the arena in consecutive ticks
+		ticks    []func(state *swagger.DungeonsandtrollsGameState)
+		behavior Behavior
+	}{
+		{
+			name:      "idle without hostiles in sight",
+			monsterId: "monster-1",
+			ticks:     []func(state *swagger.DungeonsandtrollsGameState){nil},
+			behavior:  BehaviorIdle,
+		},
+		{
+			name:      "idle behavior from profile",
+			monsterId: "monster-1",
+			profiles:  `{"berserker": {"idleBehavior": "patrol"}}`,
+			ticks:     []func(state *swagger.DungeonsandtrollsGameState){nil},
+			behavior:  BehaviorPatrol,
+		},
+		{
+			name:      "engage hostile within engage distance",
+			monsterId: "monster-1",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){func(state *swagger.DungeonsandtrollsGameState) {
+				moveCharacter(state, "player-1", makePosition(8, 1))
+			}},
+			behavior: BehaviorEngage,
+		},
+		{
+			name:      "engage when damaged",
+			monsterId: "monster-1",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){func(state *swagger.DungeonsandtrollsGameState) {
+				findMonster(state, "monster-1").LastDamageTaken = 0
+			}},
+			behavior: BehaviorEngage,
+		},
+		{
+			name:      "ranged monsters kite",
+			monsterId: "monster-2",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){func(state *swagger.DungeonsandtrollsGameState) {
+				moveCharacter(state, "player-1", makePosition(8, 6))
+			}},
+			behavior: BehaviorKite,
+		},
+		{
+			name:      "flee on low vitals",
+			monsterId: "monster-1",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){func(state *swagger.DungeonsandtrollsGameState) {
+				moveCharacter(state, "player-1", makePosition(8, 1))
+				setVitals(findMonster(state, "monster-1"), 0.01)
+			}},
+			behavior: BehaviorFlee,
+		},
+		{
+			name:      "flee on sharp life drop",
+			monsterId: "monster-1",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(8, 1))
+				},
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(8, 1))
+					monster := findMonster(state, "monster-1")
+					monster.Attributes.Life = monster.MaxAttributes.Life * 0.7
+					monster.LifePercentage = 0.7
+				},
+			},
+			behavior: BehaviorFlee,
+		},
+		{
+			name:      "regroup with friendly far away",
+			monsterId: "monster-1",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){func(state *swagger.DungeonsandtrollsGameState) {
+				moveCharacter(state, "player-1", makePosition(8, 1))
+				setVitals(findMonster(state, "monster-1"), 0.05)
+			}},
+			behavior: BehaviorRegroup,
+		},
+		{
+			name:      "keep fleeing until vitals recover above the margin",
+			monsterId: "monster-1",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				fleeingMonster(0.01),
+				// Vitals above FleeVitals but below FleeVitals + RecoverVitalsMargin after FleeMinTicks
+				fleeingMonster(0.05),
+				fleeingMonster(0.05),
+				fleeingMonster(0.05),
+			},
+			behavior: BehaviorFlee,
+		},
+		{
+			name:      "stop fleeing when vitals recover",
+			monsterId: "monster-1",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				fleeingMonster(0.01),
+				fleeingMonster(0.2),
+				fleeingMonster(0.2),
+				fleeingMonster(0.2),
+			},
+			behavior: BehaviorEngage,
+		},
+		{
+			name:      "flee for FleeMinTicks",
+			monsterId: "monster-1",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				fleeingMonster(0.01),
+				fleeingMonster(0.2),
+			},
+			behavior: BehaviorFlee,
+		},
+		{
+			// Engaged at distance 3, the hostile is then further than EngageDistance (10 tiles) but within DisengageDistance
+			name:      "disengage only beyond disengage distance",
+			monsterId: "monster-1",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(8, 1))
+				},
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(5, 7))
+				},
+			},
+			behavior: BehaviorEngage,
+		},
+		{
+			name:      "disengage beyond disengage distance",
+			monsterId: "monster-1",
+			profiles:  `{"berserker": {"leashRadius": 0}}`,
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(8, 1))
+				},
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(5, 7))
+					moveCharacter(state, "monster-1", makePosition(12, 1))
+				},
+			},
+			behavior: BehaviorIdle,
+		},
+		{
+			name:      "return to spawn after chasing too far",
+			monsterId: "monster-1",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(8, 1))
+				},
+				func(state *swagger.DungeonsandtrollsGameState) {
+					// Out of sight behind the wall, 11 tiles from home
+					moveCharacter(state, "monster-1", makePosition(1, 4))
+					moveCharacter(state, "player-1", makePosition(12, 7))
+				},
+			},
+			behavior: BehaviorReturnToSpawn,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newTestDispatcher()
+			if test.profiles != "" {
+				profiles, err := ParseConfigProfiles([]byte(test.profiles))
+				if err != nil {
+					t.Fatal(err)
+				}
+				d.Profiles = profiles
+			}
+			for i, change := range test.ticks {
+				state := loadArena(t)
+				state.Tick = int32(i + 1)
+				if change != nil {
+					change(state)
+				}
+				if err := d.HandleTick(state, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			bot := d.Bots[test.monsterId]
+			if bot.BotState.Behavior != test.behavior {
+				t.Errorf("expected behavior %s, got %s (vitals %v)", test.behavior, bot.BotState.Behavior, bot.getCurrentVitalsRatio())
+			}
+		})
+	}
+}
+
+func TestBehaviorTicksAndOverride(t *testing.T) {
+	d := newTestDispatcher()
+	for tick := int32(1); tick <= 3; tick++ {
+		state := loadArena(t)
+		state.Tick = tick
+		if err := d.HandleTick(state, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bot := d.Bots["monster-1"]
+	if bot.BotState.Behavior != BehaviorIdle || bot.BotState.BehaviorTicks != 2 {
+		t.Errorf("expected idle for 2 ticks, got %s for %d", bot.BotState.Behavior, bot.BotState.BehaviorTicks)
+	}
+	if err := d.SetBotOverride("monster-1", BotOverride{Behavior: BehaviorGuard}); err != nil {
+		t.Fatal(err)
+	}
+	state := loadArena(t)
+	state.Tick = 4
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// Behavior changes reset the counter and set the guard post
+	if bot.BotState.Behavior != BehaviorGuard || bot.BotState.BehaviorTicks != 0 || bot.BotState.BehaviorPosition == nil {
+		t.Errorf("expected forced guard with a post, got %s for %d (post %v)", bot.BotState.Behavior, bot.BotState.BehaviorTicks, bot.BotState.BehaviorPosition)
+	}
+}
+
+func TestBehaviorRejection(t *testing.T) {
+	d := newTestDispatcher()
+	state := loadArena(t)
+	moveCharacter(state, "player-1", makePosition(8, 1))
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	bot := d.Bots["monster-1"]
+	player := bot.BotState.Objects.Players[0]
+	move := getDefaultMoveSkill()
+	// Monster at (10, 2), player at (8, 1)
+	tests := []struct {
+		name     string
+		behavior Behavior
+		anchor   swagger.DungeonsandtrollsPosition
+		kite     int32
+		target   swagger.DungeonsandtrollsPosition
+		rejected bool
+	}{
+		{"flee away", BehaviorFlee, makePosition(8, 1), 0, makePosition(11, 2), false},
+		{"flee closer", BehaviorFlee, makePosition(8, 1), 0, makePosition(9, 2), true},
+		{"kite outside range", BehaviorKite, makePosition(8, 1), 5, makePosition(11, 2), false},
+		{"kite inside range", BehaviorKite, makePosition(8, 1), 5, makePosition(9, 2), true},
+		{"guard within radius", BehaviorGuard, makePosition(10, 2), 0, makePosition(12, 2), false},
+		{"guard leaving radius", BehaviorGuard, makePosition(10, 5), 0, makePosition(10, 1), true},
+		{"return towards spawn", BehaviorReturnToSpawn, makePosition(12, 2), 0, makePosition(11, 2), false},
+		{"return away from spawn", BehaviorReturnToSpawn, makePosition(12, 2), 0, makePosition(9, 2), true},
+		{"engage anywhere", BehaviorEngage, makePosition(8, 1), 0, makePosition(9, 2), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			anchor := test.anchor
+			bot.BotState.Behavior = test.behavior
+			bot.BotState.BehaviorPosition = &anchor
+			bot.BotState.KiteRange = test.kite
+			position := test.target
+			target := MapObject{Type: MapObjectTypeEmpty, MapObjects: swagger.DungeonsandtrollsMapObjects{Position: &position, IsFree: true}}
+			if rejected := bot.behaviorRejection(move, target) != ""; rejected != test.rejected {
+				t.Errorf("expected rejected %v, got %v", test.rejected, rejected)
+			}
+		})
+	}
+	// Evading monsters don't attack
+	bot.BotState.Behavior = BehaviorEvade
+	slash := getAllSkills(bot.Details.Monster.EquippedItems)[0]
+	if bot.behaviorRejection(slash, player) != RejectionBehavior {
+		t.Error("evading monster attacks")
+	}
+}
@@ -2,22 +2,53 @@ package bot
 
 import (
 	"math/rand"
+	"time"
 
 	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/pathfinding"
 	"go.uber.org/zap"
 )
 
 type BotState struct {
-	Objects     MapObjectsByCategory
-	MapExtended map[swagger.DungeonsandtrollsPosition]MapCellExt
-	Self        MapObject
-	Yells       []string
+	Objects MapObjectsByCategory
+	// Distances and line of sight from the monster position (see MapView)
+	MapExtended MapView
+	// Cheapest paths from the monster position (see Tuning.Pathfinding)
+	Paths *pathfinding.Tree
+	Self  MapObject
+	Yells []string
+
+	Behavior      Behavior
+	BehaviorTicks int
+	// Guard post, spawn, closest friendly, or closest hostile (when fleeing or kiting) depending on behavior
+	BehaviorPosition *swagger.DungeonsandtrollsPosition
+	// Set only while fleeing or kiting (see danger.go)
+	Danger    *DangerMap
+	KiteRange int32
+
+	// Position where the monster was first seen (see rememberHome)
+	Home      *swagger.DungeonsandtrollsPosition
+	HomeLevel int32
+	// Ticks without hostiles in line of sight and ticks spent resting at home while evading
+	TicksWithoutHostiles int
+	RestTicks            int
+	// Object protected by guardians (see Config.GuardObject)
+	Guarded *GuardedObject
+	// Waypoints of patrolling monsters starting at home (see patrolRoute)
+	PatrolRoute []swagger.DungeonsandtrollsPosition
+	PatrolIndex int
 
-	State                 string
 	TargetPosition        *swagger.DungeonsandtrollsPosition
 	TargetPositionTimeout int
 
 	DefaultMovePenalty int
+
+	// Threat of hostiles by ID (see threat.go)
+	Threat ThreatTable
+	// Allies by ID rated for healing and buffs (only for monsters with support skills, see support.go)
+	Triage TriageTable
+	// Longest range of healing and buff skills (0 without triage)
+	SupportRange int32
 	// TargetObject   swagger.DungeonsandtrollsMapObjects
 	// Target         swagger.DungeonsandtrollsMonster
 }
@@ -29,15 +60,45 @@ type Bot struct {
 	MonsterId string
 
 	Config Config
+	Tuning *Tuning
 
 	BotState  BotState
 	GameState *swagger.DungeonsandtrollsGameState
 	Details   MonsterDetails
+	// Shared by all bots on the level in the current tick
+	LevelCache  *LevelCache
+	Coordinator *Coordinator
+	// Commands must be ready before the deadline (zero for no deadline)
+	Deadline time.Time
+	// Seeded by the dispatcher from the tick seed and the monster ID (see botSeed)
+	Rand *rand.Rand
+	// Fixed number of planner expansions instead of the time budget (see planSkillSequence)
+	PlannerExpansions int
+	// Evaluation of virtual bots stops at the deadline (see planningExpired)
+	planDeadline time.Time
+	// Command produced in the previous tick (re-issued by RunDegraded)
+	LastCommand *swagger.DungeonsandtrollsCommandsBatch
+
+	Stats BotStats
+	// Ticks since the monster was last seen in the game state (see collectBots)
+	MissingTicks int
 
 	PrevBotState  BotState
 	PrevGameState *swagger.DungeonsandtrollsGameState
 	PrevDetails   MonsterDetails
 
+	// Set by operators, applied every tick (see BotOverride)
+	Override BotOverride
+	Disabled bool
+	// State after the last run for the admin API and delivery of the last command (guarded by BotDispatcher.BotsLock)
+	Snapshot BotSnapshot
+	Delivery DeliveryStatus
+
+	// Debug enables decision traces
+	Debug     bool
+	Trace     *DecisionTrace
+	LastTrace *DecisionTrace
+
 	Logger      *zap.SugaredLogger
 	Environment string
 }
@@ -45,6 +106,10 @@ type Bot struct {
 func (b *Bot) Run() *swagger.DungeonsandtrollsCommandsBatch {
 	b.BotState.Self = NewMonsterMapObject(*b.Details.MapObjects, b.Details.Index)
 	b.BotState.Yells = []string{}
+	b.Trace = nil
+	if b.Debug {
+		b.Trace = b.newDecisionTrace()
+	}
 	monster := b.Details.Monster
 	// monsterTileObjects := b.Details.MapObjects
 	level := b.Details.Level
@@ -53,14 +118,17 @@ func (b *Bot) Run() *swagger.DungeonsandtrollsCommandsBatch {
 	if monster.Algorithm == "none" {
 		b.Logger.Warnw("Skipping monster with algorithm 'none'")
 		b.addYell("I'm a chest ... I think")
+		b.Trace.SetFallback("skipped: algorithm none")
 		return nil
 	}
 	if monster.Attributes.Life <= 0 {
 		b.Logger.Warnw("Skipping DEAD monster")
+		b.Trace.SetFallback("skipped: dead")
 		return nil
 	}
 	if monster.Stun.IsStunned {
 		b.Logger.Warnw("Skipping stunned monster")
+		b.Trace.SetFallback("skipped: stunned")
 		return b.Yell("STUNNED!")
 	}
 	b.Logger.Infow("Handling monster",
@@ -70,6 +138,7 @@ func (b *Bot) Run() *swagger.DungeonsandtrollsCommandsBatch {
 	// calculate distance and line of sight
 	b.BotState.MapExtended = b.calculateDistanceAndLineOfSight(level, *position)
 	b.BotState.Objects = b.getMapObjectsByCategoryForLevel(level)
+	b.BotState.Paths = b.levelGrid().Dijkstra(*position, b.Tuning.Movement.PathSearchMaxCost)
 
 	b.BotState.TargetPositionTimeout -= 1
 	if b.BotState.TargetPositionTimeout <= 0 {
@@ -80,6 +149,10 @@ func (b *Bot) Run() *swagger.DungeonsandtrollsCommandsBatch {
 		b.Logger.Infow("Resetting target position because reached")
 		b.BotState.TargetPosition = nil
 	}
+	b.bindGuardedObject()
+	b.updateThreat()
+	b.updateTriage()
+	b.updateBehavior()
 	// One shot skill eval
 	return b.bestSkill()
 }
@@ -89,19 +162,33 @@ func (b *Bot) moveTowardsEnemy(enemies []MapObject) *swagger.DungeonsandtrollsCo
 	magicDistance := 15 // distance threshold
 	closeEnemies := []MapObject{}
 	for _, enemy := range enemies {
-		if b.BotState.MapExtended[*enemy.MapObjects.Position].distance < magicDistance {
+		if b.BotState.MapExtended.At(*enemy.MapObjects.Position).distance < magicDistance {
 			closeEnemies = append(closeEnemies, enemy)
 		}
 	}
 	if len(closeEnemies) == 0 {
 		return nil
 	}
-	rp := rand.Intn(len(closeEnemies))
-	b.addYell("I'm coming for you " + closeEnemies[rp].GetName() + "!")
+	// Go after whoever hurts us the most
+	target := b.pickThreatTarget(closeEnemies)
+	if target == nil {
+		target = &closeEnemies[b.Rand.Intn(len(closeEnemies))]
+	}
+	b.addYell("I'm coming for you " + target.GetName() + "!")
+	// Walk our own path step by step so that we go around harmful ground effects
+	move := target.MapObjects.Position
+	path, found := b.BotState.Paths.PathTo(*move)
+	if found && path.Next() != nil {
+		move = path.Next()
+	}
+	b.reserveDestination(move)
 	b.Logger.Infow("I'm coming for you!",
-		"targetName", closeEnemies[rp].GetName(),
+		"targetName", target.GetName(),
+		"targetThreat", b.BotState.Threat[target.GetId()],
+		"pathLength", path.Len(),
+		"pathCost", path.Cost,
 	)
 	return &swagger.DungeonsandtrollsCommandsBatch{
-		Move: closeEnemies[rp].MapObjects.Position,
+		Move: move,
 	}
 }
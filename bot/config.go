@@ -1,14 +1,34 @@
 package bot
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
 type Config struct {
-	Aggression   float32
-	Preservation float32
-	Support      float32
+	Aggression   float32 `json:"aggression"`
+	Preservation float32 `json:"preservation"`
+	Support      float32 `json:"support"`
+
+	Restlessness float32 `json:"restlessness"`
+	Randomness   float32 `json:"randomness"`
 
-	Restlessness float32
-	Randomness   float32
+	// Behavior when there is nothing to fight: idle (wanders around home), patrol (walks a route of waypoints), or guard (sentry at its post)
+	IdleBehavior Behavior `json:"idleBehavior"`
+	// Flee when vitals drop below this ratio of full vitals (0 never flees)
+	FleeVitals float32 `json:"fleeVitals"`
+	// Flee when this ratio of max life is lost in one tick (0 never flees because of a sharp drop)
+	FleeLifeDrop float32 `json:"fleeLifeDrop"`
+	// Monsters pulled further than this from home evade back (0 for no leash)
+	LeashRadius int32 `json:"leashRadius"`
+	// Guardians protect the closest stairs, portal or chest near home: they block paths to it and attack players approaching it
+	GuardObject GuardObject `json:"guardObject"`
 }
 
+const defaultProfileName = "default"
+
 func NewConfig(algorithm string) Config {
 	return Config{
 		Aggression:   4,
@@ -17,5 +37,185 @@ func NewConfig(algorithm string) Config {
 
 		Restlessness: 1.2,
 		Randomness:   0.03,
+
+		IdleBehavior: BehaviorIdle,
+		FleeVitals:   0.2,
+		FleeLifeDrop: 0.25,
+		LeashRadius:  20,
+	}
+}
+
+func (c Config) Validate() error {
+	if c.Aggression < 0 {
+		return fmt.Errorf("aggression must not be negative (got %v)", c.Aggression)
+	}
+	if c.Preservation < 0 {
+		return fmt.Errorf("preservation must not be negative (got %v)", c.Preservation)
+	}
+	if c.Support < 0 {
+		return fmt.Errorf("support must not be negative (got %v)", c.Support)
+	}
+	if c.Restlessness < 0 {
+		return fmt.Errorf("restlessness must not be negative (got %v)", c.Restlessness)
+	}
+	if c.Randomness < 0 || c.Randomness > 1 {
+		return fmt.Errorf("randomness must be between 0 and 1 (got %v)", c.Randomness)
+	}
+	if c.FleeVitals < 0 || c.FleeVitals > 1 {
+		return fmt.Errorf("fleeVitals must be between 0 and 1 (got %v)", c.FleeVitals)
+	}
+	if c.FleeLifeDrop < 0 || c.FleeLifeDrop > 1 {
+		return fmt.Errorf("fleeLifeDrop must be between 0 and 1 (got %v)", c.FleeLifeDrop)
+	}
+	if c.LeashRadius < 0 {
+		return fmt.Errorf("leashRadius must not be negative (got %v)", c.LeashRadius)
+	}
+	validGuardObject := false
+	for _, guardObject := range guardObjects {
+		if c.GuardObject == guardObject {
+			validGuardObject = true
+		}
+	}
+	if !validGuardObject {
+		return fmt.Errorf("guardObject must be one of %q (got %q)", guardObjects, c.GuardObject)
+	}
+	for _, behavior := range idleBehaviors {
+		if c.IdleBehavior == behavior {
+			return nil
+		}
+	}
+	return fmt.Errorf("idleBehavior must be one of %v (got %q)", idleBehaviors, c.IdleBehavior)
+}
+
+// ConfigProfiles maps monster algorithms (e.g. "berserker", "coward") to configs
+// Algorithms without a profile get the default profile
+type ConfigProfiles struct {
+	Default  Config
+	Profiles map[string]Config
+}
+
+func NewConfigProfiles() *ConfigProfiles {
+	return &ConfigProfiles{
+		Default:  NewConfig(defaultProfileName),
+		Profiles: map[string]Config{},
+	}
+}
+
+func (p *ConfigProfiles) ConfigFor(algorithm string) Config {
+	if p == nil {
+		return NewConfig(algorithm)
+	}
+	config, found := p.Profiles[algorithm]
+	if !found {
+		return p.Default
+	}
+	return config
+}
+
+// Profile returns the config by profile name ("default" for the default profile)
+func (p *ConfigProfiles) Profile(name string) (Config, bool) {
+	if name == defaultProfileName {
+		if p == nil {
+			return NewConfig(name), true
+		}
+		return p.Default, true
+	}
+	if p == nil {
+		return Config{}, false
+	}
+	config, found := p.Profiles[name]
+	return config, found
+}
+
+// LoadConfigProfiles reads profiles from a JSON file:
+//
+//	{
+//	  "default":   {"aggression": 4, "preservation": 2, ...},
+//	  "berserker": {"aggression": 9},
+//	  "sniper":    {"extends": "berserker", "restlessness": 0.4}
+//	}
+//
+// Each profile starts as a copy of its parent ("default" unless "extends" is set)
+// and overrides only the fields it specifies. Unknown fields (e.g. typos) are errors.
+func LoadConfigProfiles(path string) (*ConfigProfiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config profiles: %w", err)
+	}
+	return ParseConfigProfiles(data)
+}
+
+func ParseConfigProfiles(data []byte) (*ConfigProfiles, error) {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing config profiles: %w", err)
+	}
+	profiles := NewConfigProfiles()
+	resolving := map[string]bool{}
+
+	var resolve func(name string) (Config, error)
+	resolve = func(name string) (Config, error) {
+		if config, found := profiles.Profiles[name]; found {
+			return config, nil
+		}
+		rawProfile, found := raw[name]
+		if !found {
+			if name == defaultProfileName {
+				return profiles.Default, nil
+			}
+			return Config{}, fmt.Errorf("profile %q not found", name)
+		}
+		if resolving[name] {
+			return Config{}, fmt.Errorf("profile %q is part of an inheritance cycle", name)
+		}
+		resolving[name] = true
+		defer delete(resolving, name)
+
+		header := struct {
+			Extends string `json:"extends"`
+		}{}
+		if err := json.Unmarshal(rawProfile, &header); err != nil {
+			return Config{}, fmt.Errorf("profile %q: %w", name, err)
+		}
+		config := NewConfig(name)
+		if name != defaultProfileName {
+			parent := header.Extends
+			if parent == "" {
+				parent = defaultProfileName
+			}
+			parentConfig, err := resolve(parent)
+			if err != nil {
+				return Config{}, fmt.Errorf("profile %q: %w", name, err)
+			}
+			config = parentConfig
+		} else if header.Extends != "" {
+			return Config{}, fmt.Errorf("profile %q can't extend other profiles", name)
+		}
+		profile := struct {
+			Config
+			Extends string `json:"extends"`
+		}{Config: config}
+		decoder := json.NewDecoder(bytes.NewReader(rawProfile))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&profile); err != nil {
+			return Config{}, fmt.Errorf("profile %q: %w", name, err)
+		}
+		config = profile.Config
+		if err := config.Validate(); err != nil {
+			return Config{}, fmt.Errorf("profile %q: %w", name, err)
+		}
+		profiles.Profiles[name] = config
+		return config, nil
+	}
+
+	for name := range raw {
+		if _, err := resolve(name); err != nil {
+			return nil, err
+		}
+	}
+	if config, found := profiles.Profiles[defaultProfileName]; found {
+		profiles.Default = config
+		delete(profiles.Profiles, defaultProfileName)
 	}
+	return profiles, nil
 }
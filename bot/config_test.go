@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfigProfiles(t *testing.T) {
+	profiles, err := ParseConfigProfiles([]byte(`{
+		"default":   {"aggression": 3, "support": 2},
+		"berserker": {"aggression": 9, "fleeVitals": 0},
+		"sniper":    {"extends": "berserker", "restlessness": 0.4},
+		"healer":    {"support": 5}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := NewConfig(defaultProfileName)
+	tests := []struct {
+		algorithm    string
+		aggression   float32
+		support      float32
+		restlessness float32
+		fleeVitals   float32
+	}{
+		// Fields missing in the file keep the built-in defaults
+		{"default", 3, 2, defaults.Restlessness, defaults.FleeVitals},
+		{"berserker", 9, 2, defaults.Restlessness, 0},
+		// Inherits from berserker, which inherits from default
+		{"sniper", 9, 2, 0.4, 0},
+		{"healer", 3, 5, defaults.Restlessness, defaults.FleeVitals},
+		// Algorithms without a profile get the default profile
+		{"unknown", 3, 2, defaults.Restlessness, defaults.FleeVitals},
+	}
+	for _, test := range tests {
+		t.Run(test.algorithm, func(t *testing.T) {
+			config := profiles.ConfigFor(test.algorithm)
+			if config.Aggression != test.aggression || config.Support != test.support || config.Restlessness != test.restlessness || config.FleeVitals != test.fleeVitals {
+				t.Errorf("unexpected config %+v", config)
+			}
+		})
+	}
+	if _, found := profiles.Profile("unknown"); found {
+		t.Error("unknown profile found by name")
+	}
+	if config, found := profiles.Profile(defaultProfileName); !found || config.Aggression != 3 {
+		t.Errorf("expected default profile, got %+v", config)
+	}
+}
+
+func TestParseConfigProfilesWithoutDefault(t *testing.T) {
+	profiles, err := ParseConfigProfiles([]byte(`{"coward": {"fleeVitals": 0.5}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profiles.Default != NewConfig(defaultProfileName) {
+		t.Errorf("expected built-in default profile, got %+v", profiles.Default)
+	}
+	if config := profiles.ConfigFor("coward"); config.FleeVitals != 0.5 || config.Aggression != profiles.Default.Aggression {
+		t.Errorf("unexpected coward config %+v", config)
+	}
+	// Bots without profiles (no DNT_CONFIG_PROFILES) use the built-in default
+	var missing *ConfigProfiles
+	if config := missing.ConfigFor("coward"); config != NewConfig("coward") {
+		t.Errorf("unexpected config without profiles %+v", config)
+	}
+}
+
+func TestParseConfigProfilesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		error string
+	}{
+		{"unknown field", `{"berserker": {"agression": 9}}`, `unknown field "agression"`},
+		{"unknown field in default", `{"default": {"fleeVital": 0.1}}`, `unknown field "fleeVital"`},
+		{"cycle", `{"a": {"extends": "b"}, "b": {"extends": "c"}, "c": {"extends": "a"}}`, "inheritance cycle"},
+		{"self cycle", `{"a": {"extends": "a"}}`, "inheritance cycle"},
+		{"missing parent", `{"a": {"extends": "b"}}`, `profile "b" not found`},
+		{"default extends", `{"default": {"extends": "a"}, "a": {}}`, "can't extend"},
+		{"invalid value", `{"a": {"randomness": 2}}`, "randomness must be between 0 and 1"},
+		{"invalid idle behavior", `{"a": {"idleBehavior": "flee"}}`, "idleBehavior must be one of"},
+		{"wrong type", `{"a": {"aggression": "high"}}`, `profile "a"`},
+		{"not an object", `[]`, "parsing config profiles"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseConfigProfiles([]byte(test.json))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				t.Errorf("expected error containing %q, got %v", test.error, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigProfilesFile(t *testing.T) {
+	profiles, err := LoadConfigProfiles("../profiles.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := profiles.Profile("berserker"); !found {
+		t.Error("berserker profile missing")
+	}
+}
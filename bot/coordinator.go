@@ -0,0 +1,217 @@
+package bot
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// Coordinator shares tactical assignments between bots on one level in one tick
+// It is created in BotDispatcher.HandleLevel and its assignments are added to skill scores as bonuses (see SkillResult.Coordination)
+type Coordinator struct {
+	Level int32
+
+	// Focus fire target (hostile with the lowest effective HP weighted by path distance) by monster faction
+	FocusTargets map[string]string
+	// Characters that took damage recently by ID -> missing life (0-1)
+	UnderAttack map[string]float32
+
+	lock sync.Mutex
+	// Destination tile -> monster ID (the lowest ID wins, see Reserve)
+	reservations map[swagger.DungeonsandtrollsPosition]string
+}
+
+type coordinatedCharacter struct {
+	id          string
+	faction     string
+	position    swagger.DungeonsandtrollsPosition
+	effectiveHP float32
+	missingLife float32
+	damaged     bool
+}
+
+func NewCoordinator(level *swagger.DungeonsandtrollsLevel, layout *LevelLayout, tuning *Tuning) *Coordinator {
+	c := &Coordinator{
+		Level:        level.Level,
+		FocusTargets: map[string]string{},
+		UnderAttack:  map[string]float32{},
+		reservations: map[swagger.DungeonsandtrollsPosition]string{},
+	}
+	characters := []coordinatedCharacter{}
+	// Monster positions by faction
+	monsterFactions := map[string][]swagger.DungeonsandtrollsPosition{}
+	for _, object := range level.Objects {
+		if object.Position == nil {
+			continue
+		}
+		for _, player := range object.Players {
+			characters = append(characters, newCoordinatedCharacter(player.Id, "player", *object.Position, player.Attributes, player.MaxAttributes, player.LastDamageTaken, tuning))
+		}
+		for _, monster := range object.Monsters {
+			if monster.Faction == "neutral" || monster.Attributes == nil || monster.Attributes.Life <= 0 {
+				continue
+			}
+			monsterFactions[monster.Faction] = append(monsterFactions[monster.Faction], *object.Position)
+			characters = append(characters, newCoordinatedCharacter(monster.Id, monster.Faction, *object.Position, monster.Attributes, monster.MaxAttributes, monster.LastDamageTaken, tuning))
+		}
+	}
+	// Deterministic tie breaking
+	sort.Slice(characters, func(i, j int) bool {
+		return characters[i].id < characters[j].id
+	})
+	for _, character := range characters {
+		if character.damaged && character.missingLife > 0 {
+			c.UnderAttack[character.id] = character.missingLife
+		}
+	}
+	for faction, positions := range monsterFactions {
+		var focus *coordinatedCharacter
+		focusScore := float32(math.MaxFloat32)
+		for i := range characters {
+			character := &characters[i]
+			if friendly, _ := areFactionsFriendly(faction, character.faction); friendly || character.effectiveHP <= 0 {
+				continue
+			}
+			distance := closestDistance(layout, positions, character.position)
+			if distance == math.MaxInt32 {
+				// Nobody of the faction can walk to the character
+				continue
+			}
+			// Weak hostiles on the other side of the level are not worth the walk
+			score := character.effectiveHP + tuning.Coordination.FocusDistanceWeight*float32(distance)
+			if score < focusScore {
+				focus = character
+				focusScore = score
+			}
+		}
+		if focus != nil {
+			c.FocusTargets[faction] = focus.id
+		}
+	}
+	return c
+}
+
+// closestDistance is the shortest walking distance from any of the positions (math.MaxInt32 if unreachable)
+func closestDistance(layout *LevelLayout, positions []swagger.DungeonsandtrollsPosition, to swagger.DungeonsandtrollsPosition) int32 {
+	closest := int32(math.MaxInt32)
+	for _, position := range positions {
+		if distance := layout.Distance(position, to); distance < closest {
+			closest = distance
+		}
+	}
+	return closest
+}
+
+func newCoordinatedCharacter(id, faction string, position swagger.DungeonsandtrollsPosition, attrs, maxAttrs *swagger.DungeonsandtrollsAttributes, lastDamageTaken int32, tuning *Tuning) coordinatedCharacter {
+	character := coordinatedCharacter{
+		id:       id,
+		faction:  faction,
+		position: position,
+		damaged:  lastDamageTaken <= tuning.Behavior.CombatTicks,
+	}
+	if attrs == nil {
+		return character
+	}
+	// Inverse of the damage formula (see calculateDamage) with average resist
+	resist := (attrs.SlashResist + attrs.PierceResist + attrs.FireResist + attrs.PoisonResist + attrs.ElectricResist) / 5
+	character.effectiveHP = attrs.Life * float32(10+math.Max(float64(resist), -5)) / 10
+	if maxAttrs != nil && maxAttrs.Life > 0 {
+		character.missingLife = 1 - attrs.Life/maxAttrs.Life
+	}
+	return character
+}
+
+// Reserve claims the destination tile for the monster
+// Conflicts are resolved by monster ID (the lowest ID wins) and not by which worker runs first
+func (c *Coordinator) Reserve(position swagger.DungeonsandtrollsPosition, monsterId string) bool {
+	if c == nil {
+		return true
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if reservedBy, found := c.reservations[position]; found && reservedBy < monsterId {
+		return false
+	}
+	c.reservations[position] = monsterId
+	return true
+}
+
+// IsReservedByOther returns true if a monster with a lower ID is moving to the tile
+func (c *Coordinator) IsReservedByOther(position swagger.DungeonsandtrollsPosition, monsterId string) bool {
+	if c == nil {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	reservedBy, found := c.reservations[position]
+	return found && reservedBy < monsterId
+}
+
+func (c *Coordinator) FocusTarget(faction string) string {
+	if c == nil {
+		return ""
+	}
+	return c.FocusTargets[faction]
+}
+
+// coordinationBonus scores how well the skill + target combination follows the level assignments
+func (b *Bot) coordinationBonus(skill swagger.DungeonsandtrollsSkill, target MapObject, result SkillResult) float32 {
+	t := b.Tuning.Coordination
+	bonus := float32(0)
+	if !target.IsEmpty() {
+		targetId := target.GetId()
+		if result.VitalsHostile < 0 && targetId == b.Coordinator.FocusTarget(b.Details.Monster.Faction) {
+			bonus += t.FocusFireBonus
+		}
+		if b.IsFriendly(target) && result.VitalsFriendly+result.BuffsFriendly+result.ResistsFriendly > 0 {
+			if missingLife, found := b.Coordinator.underAttack(targetId); found {
+				// Healers and support monsters care the most
+				bonus += t.AllyUnderAttackBonus * missingLife * b.Config.Support
+			}
+		}
+	}
+	if skill.CasterEffects.Flags.Movement {
+		// Reservations are made for the entered tile (see reserveDestination)
+		entered := b.enteredPosition(skill, target)
+		if entered != nil && b.Coordinator.IsReservedByOther(*entered, b.MonsterId) {
+			bonus -= t.ReservedTilePenalty
+		}
+	}
+	return bonus
+}
+
+func (c *Coordinator) underAttack(id string) (float32, bool) {
+	if c == nil {
+		return 0, false
+	}
+	missingLife, found := c.UnderAttack[id]
+	return missingLife, found
+}
+
+// enteredPosition is the tile the monster enters this tick by using the movement skill
+// Moves walk along the path to the target (the server moves one tile per tick), other movement skills land on the target
+// Only the path tree of the bot is used (targets further than Tuning.Movement.PathSearchMaxCost enter no known tile)
+func (b *Bot) enteredPosition(skill swagger.DungeonsandtrollsSkill, target MapObject) *swagger.DungeonsandtrollsPosition {
+	if !isDefaultMoveSkill(skill) {
+		return b.getSkillTargetPosition(&skill, &target)
+	}
+	path, found := b.BotState.Paths.PathTo(*target.GetPosition())
+	if !found {
+		return nil
+	}
+	return path.Next()
+}
+
+// reserveDestination reserves the tile the command moves the monster to
+func (b *Bot) reserveDestination(position *swagger.DungeonsandtrollsPosition) {
+	if position == nil {
+		return
+	}
+	if !b.Coordinator.Reserve(*position, b.MonsterId) {
+		b.Logger.Debugw("Destination already reserved by another monster",
+			"position", position,
+		)
+	}
+}
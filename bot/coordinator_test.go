@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// newTestLevel builds level 1 from rows of tiles ('#' wall, anything else free)
+func newTestLevel(rows ...string) *swagger.DungeonsandtrollsLevel {
+	level := &swagger.DungeonsandtrollsLevel{Level: 1, Width: int32(len(rows[0])), Height: int32(len(rows))}
+	for y, row := range rows {
+		for x, c := range row {
+			position := makePosition(int32(x), int32(y))
+			level.Objects = append(level.Objects, swagger.DungeonsandtrollsMapObjects{
+				Position: &position,
+				IsWall:   c == '#',
+				IsFree:   c != '#',
+			})
+		}
+	}
+	return level
+}
+
+func tileOf(level *swagger.DungeonsandtrollsLevel, position swagger.DungeonsandtrollsPosition) *swagger.DungeonsandtrollsMapObjects {
+	for i := range level.Objects {
+		if *level.Objects[i].Position == position {
+			return &level.Objects[i]
+		}
+	}
+	panic("tile not found")
+}
+
+func addTestPlayer(level *swagger.DungeonsandtrollsLevel, id string, position swagger.DungeonsandtrollsPosition, life float32) {
+	tile := tileOf(level, position)
+	tile.Players = append(tile.Players, swagger.DungeonsandtrollsCharacter{
+		Id:              id,
+		Attributes:      &swagger.DungeonsandtrollsAttributes{Life: life},
+		MaxAttributes:   &swagger.DungeonsandtrollsAttributes{Life: 100},
+		LastDamageTaken: 10,
+	})
+}
+
+func addTestMonster(level *swagger.DungeonsandtrollsLevel, id string, position swagger.DungeonsandtrollsPosition, life float32) {
+	tile := tileOf(level, position)
+	tile.Monsters = append(tile.Monsters, swagger.DungeonsandtrollsMonster{
+		Id:              id,
+		Faction:         "monster",
+		Attributes:      &swagger.DungeonsandtrollsAttributes{Life: life},
+		MaxAttributes:   &swagger.DungeonsandtrollsAttributes{Life: 100},
+		LastDamageTaken: 10,
+	})
+}
+
+func TestFocusTargetWeightsPathDistance(t *testing.T) {
+	level := newTestLevel(
+		"############",
+		"#####.######",
+		"............",
+		"............",
+	)
+	addTestMonster(level, "monster-1", makePosition(0, 2), 50)
+	addTestPlayer(level, "near", makePosition(2, 2), 100)
+	addTestPlayer(level, "far", makePosition(11, 2), 60)
+	// Weakest but nobody can walk to it
+	addTestPlayer(level, "walled", makePosition(5, 0), 1)
+	layout := NewLevelCache(level, nil).Layout
+
+	tests := []struct {
+		name           string
+		distanceWeight float32
+		focus          string
+	}{
+		// near: 100 + 5 * 2, far: 60 + 5 * 11
+		{"default weight", 5, "near"},
+		{"no weight", 0, "far"},
+		{"low weight", 1, "far"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tuning := DefaultTuning()
+			tuning.Coordination.FocusDistanceWeight = test.distanceWeight
+			c := NewCoordinator(level, layout, &tuning)
+			if focus := c.FocusTarget("monster"); focus != test.focus {
+				t.Errorf("expected focus %s, got %s", test.focus, focus)
+			}
+		})
+	}
+}
+
+func TestFocusTargetPerFaction(t *testing.T) {
+	level := newTestLevel(
+		"........",
+	)
+	addTestMonster(level, "monster-1", makePosition(0, 0), 50)
+	addTestMonster(level, "monster-2", makePosition(1, 0), 20)
+	templar := &tileOf(level, makePosition(1, 0)).Monsters[0]
+	templar.Faction = "templar"
+	addTestPlayer(level, "player-1", makePosition(7, 0), 100)
+	tuning := DefaultTuning()
+	c := NewCoordinator(level, NewLevelCache(level, nil).Layout, &tuning)
+	// Templars are hostile to monsters and friendly to players
+	if focus := c.FocusTarget("monster"); focus != "monster-2" {
+		t.Errorf("expected monster faction to focus monster-2, got %q", focus)
+	}
+	if focus := c.FocusTarget("templar"); focus != "monster-1" {
+		t.Errorf("expected templars to focus monster-1, got %q", focus)
+	}
+	if focus := c.FocusTarget("neutral"); focus != "" {
+		t.Errorf("expected no focus for neutral monsters, got %q", focus)
+	}
+	var missing *Coordinator
+	if focus := missing.FocusTarget("monster"); focus != "" {
+		t.Errorf("expected no focus without coordinator, got %q", focus)
+	}
+}
+
+func TestReserveLowestIdWins(t *testing.T) {
+	position := makePosition(3, 3)
+	orders := [][]string{
+		{"monster-1", "monster-2", "monster-3"},
+		{"monster-3", "monster-2", "monster-1"},
+		{"monster-2", "monster-3", "monster-1"},
+	}
+	for _, order := range orders {
+		c := &Coordinator{reservations: map[swagger.DungeonsandtrollsPosition]string{}}
+		for _, monsterId := range order {
+			c.Reserve(position, monsterId)
+		}
+		if reservedBy := c.reservations[position]; reservedBy != "monster-1" {
+			t.Errorf("order %v: expected tile reserved by monster-1, got %s", order, reservedBy)
+		}
+		if c.IsReservedByOther(position, "monster-1") {
+			t.Errorf("order %v: monster-1 yields its tile", order)
+		}
+		if !c.IsReservedByOther(position, "monster-2") {
+			t.Errorf("order %v: monster-2 doesn't yield to monster-1", order)
+		}
+		if !c.Reserve(position, "monster-1") || c.Reserve(position, "monster-2") {
+			t.Errorf("order %v: reservation not kept by the lowest ID", order)
+		}
+	}
+	var missing *Coordinator
+	if !missing.Reserve(position, "monster-1") || missing.IsReservedByOther(position, "monster-1") {
+		t.Error("missing coordinator must not block moves")
+	}
+}
+
+func TestEnteredPositionFollowsPathTree(t *testing.T) {
+	d := newTestDispatcher()
+	if err := d.HandleTick(loadArena(t), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	bot := d.Bots["monster-1"]
+	player := bot.BotState.Objects.Players[0]
+	entered := bot.enteredPosition(getDefaultMoveSkill(), player)
+	if entered == nil {
+		t.Fatal("no entered position for a reachable player")
+	}
+	path, _ := bot.BotState.Paths.PathTo(*player.GetPosition())
+	if *entered != *path.Next() || manhattanDistance(*entered, *bot.Details.Position) != 1 {
+		t.Errorf("expected first step %v of the path tree, got %v", *path.Next(), *entered)
+	}
+	// Targets out of the path tree enter no known tile
+	bot.BotState.Paths = nil
+	if entered := bot.enteredPosition(getDefaultMoveSkill(), player); entered != nil {
+		t.Errorf("expected no entered position without path tree, got %v", *entered)
+	}
+}
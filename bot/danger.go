@@ -0,0 +1,233 @@
+package bot
+
+import (
+	"math"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// DangerMap rates tiles around the monster by the hostiles that can hit them in the next tick
+// Built only for fleeing and kiting monsters (see updateBehaviorPosition)
+type DangerMap struct {
+	// Hostiles in skill range with line of sight count 1, hostiles that have to walk first count Tuning.Danger.ApproachDanger
+	Danger map[swagger.DungeonsandtrollsPosition]float32
+	// Tiles seen by at least one hostile
+	Exposed map[swagger.DungeonsandtrollsPosition]bool
+}
+
+// At is 0 for tiles outside of the map (and for nil maps)
+func (m *DangerMap) At(position swagger.DungeonsandtrollsPosition) float32 {
+	if m == nil {
+		return 0
+	}
+	return m.Danger[position]
+}
+
+func (m *DangerMap) IsExposed(position swagger.DungeonsandtrollsPosition) bool {
+	if m == nil {
+		return false
+	}
+	return m.Exposed[position]
+}
+
+// hostileRange is the longest range of the damage skills of the hostile
+// Players and monsters without known damage skills get Tuning.Danger.DefaultHostileRange
+func (b *Bot) hostileRange(hostile MapObject) int32 {
+	attrs := hostile.GetAttributes()
+	hostileRange := b.Tuning.Danger.DefaultHostileRange
+	if attrs == nil {
+		return hostileRange
+	}
+	found := false
+	for _, skill := range getAllSkills(hostile.GetEquippedItems()) {
+		if (skill.Flags != nil && skill.Flags.Passive) || skill.Range_ == nil || skill.DamageAmount == nil {
+			continue
+		}
+		if calculateAttributesValue(*attrs, *skill.DamageAmount) <= 0 {
+			continue
+		}
+		skillRange := int32(calculateAttributesValue(*attrs, *skill.Range_))
+		if !found || skillRange > hostileRange {
+			hostileRange = skillRange
+			found = true
+		}
+	}
+	return hostileRange
+}
+
+// dangerMap covers tiles reachable within Tuning.Danger.SearchRadius of the monster
+func (b *Bot) dangerMap() *DangerMap {
+	t := b.Tuning.Danger
+	layout := b.levelCache().Layout
+	position := *b.Details.Position
+	dangerMap := &DangerMap{
+		Danger:  map[swagger.DungeonsandtrollsPosition]float32{},
+		Exposed: map[swagger.DungeonsandtrollsPosition]bool{},
+	}
+	tiles := b.searchTiles()
+	for _, hostile := range b.BotState.Objects.Hostile {
+		hostilePosition := *hostile.GetPosition()
+		hostileRange := b.hostileRange(hostile)
+		if manhattanDistance(position, hostilePosition) > hostileRange+t.ReachMargin+t.SearchRadius {
+			continue
+		}
+		for _, tile := range tiles {
+			visible := layout.LineOfSight(hostilePosition, tile)
+			if visible {
+				dangerMap.Exposed[tile] = true
+			}
+			distance := layout.Distance(hostilePosition, tile)
+			if visible && distance <= hostileRange {
+				dangerMap.Danger[tile] += 1
+			} else if distance <= hostileRange+t.ReachMargin {
+				dangerMap.Danger[tile] += t.ApproachDanger
+			}
+		}
+	}
+	b.Logger.Debugw("Danger map computed",
+		"hostilesCount", len(b.BotState.Objects.Hostile),
+		"tilesCount", len(tiles),
+		"danger", dangerMap.At(position),
+	)
+	return dangerMap
+}
+
+// searchTiles returns free tiles within Tuning.Danger.SearchRadius the monster can walk to (in a fixed order)
+func (b *Bot) searchTiles() []swagger.DungeonsandtrollsPosition {
+	radius := b.Tuning.Danger.SearchRadius
+	position := *b.Details.Position
+	cache := b.levelCache()
+	tiles := []swagger.DungeonsandtrollsPosition{position}
+	for x := position.PositionX - radius; x <= position.PositionX+radius; x++ {
+		for y := position.PositionY - radius; y <= position.PositionY+radius; y++ {
+			tile := makePosition(x, y)
+			if tile == position || manhattanDistance(position, tile) > radius {
+				continue
+			}
+			// Walls are never dangerous (nothing sees them), they must not become the safest tile
+			if object, found := cache.Tiles[tile]; found && !object.IsFree {
+				continue
+			}
+			if cache.Layout.Distance(position, tile) == math.MaxInt32 {
+				continue
+			}
+			if _, reachable := b.BotState.Paths.Cost(tile); reachable {
+				tiles = append(tiles, tile)
+			}
+		}
+	}
+	return tiles
+}
+
+// safestPosition picks the tile with the lowest danger, preferring tiles near friendlies and out of sight of hostiles
+// Returns nil when the monster is already on the safest tile
+func (b *Bot) safestPosition(dangerMap *DangerMap) *swagger.DungeonsandtrollsPosition {
+	t := b.Tuning.Danger
+	position := *b.Details.Position
+	var safest *swagger.DungeonsandtrollsPosition
+	bestScore := float32(-math.MaxFloat32)
+	for _, tile := range b.searchTiles() {
+		if tile != position && b.isOccupied(tile) {
+			continue
+		}
+		cost, _ := b.BotState.Paths.Cost(tile)
+		score := -t.DangerWeight*dangerMap.At(tile) - t.PathCostWeight*cost
+		if !dangerMap.IsExposed(tile) {
+			score += t.HiddenBonus
+		}
+		if distance := b.distanceToClosestFriendly(tile); distance < math.MaxInt32 {
+			score += t.FriendlyWeight * t.FriendlyHalfDistance / (float32(distance) + t.FriendlyHalfDistance)
+		}
+		if score > bestScore {
+			tile := tile
+			safest = &tile
+			bestScore = score
+		}
+	}
+	if safest == nil || *safest == position {
+		return nil
+	}
+	b.Logger.Debugw("Safest position found",
+		"safestPosition", safest,
+		"score", bestScore,
+		"danger", dangerMap.At(*safest),
+		"currentDanger", dangerMap.At(position),
+	)
+	return safest
+}
+
+// Tiles with characters other than the monster itself
+func (b *Bot) isOccupied(position swagger.DungeonsandtrollsPosition) bool {
+	tileInfo, found := b.BotState.MapExtended.Lookup(position)
+	if !found {
+		return false
+	}
+	if len(tileInfo.mapObjects.Players) > 0 {
+		return true
+	}
+	for _, monster := range tileInfo.mapObjects.Monsters {
+		if monster.Id != b.MonsterId {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bot) distanceToClosestFriendly(position swagger.DungeonsandtrollsPosition) int32 {
+	closest := int32(math.MaxInt32)
+	for _, friendly := range b.BotState.Objects.Friendly {
+		if friendly.GetId() == b.MonsterId {
+			continue
+		}
+		if distance := manhattanDistance(position, *friendly.GetPosition()); distance < closest {
+			closest = distance
+		}
+	}
+	return closest
+}
+
+// kiteRange is the range of the most damaging skill of ranged monsters (0 for melee monsters)
+func (b *Bot) kiteRange() int32 {
+	minRange := b.Tuning.Behavior.KiteMinRange
+	if minRange <= 0 {
+		return 0
+	}
+	skills := b.filterDamageSkills(b.filterActiveSkills(getAllSkills(b.Details.Monster.EquippedItems)))
+	bestDamage := float32(0)
+	bestRange := int32(0)
+	for _, skill := range skills {
+		damage := b.calculateAttributesValue(*skill.DamageAmount)
+		if damage > bestDamage {
+			bestDamage = damage
+			bestRange = int32(b.calculateAttributesValue(*skill.Range_))
+		}
+	}
+	if bestRange < minRange {
+		return 0
+	}
+	return bestRange
+}
+
+// scoreKiteDistance penalizes positions off the kite range from the closest hostile
+func (b *Bot) scoreKiteDistance(position *swagger.DungeonsandtrollsPosition) float32 {
+	anchor := b.BotState.BehaviorPosition
+	kiteRange := b.BotState.KiteRange
+	if b.BotState.Behavior != BehaviorKite || anchor == nil || kiteRange <= 0 {
+		return 0
+	}
+	offset := math.Abs(float64(manhattanDistance(*position, *anchor) - kiteRange))
+	return -b.Tuning.Behavior.KiteDistanceWeight * float32(offset) / float32(kiteRange)
+}
+
+// lifeDrop is the life lost since the previous tick relative to max life
+func (b *Bot) lifeDrop() float32 {
+	monster := b.Details.Monster
+	previous := b.PrevDetails.Monster
+	if previous == nil || previous.Attributes == nil || monster.Attributes == nil || monster.MaxAttributes == nil {
+		return 0
+	}
+	if monster.MaxAttributes.Life <= 0 {
+		return 0
+	}
+	return (previous.Attributes.Life - monster.Attributes.Life) / monster.MaxAttributes.Life
+}
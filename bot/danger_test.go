@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFleeTargetIsFreeTile(t *testing.T) {
+	d := newTestDispatcher()
+	if err := d.HandleTick(loadArena(t), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetBotOverride("monster-1", BotOverride{Behavior: BehaviorFlee}); err != nil {
+		t.Fatal(err)
+	}
+	state := loadArena(t)
+	state.Tick = 1
+	// Player below the wall separating the spawn from the monsters
+	moveCharacter(state, "player-1", makePosition(7, 6))
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	bot := d.Bots["monster-1"]
+	if bot.BotState.Behavior != BehaviorFlee {
+		t.Fatalf("expected monster to flee, got %s", bot.BotState.Behavior)
+	}
+	cache := bot.LevelCache
+	for _, tile := range bot.searchTiles() {
+		if object, found := cache.Tiles[tile]; found && !object.IsFree {
+			t.Errorf("searched tile %v is not free", tile)
+		}
+	}
+	target := bot.BotState.TargetPosition
+	if target == nil {
+		t.Fatal("fleeing monster has no target position")
+	}
+	if object, found := cache.Tiles[*target]; (found && !object.IsFree) || !cache.Layout.InBounds(*target) {
+		t.Errorf("flee target %v is not a free tile", *target)
+	}
+	if bot.BotState.Danger.At(*target) > bot.BotState.Danger.At(*bot.Details.Position) {
+		t.Errorf("flee target %v is more dangerous than the monster position", *target)
+	}
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"math"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// RunDegraded is a cheap replacement for Run() used when there is no time left in the tick
+// It re-issues the previous command while it still makes sense, or walks towards the closest hostile
+func (b *Bot) RunDegraded() *swagger.DungeonsandtrollsCommandsBatch {
+	b.BotState.Self = NewMonsterMapObject(*b.Details.MapObjects, b.Details.Index)
+	b.BotState.Yells = []string{}
+	b.Trace = nil
+	if b.Debug {
+		b.Trace = b.newDecisionTrace()
+	}
+	monster := b.Details.Monster
+	if monster.Algorithm == "none" || monster.Attributes.Life <= 0 || monster.Stun.IsStunned {
+		b.Trace.SetFallback("degraded: skipped")
+		return nil
+	}
+	if b.isPreviousCommandValid() {
+		b.Trace.SetFallback("degraded: previous command")
+		previous := *b.LastCommand
+		previous.Yell = nil
+		return &previous
+	}
+	if hostile := b.closestHostilePosition(); hostile != nil {
+		b.Trace.SetFallback("degraded: move towards closest hostile")
+		return &swagger.DungeonsandtrollsCommandsBatch{
+			Move: hostile,
+		}
+	}
+	b.Trace.SetFallback("degraded: none")
+	return nil
+}
+
+func (b *Bot) isPreviousCommandValid() bool {
+	previous := b.LastCommand
+	if previous == nil {
+		return false
+	}
+	if previous.Move != nil {
+		return *previous.Move != *b.Details.Position
+	}
+	if previous.Skill == nil {
+		return false
+	}
+	var skill *swagger.DungeonsandtrollsSkill
+	for _, s := range getAllSkills(b.Details.Monster.EquippedItems) {
+		if s.Id == previous.Skill.SkillId {
+			skill = &s
+			break
+		}
+	}
+	if skill == nil || areAttributeRequirementMet(*b.Details.Monster.Attributes, *skill.Cost) != nil {
+		return false
+	}
+	if previous.Skill.TargetId == "" {
+		return true
+	}
+	// Target has to be still alive on the level
+	for _, object := range b.Details.CurrentMap.Objects {
+		for _, player := range object.Players {
+			if player.Id == previous.Skill.TargetId {
+				return player.Attributes == nil || player.Attributes.Life > 0
+			}
+		}
+		for _, monster := range object.Monsters {
+			if monster.Id == previous.Skill.TargetId {
+				return monster.Attributes == nil || monster.Attributes.Life > 0
+			}
+		}
+	}
+	return false
+}
+
+// closestHostilePosition uses manhattan distance (no line of sight or path search)
+func (b *Bot) closestHostilePosition() *swagger.DungeonsandtrollsPosition {
+	faction := b.Details.Monster.Faction
+	var closest *swagger.DungeonsandtrollsPosition
+	closestDistance := int32(math.MaxInt32)
+	check := func(position *swagger.DungeonsandtrollsPosition, otherFaction string) {
+		if otherFaction == "neutral" {
+			return
+		}
+		if friendly, _ := areFactionsFriendly(faction, otherFaction); friendly {
+			return
+		}
+		if distance := manhattanDistance(*b.Details.Position, *position); distance < closestDistance {
+			closest = position
+			closestDistance = distance
+		}
+	}
+	for i := range b.Details.CurrentMap.Objects {
+		object := &b.Details.CurrentMap.Objects[i]
+		for range object.Players {
+			check(object.Position, "player")
+		}
+		for _, monster := range object.Monsters {
+			check(object.Position, monster.Faction)
+		}
+	}
+	return closest
+}
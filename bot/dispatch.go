@@ -2,11 +2,18 @@ package bot
 
 import (
 	"context"
+	"hash/fnv"
+	"math/rand"
+	"runtime"
+	"runtime/debug"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/antihax/optional"
 	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/metrics"
 	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/swaggerutil"
 	"go.uber.org/zap"
 )
@@ -22,11 +29,104 @@ type MonsterDetails struct {
 	CurrentMap *swagger.DungeonsandtrollsLevel
 }
 
+// CommandSender replaces sending commands to the server (e.g. in the offline simulator)
+// Custom senders are called synchronously
+type CommandSender interface {
+	SendMonsterCommands(cmds swagger.DungeonsandtrollsCommandsForMonsters, logger *zap.SugaredLogger) error
+}
+
+// CommandRecorder gets every command produced by the bots (synchronously, before it is sent)
+type CommandRecorder interface {
+	RecordMonsterCommand(monsterId string, cmd swagger.DungeonsandtrollsCommandsBatch)
+}
+
+// Game ticks take about a second, commands sent later are used in the next tick
+const DefaultTickDeadline = 800 * time.Millisecond
+
+// Monsters with less time than this left in the tick are degraded (see Bot.RunDegraded)
+const DefaultMinMonsterBudget = 2 * time.Millisecond
+
+// TickStats summarize how the dispatcher kept up with the tick deadline
+type TickStats struct {
+	Tick     int32
+	Monsters int
+	// Monsters handled by RunDegraded because there was not enough time left
+	Degraded int
+	// IDs of the degraded monsters (sorted, recorded for replay)
+	DegradedMonsters []string
+	// Bots that panicked (no command was sent for them)
+	Panics int
+	// Bots disabled by operators (see BotOverride)
+	Disabled int
+	// Monsters by delivery status of their commands (see DeliveryStatus)
+	Deliveries map[string]int
+	// Bots kept after the tick and freed in the tick (see collectBots)
+	Bots    int
+	Removed int
+	// Sampled every MemStatsTicks ticks (runtime.ReadMemStats stops the world)
+	HeapAllocBytes uint64
+	Duration       time.Duration
+	// Commands were ready after the tick deadline
+	DeadlineMissed bool
+	// Bots were not run (see BotDispatcher.SetPaused)
+	Paused bool
+}
+
 type BotDispatcher struct {
-	Client        *swagger.APIClient
-	Ctx           context.Context
-	Bots          map[string]*Bot
-	BotsLock      sync.Mutex
+	Client   *swagger.APIClient
+	Ctx      context.Context
+	Bots     map[string]*Bot
+	BotsLock sync.Mutex
+	Profiles *ConfigProfiles
+	Sender   CommandSender
+	Recorder CommandRecorder
+	// Commands are batched by CommandBatching, transient failures are retried CommandRetries times
+	// Commands older than CommandTTL are dropped (0 for no TTL)
+	CommandBatching     CommandBatching
+	CommandRetries      int
+	CommandRetryBackoff time.Duration
+	CommandTTL          time.Duration
+	sink                *commandSink
+	TuningFile          *TuningFile
+	Tuning              *Tuning
+	// Monster IDs or names with decision traces enabled ("*" for all monsters)
+	DebugMonsters []string
+	// Decision traces are dumped to TraceDir when set
+	TraceDir string
+	// Bots have to decide within TickDeadline from the tick start (0 for no deadline)
+	// The time left is split between the monsters left in the tick
+	TickDeadline     time.Duration
+	MinMonsterBudget time.Duration
+	LastTickStats    TickStats
+	tickLock         sync.Mutex
+	tickStats        TickStats
+	monstersLeft     int
+	// RNG seed of the current tick, bots get their own RNG derived from it so that they don't depend on each other's order
+	Seed int64
+	// Planner expansions per monster used instead of Tuning.Planner.BudgetMs so that decisions don't depend on the wall clock
+	// (e.g. DeterministicPlannerExpansions in replay, 0 for the time budget)
+	PlannerExpansions int
+	// Monsters handled by RunDegraded regardless of the time left (e.g. monsters degraded in a recorded tick)
+	ForceDegraded map[string]bool
+	// Bots run on a pool of Workers goroutines
+	Workers int
+	workers chan struct{}
+	// Bots of monsters missing for DespawnTicks are freed and reported to Lifecycle
+	DespawnTicks int
+	Lifecycle    BotLifecycleListener
+	// Heap stats are sampled every MemStatsTicks ticks (see TickStats.HeapAllocBytes)
+	MemStatsTicks  int32
+	heapAllocBytes uint64
+	// Paused dispatchers don't run the bots and send no commands
+	paused int32
+	tick   int32
+	// Operator overrides by monster ID and disabled levels -> until tick (guarded by BotsLock)
+	overrides      map[string]BotOverride
+	disabledLevels map[int32]int32
+	// Commands sent in the background (see WaitForCommands)
+	sends sync.WaitGroup
+	// Level layouts from previous ticks by level (see LevelCache)
+	levelLayouts  map[int32]*LevelLayout
 	Logger        *zap.SugaredLogger
 	LoggerWTick   *zap.SugaredLogger
 	TickStartTime time.Time
@@ -35,24 +135,65 @@ type BotDispatcher struct {
 
 func NewBotDispatcher(client *swagger.APIClient, ctx context.Context, logger *zap.SugaredLogger, environment string) *BotDispatcher {
 	return &BotDispatcher{
-		Client:      client,
-		Ctx:         ctx,
-		Bots:        make(map[string]*Bot),
-		BotsLock:    sync.Mutex{},
-		Logger:      logger,
-		Environment: environment,
+		Client:              client,
+		Ctx:                 ctx,
+		Bots:                make(map[string]*Bot),
+		BotsLock:            sync.Mutex{},
+		levelLayouts:        make(map[int32]*LevelLayout),
+		TickDeadline:        DefaultTickDeadline,
+		MinMonsterBudget:    DefaultMinMonsterBudget,
+		Workers:             runtime.NumCPU(),
+		DespawnTicks:        DefaultDespawnTicks,
+		MemStatsTicks:       DefaultMemStatsTicks,
+		CommandBatching:     BatchPerMonster,
+		CommandRetries:      DefaultCommandRetries,
+		CommandRetryBackoff: DefaultCommandRetryBackoff,
+		CommandTTL:          DefaultCommandTTL,
+		Logger:              logger,
+		Environment:         environment,
+	}
+}
+
+func (d *BotDispatcher) updateTuning() {
+	if d.TuningFile != nil {
+		d.TuningFile.ReloadIfChanged(d.LoggerWTick)
+		d.Tuning = d.TuningFile.Current()
+	}
+	if d.Tuning == nil {
+		tuning := DefaultTuning()
+		d.Tuning = &tuning
 	}
 }
 
 func (d *BotDispatcher) HandleTick(gameState *swagger.DungeonsandtrollsGameState, tickStartTime time.Time) error {
 	d.TickStartTime = tickStartTime
+	atomic.StoreInt32(&d.tick, gameState.Tick)
 	d.LoggerWTick = d.Logger.With(
 		"tick", gameState.Tick,
 		"tickStartTime", tickStartTime,
 	)
+	if d.IsPaused() {
+		d.LastTickStats = TickStats{Tick: gameState.Tick, Paused: true}
+		d.LoggerWTick.Warnw("Monster AI is paused, no commands sent")
+		return nil
+	}
+	// Tuning only changes between ticks
+	d.updateTuning()
+	d.tickStats = TickStats{Tick: gameState.Tick}
+	d.monstersLeft = d.countMonstersToRun(gameState)
+	d.workers = make(chan struct{}, d.workerCount())
+	d.sink = d.newCommandSink(gameState.Tick)
 
-	for _, level := range gameState.Map_.Levels {
-		// go d.HandleLevel(gameState, level)
+	handleLevel := func(level swagger.DungeonsandtrollsLevel) {
+		defer func() {
+			if err := recover(); err != nil {
+				d.LoggerWTick.Errorw("PANIC in HandleLevel()",
+					"error", err,
+					"stack", string(debug.Stack()),
+					"mapLevel", level.Level,
+				)
+			}
+		}()
 		err := d.HandleLevel(gameState, level)
 		if err != nil {
 			d.LoggerWTick.Error("Error when running monster AI for level",
@@ -61,85 +202,334 @@ func (d *BotDispatcher) HandleTick(gameState *swagger.DungeonsandtrollsGameState
 			)
 		}
 	}
+	if d.workerCount() == 1 {
+		// A single worker runs the levels one by one in a reproducible order (see replay)
+		levels := append([]swagger.DungeonsandtrollsLevel{}, gameState.Map_.Levels...)
+		sort.SliceStable(levels, func(i, j int) bool {
+			return levels[i].Level < levels[j].Level
+		})
+		for _, level := range levels {
+			handleLevel(level)
+		}
+	} else {
+		wg := sync.WaitGroup{}
+		for _, level := range gameState.Map_.Levels {
+			wg.Add(1)
+			go func(level swagger.DungeonsandtrollsLevel) {
+				defer wg.Done()
+				handleLevel(level)
+			}(level)
+		}
+		wg.Wait()
+	}
+	d.sink.Flush(d.LoggerWTick)
+	deadline := time.Time{}
+	if d.TickDeadline > 0 {
+		deadline = tickStartTime.Add(d.TickDeadline)
+	}
+	statuses, done := d.sink.WaitUntil(deadline)
+	d.storeDeliveries(statuses)
+	if !done {
+		// Retries don't hold up the tick, they are dropped as stale once the next tick starts
+		go d.storeLateDeliveries(d.sink, d.LoggerWTick)
+	}
+	d.collectBots(gameState.Tick)
+	sort.Strings(d.tickStats.DegradedMonsters)
+	d.tickStats.Duration = time.Since(tickStartTime)
+	d.tickStats.DeadlineMissed = d.TickDeadline > 0 && d.tickStats.Duration > d.TickDeadline
+	d.LastTickStats = d.tickStats
+	logTickStats := d.LoggerWTick.Infow
+	if d.tickStats.Degraded > 0 || d.tickStats.Panics > 0 || d.tickStats.DeadlineMissed {
+		logTickStats = d.LoggerWTick.Warnw
+	}
+	logTickStats("Tick handled",
+		"monstersCount", d.tickStats.Monsters,
+		"degradedCount", d.tickStats.Degraded,
+		"disabledCount", d.tickStats.Disabled,
+		"deliveries", d.tickStats.Deliveries,
+		"panicsCount", d.tickStats.Panics,
+		"duration", d.tickStats.Duration,
+		"deadlineMissed", d.tickStats.DeadlineMissed,
+		"workers", cap(d.workers),
+		"botsCount", d.tickStats.Bots,
+		"removedCount", d.tickStats.Removed,
+		"heapAllocBytes", d.tickStats.HeapAllocBytes,
+	)
 	return nil
 }
 
+func (d *BotDispatcher) workerCount() int {
+	if d.Workers < 1 {
+		return 1
+	}
+	return d.Workers
+}
+
+// HandleLevel runs the bots of the level on the worker pool and sends their commands
 func (d *BotDispatcher) HandleLevel(gameState *swagger.DungeonsandtrollsGameState, level swagger.DungeonsandtrollsLevel) error {
-	// defer func(d *BotDispatcher) error {
-	// 	if err := recover(); err != nil {
-	// 		d.LoggerWTick.Error("PANIC in HandleLevel()",
-	// 			zap.Any("err", err),
-	// 			zap.Any("stack", string(debug.Stack())),
-	// 		)
-	// 		return err.(error)
-	// 	}
-	// 	return nil
-	// }(d)
+	levelStartTime := time.Now()
 	monsters := getMonstersDetailsForLevel(gameState, &level)
+	// Monsters with lower IDs reserve their destinations first (see Coordinator.Reserve)
+	sort.Slice(monsters, func(i, j int) bool {
+		return monsters[i].Id < monsters[j].Id
+	})
+	levelCache := d.newLevelCache(&level)
+	coordinator := NewCoordinator(&level, levelCache.Layout, d.Tuning)
 	d.LoggerWTick.Infow("Handling level",
 		"mapLevel", level.Level,
 		"monstersCount", len(monsters),
+		"layoutHash", levelCache.Layout.Hash,
+		"focusTargets", coordinator.FocusTargets,
+		"underAttackCount", len(coordinator.UnderAttack),
+	)
+	loggerWLevel := d.LoggerWTick.With(
+		"mapLevel", level.Level,
 	)
-	commands := swagger.DungeonsandtrollsCommandsForMonsters{}
-	commands.Commands = make(map[string]swagger.DungeonsandtrollsCommandsBatch)
+	workers := d.workers
+	sink := d.sink
+	if workers == nil {
+		// Called outside of HandleTick
+		workers = make(chan struct{}, d.workerCount())
+		sink = d.newCommandSink(gameState.Tick)
+		defer func() {
+			sink.Flush(loggerWLevel)
+			d.storeDeliveries(sink.Wait())
+		}()
+	}
+	wg := sync.WaitGroup{}
 	for i := range monsters {
 		monster := monsters[i]
-		botLogger := d.LoggerWTick.With(
-			"monsterId", monster.Id,
-			"monsterName", monster.Name,
-			"mapLevel", monster.Level,
-		)
-		d.BotsLock.Lock()
-		bot, found := d.Bots[monster.Id]
-		if !found {
-			// initialize bot / new monster
-			bot = &Bot{
-				MonsterId:   monster.Id,
-				BotState:    BotState{},
-				Config:      NewConfig(monster.Monster.Algorithm),
-				Environment: d.Environment,
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			bot := d.prepareBot(gameState, monster, levelCache, coordinator)
+			if bot == nil {
+				return
 			}
-			d.Bots[monster.Id] = bot
-		} else {
-			// copy previous state
-			bot.PrevBotState = bot.BotState
-			bot.PrevGameState = bot.GameState
-			bot.PrevDetails = bot.Details
-		}
+			cmd := d.runBot(bot)
+			if cmd == nil {
+				return
+			}
+			if d.Recorder != nil {
+				d.Recorder.RecordMonsterCommand(monster.Id, *cmd)
+			}
+			sink.Add(level.Level, monster.Id, *cmd, bot.Logger)
+		}()
+	}
+	wg.Wait()
+	metrics.ObserveLevelDecision(level.Level, time.Since(levelStartTime))
+	sink.FlushLevel(level.Level, loggerWLevel)
+	return nil
+}
+
+// prepareBot finds (or creates) the bot for the monster and hands it the state of this tick
+// Returns nil for dead monsters without a bot (e.g. bot already freed by collectBots)
+func (d *BotDispatcher) prepareBot(gameState *swagger.DungeonsandtrollsGameState, monster MonsterDetails, levelCache *LevelCache, coordinator *Coordinator) *Bot {
+	d.BotsLock.Lock()
+	bot, found := d.Bots[monster.Id]
+	if !found && isMonsterDead(monster.Monster) {
 		d.BotsLock.Unlock()
-		bot.Logger = botLogger
-		bot.GameState = gameState
-		bot.Details = monster
-		cmd := bot.Run()
-		cmd = bot.constructYellCommand(cmd)
-		if cmd != nil {
-			commands.Commands[monster.Id] = *cmd
-			// XXX: send individually
-			sendIndividually := true
-			sendAsynchronously := true
-			if sendIndividually {
-				commandsCopy := swagger.DungeonsandtrollsCommandsForMonsters{
-					Commands: make(map[string]swagger.DungeonsandtrollsCommandsBatch),
-				}
-				for k, v := range commands.Commands {
-					commandsCopy.Commands[k] = v
-				}
-				if sendAsynchronously {
-					go d.sendMonsterCommands(commandsCopy, botLogger)
-				} else {
-					d.sendMonsterCommands(commandsCopy, botLogger)
+		return nil
+	}
+	if !found {
+		// initialize bot / new monster
+		bot = &Bot{
+			MonsterId:   monster.Id,
+			BotState:    BotState{},
+			Environment: d.Environment,
+		}
+		d.Bots[monster.Id] = bot
+	} else {
+		// copy previous state
+		bot.PrevBotState = bot.BotState
+		bot.PrevGameState = bot.GameState
+		bot.PrevDetails = bot.Details
+	}
+	bot.markSeen(gameState.Tick)
+	d.applyOverride(bot, monster, gameState.Tick)
+	d.BotsLock.Unlock()
+	bot.Logger = d.LoggerWTick.With(
+		"monsterId", monster.Id,
+		"monsterName", monster.Name,
+		"mapLevel", monster.Level,
+	)
+	bot.Tuning = d.Tuning
+	bot.GameState = gameState
+	bot.Details = monster
+	bot.LevelCache = levelCache
+	bot.Coordinator = coordinator
+	bot.PlannerExpansions = d.PlannerExpansions
+	bot.Rand = rand.New(rand.NewSource(botSeed(d.Seed, gameState.Tick, monster.Id)))
+	bot.rememberHome()
+	return bot
+}
+
+// countMonstersToRun counts the monsters run in the tick (dead monsters without bots are skipped, see prepareBot)
+func (d *BotDispatcher) countMonstersToRun(gameState *swagger.DungeonsandtrollsGameState) int {
+	d.BotsLock.Lock()
+	defer d.BotsLock.Unlock()
+	count := 0
+	for _, level := range gameState.Map_.Levels {
+		for _, object := range level.Objects {
+			for i := range object.Monsters {
+				monster := &object.Monsters[i]
+				if _, found := d.Bots[monster.Id]; !found && isMonsterDead(monster) {
+					continue
 				}
-				commands.Commands = make(map[string]swagger.DungeonsandtrollsCommandsBatch)
+				count++
 			}
 		}
 	}
-	if len(commands.Commands) > 0 {
-		loggerWLevel := d.LoggerWTick.With(
-			"mapLevel", level,
-		)
-		go d.sendMonsterCommands(commands, loggerWLevel)
+	return count
+}
+
+// botSeed derives the RNG seed of the bot from the tick seed, the tick, and the monster ID
+func botSeed(seed int64, tick int32, monsterId string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(monsterId))
+	return seed ^ int64(tick)<<32 ^ int64(hash.Sum64())
+}
+
+// runBot runs the bot within its time budget (see monsterBudget)
+// A panic in the bot is logged and replaced by no command so that other monsters keep running
+// The bot keeps its state from the previous tick
+func (d *BotDispatcher) runBot(bot *Bot) (cmd *swagger.DungeonsandtrollsCommandsBatch) {
+	budget, degraded := d.monsterBudget()
+	degraded = degraded || d.ForceDegraded[bot.MonsterId]
+	bot.Deadline = time.Time{}
+	if d.TickDeadline > 0 {
+		bot.Deadline = time.Now().Add(budget)
 	}
-	return nil
+	d.tickLock.Lock()
+	d.tickStats.Monsters++
+	if bot.Disabled {
+		d.tickStats.Disabled++
+	} else if degraded {
+		d.tickStats.Degraded++
+		d.tickStats.DegradedMonsters = append(d.tickStats.DegradedMonsters, bot.MonsterId)
+	}
+	d.tickLock.Unlock()
+	if bot.Disabled {
+		bot.Logger.Debugw("Bot disabled by operator, no command sent")
+		bot.LastCommand = nil
+		d.storeSnapshot(bot, nil)
+		return nil
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			bot.Logger.Errorw("PANIC in bot, no command sent",
+				"error", err,
+				"stack", string(debug.Stack()),
+			)
+			bot.Trace.SetFallback("panic")
+			// Half-updated state must not become the previous state of the next tick
+			bot.BotState = bot.PrevBotState
+			bot.LastCommand = nil
+			cmd = nil
+			d.tickLock.Lock()
+			d.tickStats.Panics++
+			d.tickLock.Unlock()
+		}
+		d.emitTrace(bot, cmd)
+		d.storeSnapshot(bot, cmd)
+	}()
+	if degraded {
+		cmd = bot.RunDegraded()
+	} else {
+		cmd = bot.Run()
+	}
+	bot.LastCommand = cmd
+	return bot.constructYellCommand(cmd)
+}
+
+func (d *BotDispatcher) storeSnapshot(bot *Bot, cmd *swagger.DungeonsandtrollsCommandsBatch) {
+	snapshot := bot.takeSnapshot(cmd)
+	d.BotsLock.Lock()
+	bot.Snapshot = snapshot
+	d.BotsLock.Unlock()
+}
+
+// monsterBudget splits the time left until the tick deadline between the monsters left in the tick
+// Returns true if the monster should be degraded
+// Monsters run in parallel so each of them gets the time of all workers
+func (d *BotDispatcher) monsterBudget() (time.Duration, bool) {
+	d.tickLock.Lock()
+	monstersLeft := d.monstersLeft
+	d.monstersLeft--
+	d.tickLock.Unlock()
+	if d.TickDeadline <= 0 {
+		return 0, false
+	}
+	if monstersLeft < 1 {
+		monstersLeft = 1
+	}
+	tickLeft := time.Until(d.TickStartTime.Add(d.TickDeadline))
+	budget := tickLeft * time.Duration(d.workerCount()) / time.Duration(monstersLeft)
+	// No monster can use more than the rest of the tick
+	if budget > tickLeft {
+		budget = tickLeft
+	}
+	return budget, budget < d.MinMonsterBudget
+}
+
+// newLevelCache reuses the level layout from the previous tick when it didn't change
+func (d *BotDispatcher) newLevelCache(level *swagger.DungeonsandtrollsLevel) *LevelCache {
+	d.BotsLock.Lock()
+	defer d.BotsLock.Unlock()
+	if d.levelLayouts == nil {
+		d.levelLayouts = make(map[int32]*LevelLayout)
+	}
+	cache := NewLevelCache(level, d.levelLayouts[level.Level])
+	d.levelLayouts[level.Level] = cache.Layout
+	return cache
+}
+
+func (d *BotDispatcher) isDebugged(monster MonsterDetails) bool {
+	for _, debugged := range d.DebugMonsters {
+		if debugged == "*" || debugged == monster.Id || debugged == monster.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// emitTrace logs the decision trace as a single JSON document and dumps it to TraceDir
+func (d *BotDispatcher) emitTrace(bot *Bot, cmd *swagger.DungeonsandtrollsCommandsBatch) {
+	if bot.Trace == nil {
+		return
+	}
+	bot.Trace.SetCommand(cmd)
+	bot.Trace.Behavior = bot.BotState.Behavior
+	bot.Trace.Threat = bot.BotState.Threat
+	bot.Trace.Triage = bot.BotState.Triage
+	d.BotsLock.Lock()
+	bot.LastTrace = bot.Trace
+	d.BotsLock.Unlock()
+	bot.Logger.Infow("Decision trace",
+		"evaluationsCount", len(bot.Trace.Evaluations),
+		"rejected", bot.Trace.Rejected(),
+		"trace", bot.Trace,
+	)
+	if d.TraceDir != "" {
+		if err := bot.Trace.WriteToDir(d.TraceDir); err != nil {
+			bot.Logger.Errorw("Can't dump decision trace",
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// LastTrace returns the last decision trace of the monster (nil if the monster is not debugged)
+func (d *BotDispatcher) LastTrace(monsterId string) *DecisionTrace {
+	d.BotsLock.Lock()
+	defer d.BotsLock.Unlock()
+	bot, found := d.Bots[monsterId]
+	if !found {
+		return nil
+	}
+	return bot.LastTrace
 }
 
 func getMonstersDetailsForLevel(state *swagger.DungeonsandtrollsGameState, level *swagger.DungeonsandtrollsLevel) []MonsterDetails {
@@ -164,15 +554,95 @@ func getMonstersDetailsForLevel(state *swagger.DungeonsandtrollsGameState, level
 	return monsters
 }
 
-func (d *BotDispatcher) sendMonsterCommands(cmds swagger.DungeonsandtrollsCommandsForMonsters, logger *zap.SugaredLogger) error {
+// SetPaused pauses or resumes the bots from the next tick, returns false if nothing changed
+func (d *BotDispatcher) SetPaused(paused bool) bool {
+	value := int32(0)
+	if paused {
+		value = 1
+	}
+	return atomic.SwapInt32(&d.paused, value) != value
+}
+
+func (d *BotDispatcher) IsPaused() bool {
+	return atomic.LoadInt32(&d.paused) == 1
+}
+
+// WaitForCommands waits until all commands sent asynchronously are delivered (or failed)
+// Returns false on timeout
+func (d *BotDispatcher) WaitForCommands(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		d.sends.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// storeDeliveries reports the delivery status to the bots (see BotSnapshot) and tick stats
+func (d *BotDispatcher) storeDeliveries(statuses map[string]DeliveryStatus) {
+	d.BotsLock.Lock()
+	for id, status := range statuses {
+		if bot, found := d.Bots[id]; found {
+			bot.Delivery = status
+		}
+	}
+	d.BotsLock.Unlock()
+	d.tickLock.Lock()
+	d.tickStats.Deliveries = DeliverySummary(statuses)
+	d.tickLock.Unlock()
+	if failed := failedDeliveries(statuses); len(failed) > 0 {
+		d.LoggerWTick.Warnw("Commands not delivered",
+			"monsterIds", failed,
+		)
+	}
+}
+
+// storeLateDeliveries reports statuses of deliveries finished after the tick deadline to the bots (see HandleTick)
+func (d *BotDispatcher) storeLateDeliveries(sink *commandSink, logger *zap.SugaredLogger) {
+	statuses := sink.Wait()
+	d.BotsLock.Lock()
+	for id, status := range statuses {
+		// Bots may have a newer status already
+		if bot, found := d.Bots[id]; found && bot.Delivery.Tick <= status.Tick {
+			bot.Delivery = status
+		}
+	}
+	d.BotsLock.Unlock()
+	logger.Infow("Late deliveries finished",
+		"deliveries", DeliverySummary(statuses),
+	)
+	if failed := failedDeliveries(statuses); len(failed) > 0 {
+		logger.Warnw("Commands not delivered",
+			"monsterIds", failed,
+		)
+	}
+}
+
+// sendMonsterCommands makes one attempt to deliver the commands (see commandSink.deliver)
+func (d *BotDispatcher) sendMonsterCommands(cmds swagger.DungeonsandtrollsCommandsForMonsters, tickStartTime time.Time, logger *zap.SugaredLogger) error {
+	if d.Sender != nil {
+		return d.Sender.SendMonsterCommands(cmds, logger)
+	}
 	opts := swagger.DungeonsAndTrollsApiDungeonsAndTrollsMonstersCommandsOpts{
 		Blocking: optional.NewBool(false),
 	}
 	_, httpResp, err := d.Client.DungeonsAndTrollsApi.DungeonsAndTrollsMonstersCommands(d.Ctx, cmds, &opts)
-	tickDuration := time.Since(d.TickStartTime)
+	tickDuration := time.Since(tickStartTime)
 	logger2 := logger.With(
 		"tickDurationSeconds", tickDuration,
 	)
 	swaggerutil.LogResponse(logger2, err, httpResp, "MonsterCommands", cmds)
+	if err != nil {
+		sendErr := &SendError{Err: err}
+		if httpResp != nil {
+			sendErr.StatusCode = httpResp.StatusCode
+		}
+		return sendErr
+	}
 	return nil
 }
@@ -0,0 +1,264 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"go.uber.org/zap"
+)
+
+// loadArena returns a fresh copy of fixtures/arena.json (bots keep pointers into the game state)
+func loadArena(tb testing.TB) *swagger.DungeonsandtrollsGameState {
+	tb.Helper()
+	data, err := os.ReadFile("../fixtures/arena.json")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	state := swagger.DungeonsandtrollsGameState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		tb.Fatal(err)
+	}
+	return &state
+}
+
+// newTestDispatcher runs the bots one by one with a fixed seed so that their decisions are reproducible
+func newTestDispatcher() *BotDispatcher {
+	d := NewBotDispatcher(nil, context.Background(), zap.NewNop().Sugar(), "test")
+	d.Sender = &countingSender{}
+	d.CommandTTL = 0
+	d.TickDeadline = 0
+	d.Workers = 1
+	d.Seed = 1
+	d.PlannerExpansions = DeterministicPlannerExpansions
+	return d
+}
+
+// moveCharacter moves the player or monster to the position (tiles missing in the level are added)
+func moveCharacter(state *swagger.DungeonsandtrollsGameState, id string, position swagger.DungeonsandtrollsPosition) {
+	for l := range state.Map_.Levels {
+		level := &state.Map_.Levels[l]
+		var player *swagger.DungeonsandtrollsCharacter
+		var monster *swagger.DungeonsandtrollsMonster
+		for o := range level.Objects {
+			object := &level.Objects[o]
+			for i := range object.Players {
+				if object.Players[i].Id == id {
+					player = &object.Players[i]
+					object.Players = append(object.Players[:i:i], object.Players[i+1:]...)
+					break
+				}
+			}
+			for i := range object.Monsters {
+				if object.Monsters[i].Id == id {
+					monster = &object.Monsters[i]
+					object.Monsters = append(object.Monsters[:i:i], object.Monsters[i+1:]...)
+					break
+				}
+			}
+		}
+		if player == nil && monster == nil {
+			continue
+		}
+		var target *swagger.DungeonsandtrollsMapObjects
+		for o := range level.Objects {
+			if *level.Objects[o].Position == position {
+				target = &level.Objects[o]
+			}
+		}
+		if target == nil {
+			position := position
+			level.Objects = append(level.Objects, swagger.DungeonsandtrollsMapObjects{Position: &position, IsFree: true})
+			target = &level.Objects[len(level.Objects)-1]
+		}
+		if player != nil {
+			target.Players = append(target.Players, *player)
+		}
+		if monster != nil {
+			target.Monsters = append(target.Monsters, *monster)
+		}
+		return
+	}
+}
+
+// findMonster returns the monster in the game state (nil when it's not on any level)
+func findMonster(state *swagger.DungeonsandtrollsGameState, id string) *swagger.DungeonsandtrollsMonster {
+	for l := range state.Map_.Levels {
+		for o := range state.Map_.Levels[l].Objects {
+			for m := range state.Map_.Levels[l].Objects[o].Monsters {
+				if state.Map_.Levels[l].Objects[o].Monsters[m].Id == id {
+					return &state.Map_.Levels[l].Objects[o].Monsters[m]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+type countingSender struct {
+	lock     sync.Mutex
+	commands int
+}
+
+func (s *countingSender) SendMonsterCommands(cmds swagger.DungeonsandtrollsCommandsForMonsters, logger *zap.SugaredLogger) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.commands += len(cmds.Commands)
+	return nil
+}
+
+type lifecycleEvents struct {
+	lock   sync.Mutex
+	events []BotLifecycleEvent
+}
+
+func (l *lifecycleEvents) BotRemoved(event BotLifecycleEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.events = append(l.events, event)
+}
+
+// removeMonster drops the monster from the game state (e.g. it walked to another level)
+func removeMonster(state *swagger.DungeonsandtrollsGameState, monsterId string) {
+	for l := range state.Map_.Levels {
+		objects := state.Map_.Levels[l].Objects
+		for o := range objects {
+			monsters := objects[o].Monsters[:0]
+			for _, monster := range objects[o].Monsters {
+				if monster.Id != monsterId {
+					monsters = append(monsters, monster)
+				}
+			}
+			objects[o].Monsters = monsters
+		}
+	}
+}
+
+// removePlayer drops the player from the game state
+func removePlayer(state *swagger.DungeonsandtrollsGameState, playerId string) {
+	for l := range state.Map_.Levels {
+		objects := state.Map_.Levels[l].Objects
+		for o := range objects {
+			players := objects[o].Players[:0]
+			for _, player := range objects[o].Players {
+				if player.Id != playerId {
+					players = append(players, player)
+				}
+			}
+			objects[o].Players = players
+		}
+	}
+}
+
+// Run with -race, operator calls (see the admin package) run concurrently with ticks
+func TestHandleTickConcurrentWithOperators(t *testing.T) {
+	d := NewBotDispatcher(nil, context.Background(), zap.NewNop().Sugar(), "test")
+	sender := &countingSender{}
+	lifecycle := &lifecycleEvents{}
+	d.Sender = sender
+	d.Lifecycle = lifecycle
+	d.CommandTTL = 0
+	d.DespawnTicks = 2
+	d.Workers = 4
+	d.DebugMonsters = []string{"*"}
+
+	done := make(chan struct{})
+	operators := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		operators.Add(1)
+		go func(i int) {
+			defer operators.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, snapshot := range d.BotSnapshots() {
+					override := BotOverride{Debug: true}
+					if i%2 == 0 {
+						override.Behavior = BehaviorFlee
+					}
+					// The bot can be freed in the meantime
+					if err := d.SetBotOverride(snapshot.MonsterId, override); err != nil && err != ErrBotNotTracked {
+						t.Error(err)
+					}
+					d.LastTrace(snapshot.MonsterId)
+					d.BotSnapshot(snapshot.MonsterId)
+				}
+				d.RemoveBotOverride("monster-1")
+				d.DisabledLevels()
+				d.Tick()
+			}
+		}(i)
+	}
+
+	for tick := int32(1); tick <= 12; tick++ {
+		state := loadArena(t)
+		state.Tick = tick
+		if tick > 4 {
+			// Despawned after DespawnTicks
+			removeMonster(state, "monster-2")
+		}
+		if err := d.HandleTick(state, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	operators.Wait()
+
+	if sender.commands == 0 {
+		t.Error("no commands sent")
+	}
+	if len(lifecycle.events) != 1 || lifecycle.events[0].MonsterId != "monster-2" || lifecycle.events[0].Reason != BotRemovedDespawned {
+		t.Errorf("expected monster-2 to be despawned, got %+v", lifecycle.events)
+	}
+	if _, found := d.BotSnapshot("monster-2"); found {
+		t.Error("snapshot of a freed bot")
+	}
+}
+
+// A panicking bot sends no command and keeps its last good state, the other monsters keep running
+func TestPanicIsIsolated(t *testing.T) {
+	d := newTestDispatcher()
+	state := loadArena(t)
+	state.Tick = 1
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	previous := d.Bots["monster-1"].BotState
+
+	state = loadArena(t)
+	state.Tick = 2
+	// Slash without range
+	findMonster(state, "monster-1").EquippedItems[0].Skills[0].Range_ = nil
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if d.LastTickStats.Panics != 1 {
+		t.Errorf("expected 1 panic, got %d", d.LastTickStats.Panics)
+	}
+	panicked, other := d.Bots["monster-1"], d.Bots["monster-2"]
+	if panicked.LastCommand != nil {
+		t.Errorf("panicked bot sent command %+v", panicked.LastCommand)
+	}
+	if other.LastCommand == nil {
+		t.Error("other monster got no command")
+	}
+	if panicked.BotState.BehaviorTicks != previous.BehaviorTicks || panicked.BotState.Self.GetEquippedItems()[0].Skills[0].Range_ == nil {
+		t.Errorf("panicked bot kept half-updated state (behavior ticks %d, previous %d)", panicked.BotState.BehaviorTicks, previous.BehaviorTicks)
+	}
+
+	state = loadArena(t)
+	state.Tick = 3
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if d.LastTickStats.Panics != 0 || d.Bots["monster-1"].LastCommand == nil {
+		t.Errorf("bot didn't recover after the panic (%d panics)", d.LastTickStats.Panics)
+	}
+}
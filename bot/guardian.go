@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"math"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// GuardObject is the kind of object a guardian protects (see Config.GuardObject)
+type GuardObject string
+
+const (
+	GuardObjectNone   GuardObject = ""
+	GuardObjectStairs GuardObject = "stairs"
+	GuardObjectPortal GuardObject = "portal"
+	// Neutral monsters (algorithm "none")
+	GuardObjectChest GuardObject = "chest"
+)
+
+var guardObjects = []GuardObject{GuardObjectNone, GuardObjectStairs, GuardObjectPortal, GuardObjectChest}
+
+// GuardedObject is the object the monster is bound to (see bindGuardedObject)
+type GuardedObject struct {
+	Kind     GuardObject                       `json:"kind"`
+	Position swagger.DungeonsandtrollsPosition `json:"position"`
+	// ID of the chest monster (empty for stairs and portals)
+	Id string `json:"id,omitempty"`
+}
+
+// bindGuardedObject binds the monster to the closest object of Config.GuardObject within Tuning.Guardian.BindRadius of home
+// The binding is kept while the object exists (chests can be destroyed), then the next closest object is bound
+func (b *Bot) bindGuardedObject() {
+	kind := b.Config.GuardObject
+	guarded := b.BotState.Guarded
+	candidates := b.guardCandidates(kind)
+	if guarded != nil {
+		if guarded.Kind == kind {
+			for _, candidate := range candidates {
+				if candidate == *guarded {
+					return
+				}
+			}
+		}
+		b.Logger.Infow("Guarded object lost",
+			"guardedObject", guarded,
+		)
+		b.BotState.Guarded = nil
+	}
+	home := b.homePosition()
+	if kind == GuardObjectNone || home == nil {
+		return
+	}
+	layout := b.levelCache().Layout
+	closestDistance := b.Tuning.Guardian.BindRadius
+	for i := range candidates {
+		distance := layout.Distance(*home, candidates[i].Position)
+		if distance <= closestDistance {
+			b.BotState.Guarded = &candidates[i]
+			closestDistance = distance
+		}
+	}
+	if b.BotState.Guarded == nil {
+		return
+	}
+	b.Logger.Infow("Guarding object",
+		"guardedObject", b.BotState.Guarded,
+		"distanceFromHome", closestDistance,
+	)
+	if b.BotState.Behavior == BehaviorGuard {
+		b.BotState.BehaviorPosition = b.guardPost()
+	}
+}
+
+func (b *Bot) guardCandidates(kind GuardObject) []GuardedObject {
+	objects := b.BotState.Objects
+	candidates := []GuardedObject{}
+	switch kind {
+	case GuardObjectStairs:
+		if objects.Stairs != nil {
+			candidates = append(candidates, GuardedObject{Kind: kind, Position: *objects.Stairs.Position})
+		}
+	case GuardObjectPortal:
+		for _, portal := range objects.Portals {
+			candidates = append(candidates, GuardedObject{Kind: kind, Position: *portal.Position})
+		}
+	case GuardObjectChest:
+		for _, neutral := range objects.Neutral {
+			if neutral.Type == MapObjectTypeMonster {
+				candidates = append(candidates, GuardedObject{Kind: kind, Position: *neutral.GetPosition(), Id: neutral.GetId()})
+			}
+		}
+	}
+	return candidates
+}
+
+// Chests can't be stood on, players open them from an adjacent tile
+func (b *Bot) onGuardedObject(position swagger.DungeonsandtrollsPosition) bool {
+	guarded := b.BotState.Guarded
+	if guarded == nil {
+		return false
+	}
+	if guarded.Kind == GuardObjectChest {
+		return manhattanDistance(position, guarded.Position) <= 1
+	}
+	return position == guarded.Position
+}
+
+// guardIntruders are hostile players within Tuning.Guardian.ThreatRadius (walking distance) of the guarded object
+func (b *Bot) guardIntruders() []MapObject {
+	guarded := b.BotState.Guarded
+	if guarded == nil {
+		return nil
+	}
+	layout := b.levelCache().Layout
+	intruders := []MapObject{}
+	for _, hostile := range b.BotState.Objects.Hostile {
+		if hostile.Type != MapObjectTypePlayer {
+			continue
+		}
+		if layout.Distance(*hostile.GetPosition(), guarded.Position) <= b.Tuning.Guardian.ThreatRadius {
+			intruders = append(intruders, hostile)
+		}
+	}
+	return intruders
+}
+
+// scoreBlocking is the share of intruders whose shortest path to the guarded object goes through the position
+// (up to Tuning.Guardian.PathSlack extra tiles)
+func (b *Bot) scoreBlocking(position *swagger.DungeonsandtrollsPosition) float32 {
+	guarded := b.BotState.Guarded
+	if guarded == nil {
+		return 0
+	}
+	intruders := b.guardIntruders()
+	if len(intruders) == 0 {
+		return 0
+	}
+	layout := b.levelCache().Layout
+	toObject := layout.Distance(*position, guarded.Position)
+	if toObject == math.MaxInt32 {
+		return 0
+	}
+	blocked := 0
+	for _, intruder := range intruders {
+		intruderPosition := *intruder.GetPosition()
+		direct := layout.Distance(intruderPosition, guarded.Position)
+		throughPosition := layout.Distance(intruderPosition, *position)
+		if throughPosition == math.MaxInt32 {
+			continue
+		}
+		if int64(throughPosition)+int64(toObject) <= int64(direct)+int64(b.Tuning.Guardian.PathSlack) {
+			blocked++
+		}
+	}
+	return float32(blocked) / float32(len(intruders))
+}
+
+// guardianBonus prioritises damaging players standing on the guarded object or approaching it
+func (b *Bot) guardianBonus(target MapObject, result SkillResult) float32 {
+	guarded := b.BotState.Guarded
+	if guarded == nil || result.VitalsHostile >= 0 || target.Type != MapObjectTypePlayer || !b.IsHostile(target) {
+		return 0
+	}
+	t := b.Tuning.Guardian
+	position := *target.GetPosition()
+	if b.onGuardedObject(position) {
+		return t.OnObjectBonus
+	}
+	layout := b.levelCache().Layout
+	distance := layout.Distance(position, guarded.Position)
+	if distance > t.ThreatRadius {
+		return 0
+	}
+	previous, found := b.previousPlayerPosition(target.GetId())
+	if !found || layout.Distance(previous, guarded.Position) <= distance {
+		// Not approaching
+		return 0
+	}
+	return t.ApproachBonus * (1 - float32(distance)/float32(t.ThreatRadius+1))
+}
+
+func (b *Bot) previousPlayerPosition(id string) (swagger.DungeonsandtrollsPosition, bool) {
+	if b.PrevDetails.CurrentMap == nil {
+		return swagger.DungeonsandtrollsPosition{}, false
+	}
+	for _, object := range b.PrevDetails.CurrentMap.Objects {
+		for _, player := range object.Players {
+			if player.Id == id && object.Position != nil {
+				return *object.Position, true
+			}
+		}
+	}
+	return swagger.DungeonsandtrollsPosition{}, false
+}
+
+// guardPost is the guarded object or the position where the monster started guarding
+func (b *Bot) guardPost() *swagger.DungeonsandtrollsPosition {
+	if b.BotState.Guarded != nil {
+		post := b.BotState.Guarded.Position
+		return &post
+	}
+	post := *b.Details.Position
+	return &post
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+func TestScoreBlocking(t *testing.T) {
+	// Monster-1 guards the stairs at (12, 7), intruders are players within 6 steps of the stairs
+	tests := []struct {
+		name      string
+		guard     GuardObject
+		pathSlack int32
+		players   []swagger.DungeonsandtrollsPosition
+		position  swagger.DungeonsandtrollsPosition
+		score     float32
+	}{
+		{"nothing guarded", GuardObjectNone, 1, []swagger.DungeonsandtrollsPosition{makePosition(12, 3)}, makePosition(12, 5), 0},
+		{"no intruders", GuardObjectStairs, 1, []swagger.DungeonsandtrollsPosition{makePosition(4, 7)}, makePosition(11, 7), 0},
+		{"on the shortest path", GuardObjectStairs, 1, []swagger.DungeonsandtrollsPosition{makePosition(12, 3)}, makePosition(12, 5), 1},
+		{"on the guarded object", GuardObjectStairs, 1, []swagger.DungeonsandtrollsPosition{makePosition(12, 3)}, makePosition(12, 7), 1},
+		// Stepping aside costs 2 extra steps
+		{"off the shortest path", GuardObjectStairs, 1, []swagger.DungeonsandtrollsPosition{makePosition(12, 3)}, makePosition(11, 5), 0},
+		{"within path slack", GuardObjectStairs, 2, []swagger.DungeonsandtrollsPosition{makePosition(12, 3)}, makePosition(11, 5), 1},
+		{"behind the intruder", GuardObjectStairs, 1, []swagger.DungeonsandtrollsPosition{makePosition(12, 3)}, makePosition(12, 2), 0},
+		{"one of two intruders", GuardObjectStairs, 1, []swagger.DungeonsandtrollsPosition{makePosition(12, 3), makePosition(8, 7)}, makePosition(12, 5), 0.5},
+		{"other of two intruders", GuardObjectStairs, 1, []swagger.DungeonsandtrollsPosition{makePosition(12, 3), makePosition(8, 7)}, makePosition(11, 7), 0.5},
+		{"both intruders", GuardObjectStairs, 1, []swagger.DungeonsandtrollsPosition{makePosition(12, 3), makePosition(8, 7)}, makePosition(12, 7), 1},
+		{"wall", GuardObjectStairs, 1, []swagger.DungeonsandtrollsPosition{makePosition(12, 3)}, makePosition(13, 5), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newTestDispatcher()
+			profiles, err := ParseConfigProfiles([]byte(fmt.Sprintf(`{"berserker": {"guardObject": %q}}`, test.guard)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.Profiles = profiles
+			tuning := DefaultTuning()
+			tuning.Guardian.PathSlack = test.pathSlack
+			d.Tuning = &tuning
+			state := loadArena(t)
+			moveCharacter(state, "player-1", test.players[0])
+			for i, position := range test.players[1:] {
+				clonePlayer(state, "player-1", fmt.Sprintf("player-%d", i+2), position)
+			}
+			if err := d.HandleTick(state, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			bot := d.Bots["monster-1"]
+			if test.guard != GuardObjectNone && (bot.BotState.Guarded == nil || bot.BotState.Guarded.Position != makePosition(12, 7)) {
+				t.Fatalf("expected the stairs guarded, got %+v", bot.BotState.Guarded)
+			}
+			position := test.position
+			if score := bot.scoreBlocking(&position); score != test.score {
+				t.Errorf("expected blocking score %v, got %v", test.score, score)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package bot
+
+import (
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// rememberHome keeps the position where the monster was first seen (again after moving to another level)
+func (b *Bot) rememberHome() {
+	if b.BotState.Home != nil && b.BotState.HomeLevel == b.Details.Level {
+		return
+	}
+	home := *b.Details.Position
+	if b.BotState.Home != nil {
+		b.Logger.Infow("Monster moved to another level, new home",
+			"previousHome", b.BotState.Home,
+			"previousHomeLevel", b.BotState.HomeLevel,
+			"home", home,
+		)
+	}
+	b.BotState.Home = &home
+	b.BotState.HomeLevel = b.Details.Level
+	b.BotState.PatrolRoute = nil
+}
+
+// Home of the monster or the level spawn for bots that don't remember their home
+func (b *Bot) homePosition() *swagger.DungeonsandtrollsPosition {
+	if b.BotState.Home != nil {
+		return b.BotState.Home
+	}
+	if b.BotState.Objects.Spawn == nil {
+		return nil
+	}
+	return b.BotState.Objects.Spawn.Position
+}
+
+func (b *Bot) homeReached() bool {
+	home := b.homePosition()
+	return home == nil || manhattanDistance(*b.Details.Position, *home) <= b.Tuning.Behavior.SpawnReachedDistance
+}
+
+// leashBroken is true when the monster was pulled further than Config.LeashRadius from home
+func (b *Bot) leashBroken() bool {
+	home := b.homePosition()
+	return b.Config.LeashRadius > 0 && home != nil && manhattanDistance(*b.Details.Position, *home) > b.Config.LeashRadius
+}
+
+// Counts ticks without any hostile in line of sight (see Tuning.Behavior.EvadeIdleTicks)
+func (b *Bot) updateTicksWithoutHostiles() {
+	for _, hostile := range b.BotState.Objects.Hostile {
+		if b.BotState.MapExtended.At(*hostile.GetPosition()).lineOfSight {
+			if b.BotState.TicksWithoutHostiles > 0 && isIdleBehavior(b.BotState.Behavior) {
+				b.Logger.Infow("Hostile in sight, idle interrupted",
+					"hostileName", hostile.GetName(),
+					"hostilePosition", hostile.GetPosition(),
+				)
+				// Stop wandering or patrolling
+				b.BotState.TargetPosition = nil
+			}
+			b.BotState.TicksWithoutHostiles = 0
+			return
+		}
+	}
+	b.BotState.TicksWithoutHostiles++
+}
+
+// isResting is true while the evading monster regenerates at home (with out of combat skills)
+func (b *Bot) isResting() bool {
+	t := b.Tuning.Behavior
+	return b.getCurrentVitalsRatio() < t.EvadeRestVitals && b.BotState.RestTicks < t.EvadeMaxRestTicks
+}
+
+// evadeMove walks the cheapest path home, evading monsters don't stop to fight on the way
+func (b *Bot) evadeMove() *swagger.DungeonsandtrollsCommandsBatch {
+	home := b.homePosition()
+	if b.BotState.Behavior != BehaviorEvade || home == nil || b.homeReached() {
+		return nil
+	}
+	move := b.stepTowards(*home)
+	if move == nil {
+		b.Logger.Warnw("No path home, evading monster falls back to skill evaluation",
+			"home", home,
+		)
+		return nil
+	}
+	b.addFirstYell("Going home")
+	b.Logger.Infow("Evading home",
+		"home", home,
+	)
+	return move
+}
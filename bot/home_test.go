@@ -0,0 +1,228 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// pulledAway moves monster-1 with the player next to it to the position
+func pulledAway(position swagger.DungeonsandtrollsPosition, hostile swagger.DungeonsandtrollsPosition) func(state *swagger.DungeonsandtrollsGameState) {
+	return func(state *swagger.DungeonsandtrollsGameState) {
+		moveCharacter(state, "monster-1", position)
+		moveCharacter(state, "player-1", hostile)
+	}
+}
+
+// restingAtHome puts monster-1 home with the vitals ratio, the player is out of sight
+func restingAtHome(ratio float32) func(state *swagger.DungeonsandtrollsGameState) {
+	return func(state *swagger.DungeonsandtrollsGameState) {
+		setVitals(findMonster(state, "monster-1"), ratio)
+	}
+}
+
+func TestLeashAndEvade(t *testing.T) {
+	// Monster-1 is at home (10, 2) in the first tick
+	tests := []struct {
+		name     string
+		profiles string
+		tuning   func(tuning *BehaviorTuning)
+		ticks    []func(state *swagger.DungeonsandtrollsGameState)
+		behavior Behavior
+		// Evading monsters walk home instead of evaluating skills
+		evadeMove bool
+	}{
+		{
+			name:     "leash holds within radius",
+			profiles: `{"berserker": {"leashRadius": 5}}`,
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+				pulledAway(makePosition(10, 6), makePosition(9, 6)),
+			},
+			behavior: BehaviorEngage,
+		},
+		{
+			name:     "leash breaks beyond radius",
+			profiles: `{"berserker": {"leashRadius": 5}}`,
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+				pulledAway(makePosition(4, 6), makePosition(3, 6)),
+			},
+			behavior:  BehaviorEvade,
+			evadeMove: true,
+		},
+		{
+			name:     "no leash with zero radius",
+			profiles: `{"berserker": {"leashRadius": 0}}`,
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+				pulledAway(makePosition(4, 6), makePosition(3, 6)),
+			},
+			behavior: BehaviorEngage,
+		},
+		{
+			name:     "evading monsters ignore hostiles until home",
+			profiles: `{"berserker": {"leashRadius": 5}}`,
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+				pulledAway(makePosition(4, 6), makePosition(3, 6)),
+				// Within the leash but further than SpawnReachedDistance
+				pulledAway(makePosition(8, 5), makePosition(8, 6)),
+			},
+			behavior:  BehaviorEvade,
+			evadeMove: true,
+		},
+		{
+			name:     "rest at home",
+			profiles: `{"berserker": {"leashRadius": 5}}`,
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+				pulledAway(makePosition(4, 6), makePosition(3, 6)),
+				restingAtHome(0.3),
+			},
+			behavior: BehaviorEvade,
+		},
+		{
+			name:     "stop resting when vitals recover",
+			profiles: `{"berserker": {"leashRadius": 5}}`,
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+				pulledAway(makePosition(4, 6), makePosition(3, 6)),
+				restingAtHome(0.3),
+				restingAtHome(1),
+			},
+			behavior: BehaviorIdle,
+		},
+		{
+			name:     "stop resting after max rest ticks",
+			profiles: `{"berserker": {"leashRadius": 5}}`,
+			tuning:   func(tuning *BehaviorTuning) { tuning.EvadeMaxRestTicks = 1 },
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+				pulledAway(makePosition(4, 6), makePosition(3, 6)),
+				restingAtHome(0.3),
+				restingAtHome(0.3),
+			},
+			behavior: BehaviorIdle,
+		},
+		{
+			name:   "evade after idle ticks away from home",
+			tuning: func(tuning *BehaviorTuning) { tuning.EvadeIdleTicks = 2 },
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+				// Out of sight of the player behind the wall
+				pulledAway(makePosition(12, 7), makePosition(1, 1)),
+			},
+			behavior:  BehaviorEvade,
+			evadeMove: true,
+		},
+		{
+			name:   "no evade before idle ticks",
+			tuning: func(tuning *BehaviorTuning) { tuning.EvadeIdleTicks = 3 },
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+				pulledAway(makePosition(12, 7), makePosition(1, 1)),
+			},
+			behavior: BehaviorIdle,
+		},
+		{
+			name:     "patrolling monsters don't evade",
+			profiles: `{"berserker": {"idleBehavior": "patrol"}}`,
+			tuning:   func(tuning *BehaviorTuning) { tuning.EvadeIdleTicks = 2 },
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+				pulledAway(makePosition(12, 7), makePosition(1, 1)),
+			},
+			behavior: BehaviorPatrol,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newTestDispatcher()
+			if test.profiles != "" {
+				profiles, err := ParseConfigProfiles([]byte(test.profiles))
+				if err != nil {
+					t.Fatal(err)
+				}
+				d.Profiles = profiles
+			}
+			tuning := DefaultTuning()
+			if test.tuning != nil {
+				test.tuning(&tuning.Behavior)
+			}
+			d.Tuning = &tuning
+			for i, change := range test.ticks {
+				state := loadArena(t)
+				state.Tick = int32(i + 1)
+				if change != nil {
+					change(state)
+				}
+				if err := d.HandleTick(state, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			bot := d.Bots["monster-1"]
+			if bot.BotState.Behavior != test.behavior {
+				t.Errorf("expected behavior %s, got %s", test.behavior, bot.BotState.Behavior)
+			}
+			if *bot.BotState.Home != makePosition(10, 2) {
+				t.Errorf("expected home (10, 2), got %v", *bot.BotState.Home)
+			}
+			move := bot.evadeMove()
+			if !test.evadeMove {
+				if move != nil {
+					t.Errorf("unexpected evade move to %v", *move.Move)
+				}
+				return
+			}
+			if move == nil || move.Move == nil {
+				t.Fatal("expected evade move")
+			}
+			if manhattanDistance(*move.Move, *bot.BotState.Home) >= manhattanDistance(*bot.Details.Position, *bot.BotState.Home) {
+				t.Errorf("evade move from %v to %v doesn't lead home", *bot.Details.Position, *move.Move)
+			}
+		})
+	}
+}
+
+func TestHomePosition(t *testing.T) {
+	d := newTestDispatcher()
+	if err := d.HandleTick(loadArena(t), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	bot := d.Bots["monster-1"]
+	spawn := makePosition(1, 1)
+	home := makePosition(10, 2)
+	tests := []struct {
+		name      string
+		home      *swagger.DungeonsandtrollsPosition
+		homeLevel int32
+		// Home after rememberHome
+		expected swagger.DungeonsandtrollsPosition
+	}{
+		{"first seen", nil, 0, home},
+		{"remembered", &spawn, 1, spawn},
+		{"moved to another level", &spawn, 2, home},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bot.BotState.Home = test.home
+			bot.BotState.HomeLevel = test.homeLevel
+			bot.BotState.PatrolRoute = []swagger.DungeonsandtrollsPosition{spawn}
+			bot.rememberHome()
+			if *bot.homePosition() != test.expected || bot.BotState.HomeLevel != 1 {
+				t.Errorf("expected home %v on level 1, got %v on level %d", test.expected, *bot.homePosition(), bot.BotState.HomeLevel)
+			}
+			// Patrol routes start from home
+			if routeKept := bot.BotState.PatrolRoute != nil; routeKept != (test.home != nil && test.homeLevel == 1) {
+				t.Errorf("unexpected patrol route %v", bot.BotState.PatrolRoute)
+			}
+		})
+	}
+	// Bots that don't remember their home use the level spawn
+	bot.BotState.Home = nil
+	if *bot.homePosition() != spawn {
+		t.Errorf("expected spawn %v, got %v", spawn, *bot.homePosition())
+	}
+}
@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"math"
+	"sort"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+func isIdleBehavior(behavior Behavior) bool {
+	for _, idle := range idleBehaviors {
+		if behavior == idle {
+			return true
+		}
+	}
+	return false
+}
+
+// idleMove keeps monsters busy while no hostile is in sight (see Config.IdleBehavior):
+// idle monsters wander around home, patrolling monsters walk their route, and guards stay at their post
+func (b *Bot) idleMove() *swagger.DungeonsandtrollsCommandsBatch {
+	if b.BotState.TicksWithoutHostiles == 0 {
+		return nil
+	}
+	switch b.BotState.Behavior {
+	case BehaviorIdle:
+		return b.randomWalk()
+	case BehaviorPatrol:
+		return b.patrolMove()
+	case BehaviorGuard:
+		return b.sentryMove()
+	}
+	return nil
+}
+
+// randomWalk picks a new destination near home with probability Tuning.Idle.WanderChance * Config.Restlessness
+func (b *Bot) randomWalk() *swagger.DungeonsandtrollsCommandsBatch {
+	t := b.Tuning.Idle
+	if b.BotState.TargetPosition == nil {
+		if b.Rand.Float32() >= t.WanderChance*b.Config.Restlessness {
+			return nil
+		}
+		destination := b.wanderDestination()
+		if destination == nil {
+			b.Logger.Warnw("randomWalk: No free position found")
+			return nil
+		}
+		b.BotState.TargetPosition = destination
+		b.BotState.TargetPositionTimeout = t.WanderTimeout
+		b.Logger.Infow("Wandering",
+			"targetPosition", destination,
+		)
+	}
+	return b.stepTowards(*b.BotState.TargetPosition)
+}
+
+// wanderDestination is a random free tile within Tuning.Idle.WanderRadius of home the monster can walk to
+func (b *Bot) wanderDestination() *swagger.DungeonsandtrollsPosition {
+	radius := b.Tuning.Idle.WanderRadius
+	center := *b.Details.Position
+	if home := b.homePosition(); home != nil {
+		center = *home
+	}
+	for i := 0; i < 16; i++ {
+		position := makePosition(
+			center.PositionX+b.Rand.Int31n(2*radius+1)-radius,
+			center.PositionY+b.Rand.Int31n(2*radius+1)-radius,
+		)
+		if position == *b.Details.Position {
+			continue
+		}
+		tileInfo, found := b.BotState.MapExtended.Lookup(position)
+		if !found || !tileInfo.mapObjects.IsFree || tileInfo.distance == math.MaxInt32 || b.isOccupied(position) {
+			// unreachable or not free
+			continue
+		}
+		if _, reachable := b.BotState.Paths.Cost(position); !reachable {
+			continue
+		}
+		return &position
+	}
+	return nil
+}
+
+// patrolMove walks the patrol route in a loop (random walk when there are no waypoints around home)
+func (b *Bot) patrolMove() *swagger.DungeonsandtrollsCommandsBatch {
+	route := b.patrolRoute()
+	if len(route) < 2 {
+		return b.randomWalk()
+	}
+	index := b.BotState.PatrolIndex % len(route)
+	if manhattanDistance(*b.Details.Position, route[index]) <= b.Tuning.Idle.PatrolReachedDistance {
+		index = (index + 1) % len(route)
+		b.Logger.Debugw("Patrol waypoint reached",
+			"nextWaypoint", route[index],
+			"patrolIndex", index,
+		)
+	}
+	b.BotState.PatrolIndex = index
+	waypoint := route[index]
+	b.BotState.TargetPosition = &waypoint
+	b.BotState.TargetPositionTimeout = 1
+	return b.stepTowards(waypoint)
+}
+
+// patrolRoute starts at home and visits up to Tuning.Idle.PatrolMaxWaypoints closest waypoints (see LevelLayout.Waypoints)
+// The route is computed once per home
+func (b *Bot) patrolRoute() []swagger.DungeonsandtrollsPosition {
+	if b.BotState.PatrolRoute != nil {
+		return b.BotState.PatrolRoute
+	}
+	t := b.Tuning.Idle
+	home := b.homePosition()
+	if home == nil {
+		return nil
+	}
+	layout := b.levelCache().Layout
+	type candidate struct {
+		position swagger.DungeonsandtrollsPosition
+		distance int32
+	}
+	candidates := []candidate{}
+	for _, waypoint := range layout.Waypoints(t.MinRoomTiles, t.WaypointSpacing) {
+		distance := layout.Distance(*home, waypoint)
+		if distance > t.PatrolRadius || manhattanDistance(*home, waypoint) <= t.PatrolReachedDistance {
+			continue
+		}
+		candidates = append(candidates, candidate{position: waypoint, distance: distance})
+	}
+	// Waypoints are in a fixed order, the stable sort keeps routes reproducible
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	if len(candidates) > t.PatrolMaxWaypoints {
+		candidates = candidates[:t.PatrolMaxWaypoints]
+	}
+	// Nearest neighbour order, the route loops back home
+	route := []swagger.DungeonsandtrollsPosition{*home}
+	for len(candidates) > 0 {
+		last := route[len(route)-1]
+		nearest := 0
+		for i := range candidates {
+			if layout.Distance(last, candidates[i].position) < layout.Distance(last, candidates[nearest].position) {
+				nearest = i
+			}
+		}
+		route = append(route, candidates[nearest].position)
+		candidates = append(candidates[:nearest], candidates[nearest+1:]...)
+	}
+	b.BotState.PatrolRoute = route
+	b.BotState.PatrolIndex = 0
+	b.Logger.Infow("Patrol route planned",
+		"patrolRoute", route,
+	)
+	return route
+}
+
+// sentryMove returns the guard to its post, guards don't wander
+func (b *Bot) sentryMove() *swagger.DungeonsandtrollsCommandsBatch {
+	post := b.BotState.BehaviorPosition
+	if post == nil || *post == *b.Details.Position {
+		return nil
+	}
+	// Guarded chests occupy the post
+	if b.isOccupied(*post) && manhattanDistance(*post, *b.Details.Position) <= 1 {
+		return nil
+	}
+	return b.stepTowards(*post)
+}
+
+// stepTowards moves one step along the cheapest path (nil when there is no path)
+func (b *Bot) stepTowards(destination swagger.DungeonsandtrollsPosition) *swagger.DungeonsandtrollsCommandsBatch {
+	path, found := b.BotState.Paths.PathTo(destination)
+	if !found {
+		// Destination is further than Tuning.Movement.PathSearchMaxCost
+		path, found = b.levelGrid().FindPath(*b.Details.Position, destination)
+	}
+	if !found || path.Next() == nil {
+		return nil
+	}
+	move := path.Next()
+	b.reserveDestination(move)
+	b.Logger.Debugw("Stepping towards destination",
+		"destination", destination,
+		"pathLength", path.Len(),
+		"pathCost", path.Cost,
+	)
+	return &swagger.DungeonsandtrollsCommandsBatch{
+		Move: move,
+	}
+}
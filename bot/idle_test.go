@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// newPatrolDispatcher makes monster-1 patrol the arena (waypoints (5, 4) and (7, 1))
+func newPatrolDispatcher(t *testing.T, tuning func(tuning *IdleTuning)) *BotDispatcher {
+	t.Helper()
+	d := newTestDispatcher()
+	profiles, err := ParseConfigProfiles([]byte(`{"berserker": {"idleBehavior": "patrol"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Profiles = profiles
+	defaults := DefaultTuning()
+	if tuning != nil {
+		tuning(&defaults.Idle)
+	}
+	d.Tuning = &defaults
+	return d
+}
+
+// runPatrolTick runs a tick with monster-1 at the position and no players in the arena
+func runPatrolTick(t *testing.T, d *BotDispatcher, tick int32, position swagger.DungeonsandtrollsPosition) *Bot {
+	t.Helper()
+	state := loadArena(t)
+	state.Tick = tick
+	removePlayer(state, "player-1")
+	moveCharacter(state, "monster-1", position)
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return d.Bots["monster-1"]
+}
+
+func TestPatrolRoute(t *testing.T) {
+	home := makePosition(10, 2)
+	tests := []struct {
+		name   string
+		tuning func(tuning *IdleTuning)
+		route  []swagger.DungeonsandtrollsPosition
+	}{
+		{
+			name:  "closest waypoint first",
+			route: []swagger.DungeonsandtrollsPosition{home, makePosition(7, 1), makePosition(5, 4)},
+		},
+		{
+			name:   "max waypoints",
+			tuning: func(tuning *IdleTuning) { tuning.PatrolMaxWaypoints = 1 },
+			route:  []swagger.DungeonsandtrollsPosition{home, makePosition(7, 1)},
+		},
+		{
+			// (5, 4) is 9 steps away around the wall
+			name:   "walking distance within patrol radius",
+			tuning: func(tuning *IdleTuning) { tuning.PatrolRadius = 8 },
+			route:  []swagger.DungeonsandtrollsPosition{home, makePosition(7, 1)},
+		},
+		{
+			name:   "waypoints at home skipped",
+			tuning: func(tuning *IdleTuning) { tuning.PatrolReachedDistance = 4 },
+			route:  []swagger.DungeonsandtrollsPosition{home, makePosition(5, 4)},
+		},
+		{
+			name:   "no waypoints around home",
+			tuning: func(tuning *IdleTuning) { tuning.PatrolRadius = 3 },
+			route:  []swagger.DungeonsandtrollsPosition{home},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newPatrolDispatcher(t, test.tuning)
+			bot := runPatrolTick(t, d, 1, home)
+			if route := bot.patrolRoute(); !equalPositions(route, test.route) {
+				t.Errorf("expected route %v, got %v", test.route, route)
+			}
+		})
+	}
+}
+
+func TestPatrolMove(t *testing.T) {
+	d := newPatrolDispatcher(t, nil)
+	// The monster reaches the waypoints one by one and walks the route in a loop
+	ticks := []struct {
+		position swagger.DungeonsandtrollsPosition
+		index    int
+		target   swagger.DungeonsandtrollsPosition
+	}{
+		{makePosition(10, 2), 1, makePosition(7, 1)},
+		{makePosition(9, 1), 1, makePosition(7, 1)},
+		// Waypoint reached, the monster heads to the next one
+		{makePosition(7, 1), 2, makePosition(5, 4)},
+		{makePosition(5, 4), 0, makePosition(10, 2)},
+		{makePosition(10, 2), 1, makePosition(7, 1)},
+	}
+	for i, tick := range ticks {
+		bot := runPatrolTick(t, d, int32(i+1), tick.position)
+		if bot.BotState.Behavior != BehaviorPatrol {
+			t.Fatalf("tick %d: expected patrol, got %s", i+1, bot.BotState.Behavior)
+		}
+		if bot.BotState.PatrolIndex != tick.index || bot.BotState.TargetPosition == nil || *bot.BotState.TargetPosition != tick.target {
+			t.Errorf("tick %d: expected waypoint %d %v, got %d %v", i+1, tick.index, tick.target, bot.BotState.PatrolIndex, bot.BotState.TargetPosition)
+		}
+		// The route is planned once per home
+		if *bot.BotState.Home != makePosition(10, 2) || len(bot.BotState.PatrolRoute) != 3 {
+			t.Errorf("tick %d: unexpected route %v from home %v", i+1, bot.BotState.PatrolRoute, *bot.BotState.Home)
+		}
+	}
+}
+
+func TestWanderDestination(t *testing.T) {
+	d := newTestDispatcher()
+	state := loadArena(t)
+	removePlayer(state, "player-1")
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	bot := d.Bots["monster-1"]
+	home := *bot.homePosition()
+	for i := 0; i < 50; i++ {
+		destination := bot.wanderDestination()
+		if destination == nil {
+			t.Fatal("no wander destination in the open arena")
+		}
+		tile := bot.BotState.MapExtended.At(*destination)
+		if *destination == *bot.Details.Position || !tile.mapObjects.IsFree || bot.isOccupied(*destination) {
+			t.Errorf("wander destination %v is not a free tile", *destination)
+		}
+		radius := bot.Tuning.Idle.WanderRadius
+		if dx, dy := destination.PositionX-home.PositionX, destination.PositionY-home.PositionY; dx < -radius || dx > radius || dy < -radius || dy > radius {
+			t.Errorf("wander destination %v further than %d from home %v", *destination, radius, home)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package bot
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"sync"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/pathfinding"
+)
+
+// Views (distances + field of view) kept per layout before the cache is cleared
+const maxLayoutViews = 4096
+
+// LevelLayout is the static part of a level (walls and free tiles)
+// It is reused across ticks while the layout hash is unchanged,
+// so distances and line of sight from positions where monsters stand still are computed only once
+type LevelLayout struct {
+	Width  int32
+	Height int32
+	Hash   uint64
+
+	blocked []bool
+
+	lock  sync.Mutex
+	views map[swagger.DungeonsandtrollsPosition]*layoutView
+	// Patrol points (see Waypoints)
+	waypoints    []swagger.DungeonsandtrollsPosition
+	waypointsKey waypointsKey
+}
+
+type layoutView struct {
+	// Walking distance (math.MaxInt32 if unreachable)
+	distances []int32
+	visible   []bool
+}
+
+// LevelCache is built once per tick in BotDispatcher.HandleLevel and shared by all bots on the level
+type LevelCache struct {
+	Level  int32
+	Layout *LevelLayout
+	Tiles  map[swagger.DungeonsandtrollsPosition]*swagger.DungeonsandtrollsMapObjects
+}
+
+// NewLevelCache reuses the previous layout if the level layout didn't change
+func NewLevelCache(level *swagger.DungeonsandtrollsLevel, previous *LevelLayout) *LevelCache {
+	tiles := make(map[swagger.DungeonsandtrollsPosition]*swagger.DungeonsandtrollsMapObjects, len(level.Objects))
+	for i := range level.Objects {
+		tiles[*level.Objects[i].Position] = &level.Objects[i]
+	}
+	blocked := make([]bool, int(level.Width)*int(level.Height))
+	for position, tile := range tiles {
+		if !tile.IsFree && isInLayout(level.Width, level.Height, position) {
+			blocked[int(position.PositionY)*int(level.Width)+int(position.PositionX)] = true
+		}
+	}
+	hash := layoutHash(level.Width, level.Height, blocked)
+	layout := previous
+	if layout == nil || layout.Hash != hash || layout.Width != level.Width || layout.Height != level.Height {
+		layout = &LevelLayout{
+			Width:   level.Width,
+			Height:  level.Height,
+			Hash:    hash,
+			blocked: blocked,
+			views:   map[swagger.DungeonsandtrollsPosition]*layoutView{},
+		}
+	}
+	return &LevelCache{
+		Level:  level.Level,
+		Layout: layout,
+		Tiles:  tiles,
+	}
+}
+
+func layoutHash(width, height int32, blocked []bool) uint64 {
+	h := fnv.New64a()
+	header := make([]byte, 8)
+	binary.LittleEndian.PutUint32(header, uint32(width))
+	binary.LittleEndian.PutUint32(header[4:], uint32(height))
+	h.Write(header)
+	packed := make([]byte, (len(blocked)+7)/8)
+	for i, b := range blocked {
+		if b {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	h.Write(packed)
+	return h.Sum64()
+}
+
+func isInLayout(width, height int32, position swagger.DungeonsandtrollsPosition) bool {
+	return position.PositionX >= 0 && position.PositionX < width && position.PositionY >= 0 && position.PositionY < height
+}
+
+func (l *LevelLayout) InBounds(position swagger.DungeonsandtrollsPosition) bool {
+	return isInLayout(l.Width, l.Height, position)
+}
+
+func (l *LevelLayout) index(position swagger.DungeonsandtrollsPosition) int {
+	return int(position.PositionY)*int(l.Width) + int(position.PositionX)
+}
+
+// Out of bounds positions are blocked
+func (l *LevelLayout) isBlocked(x, y int32) bool {
+	if x < 0 || x >= l.Width || y < 0 || y >= l.Height {
+		return true
+	}
+	return l.blocked[int(y)*int(l.Width)+int(x)]
+}
+
+// Distance returns the walking distance (math.MaxInt32 if unreachable)
+func (l *LevelLayout) Distance(from, to swagger.DungeonsandtrollsPosition) int32 {
+	if !l.InBounds(from) || !l.InBounds(to) {
+		return math.MaxInt32
+	}
+	return l.view(from).distances[l.index(to)]
+}
+
+func (l *LevelLayout) LineOfSight(from, to swagger.DungeonsandtrollsPosition) bool {
+	if !l.InBounds(from) || !l.InBounds(to) {
+		return false
+	}
+	return l.view(from).visible[l.index(to)]
+}
+
+func (l *LevelLayout) view(origin swagger.DungeonsandtrollsPosition) *layoutView {
+	l.lock.Lock()
+	view, found := l.views[origin]
+	l.lock.Unlock()
+	if found {
+		return view
+	}
+	// Computed outside of the lock, bots on the same position may compute the same view twice
+	view = &layoutView{
+		distances: l.bfs(origin),
+		visible:   l.fieldOfView(origin),
+	}
+	l.lock.Lock()
+	if len(l.views) >= maxLayoutViews {
+		l.views = map[swagger.DungeonsandtrollsPosition]*layoutView{}
+	}
+	l.views[origin] = view
+	l.lock.Unlock()
+	return view
+}
+
+// 4-neighbour BFS over free tiles (the origin itself may be blocked)
+func (l *LevelLayout) bfs(origin swagger.DungeonsandtrollsPosition) []int32 {
+	distances := make([]int32, len(l.blocked))
+	for i := range distances {
+		distances[i] = math.MaxInt32
+	}
+	distances[l.index(origin)] = 0
+	queue := []swagger.DungeonsandtrollsPosition{origin}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		distance := distances[l.index(node)] + 1
+		for _, neighbor := range getNeighbors(node) {
+			if l.isBlocked(neighbor.PositionX, neighbor.PositionY) {
+				continue
+			}
+			i := l.index(neighbor)
+			if distances[i] != math.MaxInt32 {
+				continue
+			}
+			distances[i] = distance
+			queue = append(queue, neighbor)
+		}
+	}
+	return distances
+}
+
+// fieldOfView uses symmetric shadowcasting (https://www.albertford.com/shadowcasting/)
+// Blocking tiles are visible, tiles behind them are not
+func (l *LevelLayout) fieldOfView(origin swagger.DungeonsandtrollsPosition) []bool {
+	visible := make([]bool, len(l.blocked))
+	visible[l.index(origin)] = true
+	for quadrant := 0; quadrant < 4; quadrant++ {
+		q := fovQuadrant{layout: l, origin: origin, direction: quadrant, visible: visible}
+		q.scan(fovRow{depth: 1, start: fraction{-1, 1}, end: fraction{1, 1}})
+	}
+	return visible
+}
+
+// fraction is an exact slope (den > 0)
+type fraction struct {
+	num int64
+	den int64
+}
+
+type fovRow struct {
+	depth int64
+	start fraction
+	end   fraction
+}
+
+type fovQuadrant struct {
+	layout    *LevelLayout
+	origin    swagger.DungeonsandtrollsPosition
+	direction int
+	visible   []bool
+}
+
+func (q *fovQuadrant) transform(depth, col int64) (int32, int32) {
+	x, y := q.origin.PositionX, q.origin.PositionY
+	switch q.direction {
+	case 0: // north
+		return x + int32(col), y - int32(depth)
+	case 1: // south
+		return x + int32(col), y + int32(depth)
+	case 2: // east
+		return x + int32(depth), y + int32(col)
+	default: // west
+		return x - int32(depth), y + int32(col)
+	}
+}
+
+func (q *fovQuadrant) isWall(depth, col int64) bool {
+	x, y := q.transform(depth, col)
+	return q.layout.isBlocked(x, y)
+}
+
+func (q *fovQuadrant) reveal(depth, col int64) {
+	x, y := q.transform(depth, col)
+	position := makePosition(x, y)
+	if q.layout.InBounds(position) {
+		q.visible[q.layout.index(position)] = true
+	}
+}
+
+func (q *fovQuadrant) scan(row fovRow) {
+	// min col = round ties up (depth * start), max col = round ties down (depth * end)
+	minCol := floorDiv(2*row.depth*row.start.num+row.start.den, 2*row.start.den)
+	maxCol := -floorDiv(-(2*row.depth*row.end.num - row.end.den), 2*row.end.den)
+	prevWall, hasPrev := false, false
+	for col := minCol; col <= maxCol; col++ {
+		wall := q.isWall(row.depth, col)
+		if wall || isSymmetric(row, col) {
+			q.reveal(row.depth, col)
+		}
+		if hasPrev && prevWall && !wall {
+			row.start = tileSlope(row.depth, col)
+		}
+		if hasPrev && !prevWall && wall {
+			next := fovRow{depth: row.depth + 1, start: row.start, end: tileSlope(row.depth, col)}
+			q.scan(next)
+		}
+		prevWall, hasPrev = wall, true
+	}
+	if hasPrev && !prevWall {
+		q.scan(fovRow{depth: row.depth + 1, start: row.start, end: row.end})
+	}
+}
+
+func tileSlope(depth, col int64) fraction {
+	return fraction{num: 2*col - 1, den: 2 * depth}
+}
+
+// depth * start <= col <= depth * end
+func isSymmetric(row fovRow, col int64) bool {
+	return col*row.start.den >= row.depth*row.start.num && col*row.end.den <= row.depth*row.end.num
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// PathGrid returns a pathfinding grid for the faction using the tiles of this tick
+func (c *LevelCache) PathGrid(faction string, costs pathfinding.TileCosts) *pathfinding.Grid {
+	return pathfinding.TileGrid(c.Layout.Width, c.Layout.Height, c.Tiles, faction, costs)
+}
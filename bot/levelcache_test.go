@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"go.uber.org/zap"
+)
+
+// generateLevel returns a square level with border walls, random wall pillars and monster positions
+func generateLevel(size int32, monsters int, seed int64) (*swagger.DungeonsandtrollsLevel, []swagger.DungeonsandtrollsPosition) {
+	rng := rand.New(rand.NewSource(seed))
+	level := &swagger.DungeonsandtrollsLevel{Level: 1, Width: size, Height: size}
+	free := []swagger.DungeonsandtrollsPosition{}
+	for y := int32(0); y < size; y++ {
+		for x := int32(0); x < size; x++ {
+			position := makePosition(x, y)
+			wall := x == 0 || y == 0 || x == size-1 || y == size-1 || rng.Float32() < 0.15
+			level.Objects = append(level.Objects, swagger.DungeonsandtrollsMapObjects{
+				Position: &position,
+				IsWall:   wall,
+				IsFree:   !wall,
+			})
+			if !wall {
+				free = append(free, position)
+			}
+		}
+	}
+	positions := []swagger.DungeonsandtrollsPosition{}
+	for i := 0; i < monsters; i++ {
+		positions = append(positions, free[rng.Intn(len(free))])
+	}
+	return level, positions
+}
+
+// legacyDistanceAndLineOfSight is the per-bot computation used before LevelCache (map based BFS and ray tracing)
+func legacyDistanceAndLineOfSight(level *swagger.DungeonsandtrollsLevel, currentPosition swagger.DungeonsandtrollsPosition) map[swagger.DungeonsandtrollsPosition]MapCellExt {
+	inBounds := func(pos swagger.DungeonsandtrollsPosition) bool {
+		return isInLayout(level.Width, level.Height, pos)
+	}
+	distanceToFirstObstacle := make(map[float32]float32)
+	resultMap := make(map[swagger.DungeonsandtrollsPosition]MapCellExt)
+	for _, objects := range level.Objects {
+		resultMap[*objects.Position] = MapCellExt{
+			mapObjects: objects,
+			distance:   math.MaxInt32,
+		}
+	}
+	rayTrace := func(x1, y1, x2, y2 float32) float32 {
+		dx, dy := float32(math.Abs(float64(x2-x1))), float32(math.Abs(float64(y2-y1)))
+		sx, sy := float32(1), float32(1)
+		if x1 > x2 {
+			sx = -1
+		}
+		if y1 > y2 {
+			sy = -1
+		}
+		e := dx - dy
+		x, y := x1, y1
+		for {
+			pos := makePosition(int32(x), int32(y))
+			cell, found := resultMap[pos]
+			if !inBounds(pos) || (found && !cell.mapObjects.IsFree) {
+				return float32(math.Sqrt(float64((x-x1)*(x-x1) + (y-y1)*(y-y1))))
+			}
+			e2 := 2 * e
+			if e2 > -dy {
+				e -= dy
+				x += sx
+			}
+			if e2 < dx {
+				e += dx
+				y += sy
+			}
+		}
+	}
+	lineOfSight := func(pos1, pos2 swagger.DungeonsandtrollsPosition) bool {
+		x1, y1 := float32(pos1.PositionX)+0.5, float32(pos1.PositionY)+0.5
+		x2, y2 := float32(pos2.PositionX)+0.5, float32(pos2.PositionY)+0.5
+		distance := math.Sqrt(float64((x2-x1)*(x2-x1) + (y2-y1)*(y2-y1)))
+		slope := float32(math.Atan2(float64(y2-y1), float64(x2-x1)))
+		losDist, found := distanceToFirstObstacle[slope]
+		if !found {
+			losDist = rayTrace(x1, y1, x2, y2)
+			distanceToFirstObstacle[slope] = losDist
+		}
+		return distance < float64(losDist)
+	}
+
+	visited := make(map[swagger.DungeonsandtrollsPosition]bool)
+	queue := []swagger.DungeonsandtrollsPosition{currentPosition}
+	cell := resultMap[currentPosition]
+	cell.distance = 0
+	cell.lineOfSight = true
+	resultMap[currentPosition] = cell
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if visited[node] {
+			continue
+		}
+		visited[node] = true
+		for _, neighbor := range getNeighbors(node) {
+			cell, found := resultMap[neighbor]
+			if inBounds(neighbor) && !visited[neighbor] && (!found || cell.mapObjects.IsFree) {
+				mapObjects := swagger.DungeonsandtrollsMapObjects{IsFree: true}
+				if found {
+					mapObjects = cell.mapObjects
+				}
+				resultMap[neighbor] = MapCellExt{
+					mapObjects:  mapObjects,
+					distance:    resultMap[node].distance + 1,
+					lineOfSight: lineOfSight(currentPosition, neighbor),
+				}
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	return resultMap
+}
+
+func newLayoutBot(level *swagger.DungeonsandtrollsLevel, cache *LevelCache) *Bot {
+	return &Bot{
+		Details:    MonsterDetails{Level: level.Level, CurrentMap: level},
+		LevelCache: cache,
+		Logger:     zap.NewNop().Sugar(),
+	}
+}
+
+func TestLayoutDistancesMatchLegacy(t *testing.T) {
+	level, positions := generateLevel(40, 10, 1)
+	bot := newLayoutBot(level, NewLevelCache(level, nil))
+	for _, position := range positions {
+		legacy := legacyDistanceAndLineOfSight(level, position)
+		shared := bot.calculateDistanceAndLineOfSight(level.Level, position)
+		for tile, cell := range legacy {
+			if shared.At(tile).distance != cell.distance {
+				t.Fatalf("distance from %v to %v: shared %d, legacy %d", position, tile, shared.At(tile).distance, cell.distance)
+			}
+		}
+	}
+}
+
+var benchmarkLevelSizes = []struct {
+	size     int32
+	monsters int
+}{
+	{50, 50},
+	{100, 200},
+}
+
+// Old path: every bot computes distances and line of sight on its own every tick
+func BenchmarkLegacyPerBot(b *testing.B) {
+	for _, params := range benchmarkLevelSizes {
+		level, positions := generateLevel(params.size, params.monsters, 1)
+		b.Run(fmt.Sprintf("size=%d/monsters=%d", params.size, params.monsters), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, position := range positions {
+					legacyDistanceAndLineOfSight(level, position)
+				}
+			}
+		})
+	}
+}
+
+// Shared layout built in the tick (first tick or changed layout), bots on the same position share the view
+func BenchmarkSharedLayoutColdTick(b *testing.B) {
+	for _, params := range benchmarkLevelSizes {
+		level, positions := generateLevel(params.size, params.monsters, 1)
+		b.Run(fmt.Sprintf("size=%d/monsters=%d", params.size, params.monsters), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bot := newLayoutBot(level, NewLevelCache(level, nil))
+				for _, position := range positions {
+					bot.calculateDistanceAndLineOfSight(level.Level, position)
+				}
+			}
+		})
+	}
+}
+
+// Shared layout reused from the previous tick with monsters standing still
+func BenchmarkSharedLayoutWarmTick(b *testing.B) {
+	for _, params := range benchmarkLevelSizes {
+		level, positions := generateLevel(params.size, params.monsters, 1)
+		b.Run(fmt.Sprintf("size=%d/monsters=%d", params.size, params.monsters), func(b *testing.B) {
+			previous := NewLevelCache(level, nil).Layout
+			for _, position := range positions {
+				previous.Distance(position, position)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bot := newLayoutBot(level, NewLevelCache(level, previous))
+				for _, position := range positions {
+					bot.calculateDistanceAndLineOfSight(level.Level, position)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"runtime"
+	"sort"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/metrics"
+)
+
+// Bots of monsters missing in the game state for this many ticks are freed
+const DefaultDespawnTicks = 10
+
+// Heap stats are sampled once per this many ticks
+const DefaultMemStatsTicks = 10
+
+const (
+	BotRemovedDead      = "dead"
+	BotRemovedDespawned = "despawned"
+)
+
+// BotStats are collected over the whole life of the bot
+type BotStats struct {
+	FirstSeenTick int32 `json:"firstSeenTick"`
+	LastSeenTick  int32 `json:"lastSeenTick"`
+	TicksAlive    int   `json:"ticksAlive"`
+	// Estimated from the used skills (without randomization, see calculateExpectedDamage)
+	DamageDealt float32        `json:"damageDealt"`
+	SkillsUsed  map[string]int `json:"skillsUsed"`
+}
+
+func (s BotStats) copy() BotStats {
+	skillsUsed := make(map[string]int, len(s.SkillsUsed))
+	for name, count := range s.SkillsUsed {
+		skillsUsed[name] = count
+	}
+	s.SkillsUsed = skillsUsed
+	return s
+}
+
+// BotLifecycleEvent is emitted when a bot is freed
+type BotLifecycleEvent struct {
+	MonsterId   string   `json:"monsterId"`
+	MonsterName string   `json:"monsterName"`
+	Algorithm   string   `json:"algorithm"`
+	Level       int32    `json:"level"`
+	Reason      string   `json:"reason"`
+	Stats       BotStats `json:"stats"`
+}
+
+// BotLifecycleListener gets every freed bot (synchronously, at the end of the tick)
+type BotLifecycleListener interface {
+	BotRemoved(event BotLifecycleEvent)
+}
+
+// markSeen is called every tick the monster is in the game state
+func (b *Bot) markSeen(tick int32) {
+	if b.Stats.TicksAlive == 0 {
+		b.Stats.FirstSeenTick = tick
+	}
+	b.Stats.LastSeenTick = tick
+	b.Stats.TicksAlive++
+	b.MissingTicks = 0
+}
+
+func (b *Bot) recordSkillUse(skill swagger.DungeonsandtrollsSkill, target MapObject) {
+	if b.Stats.SkillsUsed == nil {
+		b.Stats.SkillsUsed = map[string]int{}
+	}
+	b.Stats.SkillsUsed[skill.Name]++
+	metrics.SkillsUsed.WithLabelValues(skill.Name).Inc()
+	if skill.DamageAmount == nil || skill.DamageType == nil || target.IsEmpty() || target.Type == MapObjectTypeEffect {
+		return
+	}
+	if *skill.Target == swagger.CHARACTER_SkillTarget && b.IsHostile(target) {
+		b.Stats.DamageDealt += b.calculateExpectedDamage(&target, &skill)
+	}
+}
+
+func (b *Bot) isDead() bool {
+	return isMonsterDead(b.Details.Monster)
+}
+
+func isMonsterDead(monster *swagger.DungeonsandtrollsMonster) bool {
+	return monster != nil && monster.Attributes != nil && monster.Attributes.Life <= 0
+}
+
+// collectBots frees bots of dead monsters and monsters missing for DespawnTicks
+func (d *BotDispatcher) collectBots(tick int32) {
+	events := []BotLifecycleEvent{}
+	d.BotsLock.Lock()
+	for id, bot := range d.Bots {
+		reason := ""
+		if bot.Stats.LastSeenTick != tick {
+			bot.MissingTicks++
+		}
+		if bot.MissingTicks == 0 && bot.isDead() {
+			reason = BotRemovedDead
+		} else if bot.MissingTicks >= d.DespawnTicks {
+			reason = BotRemovedDespawned
+		}
+		if reason == "" {
+			continue
+		}
+		events = append(events, BotLifecycleEvent{
+			MonsterId:   id,
+			MonsterName: bot.Details.Name,
+			Algorithm:   bot.Details.Monster.Algorithm,
+			Level:       bot.Details.Level,
+			Reason:      reason,
+			Stats:       bot.Stats,
+		})
+		delete(d.Bots, id)
+		delete(d.overrides, id)
+	}
+	d.tickStats.Bots = len(d.Bots)
+	metrics.TrackedBots.Set(float64(len(d.Bots)))
+	d.BotsLock.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].MonsterId < events[j].MonsterId
+	})
+	for _, event := range events {
+		d.LoggerWTick.Infow("Bot removed",
+			"monsterId", event.MonsterId,
+			"monsterName", event.MonsterName,
+			"mapLevel", event.Level,
+			"reason", event.Reason,
+			"ticksAlive", event.Stats.TicksAlive,
+			"damageDealt", event.Stats.DamageDealt,
+			"skillsUsed", event.Stats.SkillsUsed,
+		)
+		if d.Lifecycle != nil {
+			d.Lifecycle.BotRemoved(event)
+		}
+	}
+	d.tickStats.Removed = len(events)
+
+	// The Go collector in metrics reports the heap continuously, the sample is only for the tick log
+	if d.MemStatsTicks > 0 && tick%d.MemStatsTicks == 0 {
+		memStats := runtime.MemStats{}
+		runtime.ReadMemStats(&memStats)
+		d.heapAllocBytes = memStats.HeapAlloc
+	}
+	d.tickStats.HeapAllocBytes = d.heapAllocBytes
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDespawnAfterDespawnTicks(t *testing.T) {
+	d := newTestDispatcher()
+	lifecycle := &lifecycleEvents{}
+	d.Lifecycle = lifecycle
+	d.DespawnTicks = 3
+	// monster-1 leaves in tick 2, comes back in tick 4, and leaves again in tick 5
+	present := map[int32]bool{1: true, 4: true}
+	for tick := int32(1); tick <= 8; tick++ {
+		state := loadArena(t)
+		state.Tick = tick
+		if !present[tick] {
+			removeMonster(state, "monster-1")
+		}
+		if err := d.HandleTick(state, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		_, tracked := d.Bots["monster-1"]
+		// Missing in ticks 5, 6 and 7
+		if expected := tick < 7; tracked != expected {
+			t.Errorf("tick %d: expected tracked %v, got %v", tick, expected, tracked)
+		}
+	}
+	if len(lifecycle.events) != 1 {
+		t.Fatalf("expected one lifecycle event, got %+v", lifecycle.events)
+	}
+	event := lifecycle.events[0]
+	if event.MonsterId != "monster-1" || event.Reason != BotRemovedDespawned || event.Stats.TicksAlive != 2 || event.Stats.LastSeenTick != 4 {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if _, tracked := d.Bots["monster-2"]; !tracked {
+		t.Error("monster-2 despawned")
+	}
+}
+
+func TestDeadMonsterEvent(t *testing.T) {
+	d := newTestDispatcher()
+	lifecycle := &lifecycleEvents{}
+	d.Lifecycle = lifecycle
+	for tick := int32(1); tick <= 3; tick++ {
+		state := loadArena(t)
+		state.Tick = tick
+		if tick > 1 {
+			// Dead monsters stay in the game state for a while
+			findMonster(state, "monster-2").Attributes.Life = 0
+		}
+		if tick == 3 && d.countMonstersToRun(state) != 1 {
+			t.Errorf("expected only the living monster to share the tick budget, got %d", d.countMonstersToRun(state))
+		}
+		if err := d.HandleTick(state, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(lifecycle.events) != 1 {
+		t.Fatalf("expected one lifecycle event, got %+v", lifecycle.events)
+	}
+	event := lifecycle.events[0]
+	if event.MonsterId != "monster-2" || event.Reason != BotRemovedDead || event.Algorithm != "healer" || event.Level != 1 || event.Stats.TicksAlive != 2 {
+		t.Errorf("unexpected event %+v", event)
+	}
+	// The dead monster doesn't get a new bot
+	if _, tracked := d.Bots["monster-2"]; tracked || d.LastTickStats.Monsters != 1 || d.LastTickStats.Bots != 1 {
+		t.Errorf("dead monster tracked again (stats %+v)", d.LastTickStats)
+	}
+}
+
+func TestHeapStatsSampled(t *testing.T) {
+	d := newTestDispatcher()
+	d.MemStatsTicks = 2
+	heap := []uint64{}
+	for tick := int32(1); tick <= 3; tick++ {
+		state := loadArena(t)
+		state.Tick = tick
+		if err := d.HandleTick(state, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		heap = append(heap, d.LastTickStats.HeapAllocBytes)
+	}
+	// Sampled in tick 2 and kept in tick 3
+	if heap[0] != 0 || heap[1] == 0 || heap[2] != heap[1] {
+		t.Errorf("unexpected heap samples %v", heap)
+	}
+}
@@ -117,6 +117,29 @@ func (mo MapObject) GetMaxAttributes() *swagger.DungeonsandtrollsAttributes {
 	}
 }
 
+func (mo MapObject) GetEquippedItems() []swagger.DungeonsandtrollsItem {
+	switch mo.Type {
+	case MapObjectTypePlayer:
+		return mo.MapObjects.Players[mo.Index].Equip
+	case MapObjectTypeMonster:
+		return mo.MapObjects.Monsters[mo.Index].EquippedItems
+	default:
+		return nil
+	}
+}
+
+// Effects currently applied to the character
+func (mo MapObject) GetEffects() []swagger.DungeonsandtrollsEffect {
+	switch mo.Type {
+	case MapObjectTypePlayer:
+		return mo.MapObjects.Players[mo.Index].Effects
+	case MapObjectTypeMonster:
+		return mo.MapObjects.Monsters[mo.Index].Effects
+	default:
+		return nil
+	}
+}
+
 func (mo MapObject) GetPosition() *swagger.DungeonsandtrollsPosition {
 	return mo.MapObjects.Position
 }
@@ -137,32 +160,39 @@ func (mo MapObject) GetFaction() string {
 
 func (b *Bot) IsFriendly(mo MapObject) bool {
 	myFaction := b.Details.Monster.Faction
-	faction := mo.GetFaction()
+	friendly, known := areFactionsFriendly(myFaction, mo.GetFaction())
+	if !known {
+		b.Logger.Errorw("PANIC: IsFriendly(): Unknown faction",
+			"myFaction", myFaction,
+		)
+	}
+	return friendly
+}
+
+// areFactionsFriendly returns known = false (and friendly = true) for unknown myFaction
+func areFactionsFriendly(myFaction, faction string) (friendly bool, known bool) {
 	if faction == "neutral" {
-		return false
+		return false, true
 	}
 	if faction == myFaction {
-		return true
+		return true, true
 	}
 	switch myFaction {
 	case "player":
-		return faction == "templar"
+		return faction == "templar", true
 	case "monster":
-		return faction == "outlaw" || faction == "horror"
+		return faction == "outlaw" || faction == "horror", true
 	case "outlaw":
-		return faction == "monster"
+		return faction == "monster", true
 	case "horror":
-		return faction == "monster"
+		return faction == "monster", true
 	case "templar":
-		return faction == "player"
+		return faction == "player", true
 	case "neutral":
 		// friendly to all
-		return true
+		return true, true
 	default:
-		b.Logger.Errorw("PANIC: IsFriendly(): Unknown faction",
-			"myFaction", myFaction,
-		)
-		return true
+		return true, false
 	}
 }
 
@@ -296,9 +326,9 @@ func (b *Bot) getMapObjectsByCategoryForLevel(level int32) MapObjectsByCategory
 				objects.Effects = append(objects.Effects, mo)
 			}
 		}
-		// Maybe TODO (e.g. monsters guarding portals)
-		// if len(object.Portals) > 0 {
-
+		if object.Portal != nil {
+			objects.Portals = append(objects.Portals, &object)
+		}
 	}
 	return objects
 }
@@ -306,11 +336,12 @@ func (b *Bot) getMapObjectsByCategoryForLevel(level int32) MapObjectsByCategory
 type MapObjectsByCategory struct {
 	Spawn  *swagger.DungeonsandtrollsMapObjects
 	Stairs *swagger.DungeonsandtrollsMapObjects
+	// Guarded by guardians (see guardian.go)
+	Portals []*swagger.DungeonsandtrollsMapObjects
 
 	Players  []MapObject
 	Monsters []MapObject
 	Effects  []MapObject
-	// Portals  []MapObject
 
 	Hostile  []MapObject
 	Friendly []MapObject
@@ -2,9 +2,9 @@ package bot
 
 import (
 	"math"
-	"math/rand"
 
 	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/pathfinding"
 )
 
 const skillDefaultMove = "DEFAULT_MOVE"
@@ -61,7 +61,7 @@ func (b *Bot) getEmptyPositionsAsTargetsFromPosition(position swagger.Dungeonsan
 				// Skip current position
 				continue
 			}
-			tileInfo, found := b.BotState.MapExtended[pos]
+			tileInfo, found := b.BotState.MapExtended.Lookup(pos)
 			if found && (!tileInfo.mapObjects.IsFree || len(tileInfo.mapObjects.Monsters) > 0 || len(tileInfo.mapObjects.Players) > 0) {
 				// Skip non-free tiles or tiles with monsters or players
 				continue
@@ -72,11 +72,15 @@ func (b *Bot) getEmptyPositionsAsTargetsFromPosition(position swagger.Dungeonsan
 	return targets
 }
 
+func (b *Bot) levelGrid() *pathfinding.Grid {
+	return b.levelCache().PathGrid(b.Details.Monster.Faction, b.Tuning.Pathfinding)
+}
+
 func (b *Bot) stretchMovePosition(position swagger.DungeonsandtrollsPosition) swagger.DungeonsandtrollsPosition {
 	x := position.PositionX - b.Details.Position.PositionX
 	y := position.PositionY - b.Details.Position.PositionY
-	x = b.Details.Position.PositionX + x*6 + rand.Int31n(4) - rand.Int31n(4)
-	y = b.Details.Position.PositionY + y*6 + rand.Int31n(4) - rand.Int31n(4)
+	x = b.Details.Position.PositionX + x*6 + b.Rand.Int31n(4) - b.Rand.Int31n(4)
+	y = b.Details.Position.PositionY + y*6 + b.Rand.Int31n(4) - b.Rand.Int31n(4)
 	candidatePosition := makePosition(x, y)
 
 	dist := int32(8)
@@ -95,9 +99,11 @@ func (b *Bot) stretchMovePosition(position swagger.DungeonsandtrollsPosition) sw
 			if !b.isInBounds(b.Details.Level, pos) || distanceToCandidate > dist {
 				continue
 			}
-			tileInfo, found := b.BotState.MapExtended[pos]
-			if found && tileInfo.mapObjects.IsFree && tileInfo.lineOfSight {
-				distance := float32(int(4*distanceToCandidate)+tileInfo.distance) + rand.Float32()/100
+			tileInfo, found := b.BotState.MapExtended.Lookup(pos)
+			// Path cost instead of distance avoids positions behind harmful ground effects
+			pathCost, reachable := b.BotState.Paths.Cost(pos)
+			if found && reachable && tileInfo.mapObjects.IsFree && tileInfo.lineOfSight {
+				distance := float32(4*distanceToCandidate) + pathCost + b.Rand.Float32()/100
 				if distance < bestDistance {
 					bestDistance = distance
 					bestPosition = pos
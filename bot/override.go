@@ -0,0 +1,238 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// BotOverride is set by operators (see the admin package) and applied in prepareBot
+type BotOverride struct {
+	// Replaces the behavior chosen by nextBehavior
+	Behavior Behavior `json:"behavior,omitempty"`
+	// Config profile name used instead of the profile of the monster algorithm
+	Profile string `json:"profile,omitempty"`
+	// Disabled monsters get no commands
+	Disabled bool `json:"disabled,omitempty"`
+	// Enables decision traces
+	Debug bool `json:"debug,omitempty"`
+	// The override is removed after this tick (0 keeps it until removed)
+	UntilTick int32 `json:"untilTick,omitempty"`
+}
+
+var ErrBotNotTracked = errors.New("monster is not tracked")
+
+// BotSnapshot is a copy of the bot state taken after the bot ran (safe to read outside of the bot)
+type BotSnapshot struct {
+	MonsterId             string                                  `json:"monsterId"`
+	MonsterName           string                                  `json:"monsterName"`
+	Algorithm             string                                  `json:"algorithm"`
+	Level                 int32                                   `json:"level"`
+	Tick                  int32                                   `json:"tick"`
+	Position              *swagger.DungeonsandtrollsPosition      `json:"position"`
+	Life                  float32                                 `json:"life"`
+	Config                Config                                  `json:"config"`
+	Behavior              Behavior                                `json:"behavior"`
+	BehaviorTicks         int                                     `json:"behaviorTicks"`
+	BehaviorPosition      *swagger.DungeonsandtrollsPosition      `json:"behaviorPosition"`
+	Home                  *swagger.DungeonsandtrollsPosition      `json:"home"`
+	Guarded               *GuardedObject                          `json:"guarded,omitempty"`
+	TargetPosition        *swagger.DungeonsandtrollsPosition      `json:"targetPosition"`
+	TargetPositionTimeout int                                     `json:"targetPositionTimeout"`
+	Threat                ThreatTable                             `json:"threat,omitempty"`
+	LastCommand           *swagger.DungeonsandtrollsCommandsBatch `json:"lastCommand"`
+	Delivery              *DeliveryStatus                         `json:"delivery,omitempty"`
+	Stats                 BotStats                                `json:"stats"`
+	MissingTicks          int                                     `json:"missingTicks"`
+	Override              *BotOverride                            `json:"override,omitempty"`
+	Disabled              bool                                    `json:"disabled"`
+}
+
+func (o BotOverride) Validate(profiles *ConfigProfiles) error {
+	if o.Profile != "" {
+		if _, found := profiles.Profile(o.Profile); !found {
+			return fmt.Errorf("unknown profile %q", o.Profile)
+		}
+	}
+	if o.Behavior == "" {
+		return nil
+	}
+	for _, behavior := range behaviors {
+		if o.Behavior == behavior {
+			return nil
+		}
+	}
+	return fmt.Errorf("behavior must be one of %v (got %q)", behaviors, o.Behavior)
+}
+
+func (o BotOverride) expired(tick int32) bool {
+	return o.UntilTick > 0 && tick > o.UntilTick
+}
+
+// Tick returns the tick handled last
+func (d *BotDispatcher) Tick() int32 {
+	return atomic.LoadInt32(&d.tick)
+}
+
+// SetBotOverride replaces the override of a tracked monster, it is applied from the next tick
+func (d *BotDispatcher) SetBotOverride(monsterId string, override BotOverride) error {
+	if err := override.Validate(d.Profiles); err != nil {
+		return err
+	}
+	d.BotsLock.Lock()
+	defer d.BotsLock.Unlock()
+	if _, found := d.Bots[monsterId]; !found {
+		return ErrBotNotTracked
+	}
+	if d.overrides == nil {
+		d.overrides = map[string]BotOverride{}
+	}
+	d.overrides[monsterId] = override
+	return nil
+}
+
+// RemoveBotOverride returns false if the monster had no override
+func (d *BotDispatcher) RemoveBotOverride(monsterId string) bool {
+	d.BotsLock.Lock()
+	defer d.BotsLock.Unlock()
+	_, found := d.overrides[monsterId]
+	delete(d.overrides, monsterId)
+	return found
+}
+
+// SetLevelDisabled disables the bots of the whole level until the tick (0 until enabled again)
+func (d *BotDispatcher) SetLevelDisabled(level int32, untilTick int32) {
+	d.BotsLock.Lock()
+	defer d.BotsLock.Unlock()
+	if d.disabledLevels == nil {
+		d.disabledLevels = map[int32]int32{}
+	}
+	d.disabledLevels[level] = untilTick
+}
+
+// EnableLevel returns false if the level was not disabled
+func (d *BotDispatcher) EnableLevel(level int32) bool {
+	d.BotsLock.Lock()
+	defer d.BotsLock.Unlock()
+	_, found := d.disabledLevels[level]
+	delete(d.disabledLevels, level)
+	return found
+}
+
+// DisabledLevels maps disabled levels to the tick they are disabled until (0 until enabled again)
+func (d *BotDispatcher) DisabledLevels() map[int32]int32 {
+	d.BotsLock.Lock()
+	defer d.BotsLock.Unlock()
+	levels := map[int32]int32{}
+	for level, untilTick := range d.disabledLevels {
+		levels[level] = untilTick
+	}
+	return levels
+}
+
+// BotSnapshots returns snapshots of all tracked bots sorted by monster ID
+func (d *BotDispatcher) BotSnapshots() []BotSnapshot {
+	d.BotsLock.Lock()
+	defer d.BotsLock.Unlock()
+	snapshots := []BotSnapshot{}
+	for id, bot := range d.Bots {
+		snapshots = append(snapshots, d.snapshotWithOverride(id, bot))
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].MonsterId < snapshots[j].MonsterId
+	})
+	return snapshots
+}
+
+func (d *BotDispatcher) BotSnapshot(monsterId string) (BotSnapshot, bool) {
+	d.BotsLock.Lock()
+	defer d.BotsLock.Unlock()
+	bot, found := d.Bots[monsterId]
+	if !found {
+		return BotSnapshot{}, false
+	}
+	return d.snapshotWithOverride(monsterId, bot), true
+}
+
+// Must be called with BotsLock held
+func (d *BotDispatcher) snapshotWithOverride(monsterId string, bot *Bot) BotSnapshot {
+	snapshot := bot.Snapshot
+	snapshot.MonsterId = monsterId
+	snapshot.MissingTicks = bot.MissingTicks
+	if bot.Delivery.Status != "" {
+		delivery := bot.Delivery
+		snapshot.Delivery = &delivery
+	}
+	snapshot.Override = nil
+	if override, found := d.overrides[monsterId]; found {
+		snapshot.Override = &override
+	}
+	return snapshot
+}
+
+// applyOverride sets the config, debug flag and override of the bot for this tick
+// Must be called with BotsLock held
+func (d *BotDispatcher) applyOverride(bot *Bot, monster MonsterDetails, tick int32) {
+	override, found := d.overrides[monster.Id]
+	if found && override.expired(tick) {
+		d.LoggerWTick.Infow("Bot override expired",
+			"monsterId", monster.Id,
+			"override", override,
+		)
+		delete(d.overrides, monster.Id)
+		found = false
+	}
+	bot.Override = BotOverride{}
+	if found {
+		bot.Override = override
+	}
+	bot.Config = d.Profiles.ConfigFor(monster.Monster.Algorithm)
+	if bot.Override.Profile != "" {
+		bot.Config, _ = d.Profiles.Profile(bot.Override.Profile)
+	}
+	bot.Debug = d.isDebugged(monster) || bot.Override.Debug
+	bot.Disabled = bot.Override.Disabled
+	if untilTick, disabled := d.disabledLevels[monster.Level]; disabled {
+		if untilTick > 0 && tick > untilTick {
+			delete(d.disabledLevels, monster.Level)
+		} else {
+			bot.Disabled = true
+		}
+	}
+}
+
+// takeSnapshot copies the state for BotSnapshots after the bot ran
+func (b *Bot) takeSnapshot(cmd *swagger.DungeonsandtrollsCommandsBatch) BotSnapshot {
+	snapshot := BotSnapshot{
+		MonsterId:             b.MonsterId,
+		MonsterName:           b.Details.Name,
+		Level:                 b.Details.Level,
+		Position:              b.Details.Position,
+		Config:                b.Config,
+		Behavior:              b.BotState.Behavior,
+		BehaviorTicks:         b.BotState.BehaviorTicks,
+		BehaviorPosition:      b.BotState.BehaviorPosition,
+		Home:                  b.BotState.Home,
+		Guarded:               b.BotState.Guarded,
+		TargetPosition:        b.BotState.TargetPosition,
+		TargetPositionTimeout: b.BotState.TargetPositionTimeout,
+		Threat:                b.BotState.Threat,
+		LastCommand:           cmd,
+		Disabled:              b.Disabled,
+		// Stats keep changing while the bot runs
+		Stats: b.Stats.copy(),
+	}
+	if b.GameState != nil {
+		snapshot.Tick = b.GameState.Tick
+	}
+	if b.Details.Monster != nil {
+		snapshot.Algorithm = b.Details.Monster.Algorithm
+		if b.Details.Monster.Attributes != nil {
+			snapshot.Life = b.Details.Monster.Attributes.Life
+		}
+	}
+	return snapshot
+}
@@ -1,7 +1,7 @@
 package bot
 
 import (
-	"math/rand"
+	"math"
 )
 
 // Enemies
@@ -39,16 +39,34 @@ func (b *Bot) getFriendly(objects *MapObjectsByCategory) []MapObject {
 }
 
 func (b *Bot) pickTarget(objects *MapObjectsByCategory) *MapObject {
-	return b.pickClosestTarget(b.getEnemies(objects))
+	enemies := b.getEnemies(objects)
+	if target := b.pickThreatTarget(enemies); target != nil {
+		return target
+	}
+	return b.pickClosestTarget(enemies)
 }
 
 func (b *Bot) pickRandomTarget(enemies []MapObject) *MapObject {
 	// get random object
-	x := rand.Intn(len(enemies))
+	x := b.Rand.Intn(len(enemies))
 	return &enemies[x]
 }
 
+// pickClosestTarget returns the enemy with the shortest walking distance (threat breaks ties)
 func (b *Bot) pickClosestTarget(enemies []MapObject) *MapObject {
-	// TODO: implement
-	return &enemies[0]
+	var closest *MapObject
+	closestDistance := math.MaxInt32
+	for i := range enemies {
+		enemy := &enemies[i]
+		distance := math.MaxInt32
+		if cell, found := b.BotState.MapExtended.Lookup(*enemy.GetPosition()); found {
+			distance = cell.distance
+		}
+		if closest == nil || distance < closestDistance ||
+			(distance == closestDistance && b.BotState.Threat[enemy.GetId()] > b.BotState.Threat[closest.GetId()]) {
+			closest = enemy
+			closestDistance = distance
+		}
+	}
+	return closest
 }
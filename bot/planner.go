@@ -0,0 +1,298 @@
+package bot
+
+import (
+	"sort"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// skillCandidate is one scored skill + target combination from bestSkill()
+type skillCandidate struct {
+	skill  swagger.DungeonsandtrollsSkill
+	target MapObject
+	result SkillResult
+	score  float32
+	// Index in the decision trace (-1 without trace) and in the candidates slice
+	evaluation int
+	index      int
+}
+
+// Plan is the best skill sequence found by the planner, only the first step is used
+type Plan struct {
+	Steps []PlanStep `json:"steps"`
+	// Step scores discounted by Tuning.Planner.Discount
+	Score float32 `json:"score"`
+	// Number of virtual states evaluated beyond the first step
+	Expanded       int  `json:"expanded"`
+	BudgetExceeded bool `json:"budgetExceeded"`
+
+	first int
+}
+
+type PlanStep struct {
+	SkillName      string                             `json:"skillName"`
+	TargetName     string                             `json:"targetName"`
+	TargetPosition *swagger.DungeonsandtrollsPosition `json:"targetPosition"`
+	Score          float32                            `json:"score"`
+}
+
+// plannedEffect is a caster effect that changes non-vital attributes until it expires
+type plannedEffect struct {
+	attributes swagger.DungeonsandtrollsAttributes
+	ticks      int32
+}
+
+// Planner expansions of deterministic runs (see BotDispatcher.PlannerExpansions), enough for the default tuning
+const DeterministicPlannerExpansions = 32
+
+// planNode is a plan whose last step is not applied yet
+// Virtual bots are created lazily on expansion so that the whole expansion fits into the budget
+type planNode struct {
+	parent    *Bot
+	effects   []plannedEffect
+	candidate skillCandidate
+	steps     []PlanStep
+	score     float32
+	first     int
+}
+
+// planSkillSequence runs a beam search over sequences of up to Tuning.Planner.Depth skills
+// Following steps are evaluated by a virtual copy of the bot moved to the target position with costs paid and buffs applied
+// The search stops after Tuning.Planner.BudgetMs or after Bot.PlannerExpansions expansions (wall clock is ignored then)
+// Returns nil when planning is disabled or there is nothing to plan
+func (b *Bot) planSkillSequence(candidates []skillCandidate) *Plan {
+	t := b.Tuning.Planner
+	if t.Depth <= 1 || len(candidates) == 0 {
+		return nil
+	}
+	deadline := time.Time{}
+	if b.PlannerExpansions <= 0 {
+		deadline = time.Now().Add(time.Duration(t.BudgetMs * float32(time.Millisecond)))
+		if !b.Deadline.IsZero() && b.Deadline.Before(deadline) {
+			deadline = b.Deadline
+		}
+	}
+	// Virtual bots only log errors (e.g. unmet requirements are expected)
+	virtualLogger := b.Logger.Desugar().WithOptions(zap.IncreaseLevel(zapcore.ErrorLevel)).Sugar()
+
+	plan := &Plan{}
+	nodes := []planNode{}
+	for i, candidate := range topCandidates(candidates, t.BeamWidth) {
+		nodes = append(nodes, planNode{
+			parent:    b,
+			candidate: candidate,
+			steps:     []PlanStep{newPlanStep(candidate)},
+			score:     candidate.score,
+			first:     candidate.index,
+		})
+		if i == 0 {
+			plan.first = candidate.index
+			plan.Steps = nodes[0].steps
+			plan.Score = candidate.score
+		}
+	}
+	if len(nodes) == 0 {
+		// No candidate with positive score
+		return nil
+	}
+	discount := float32(1)
+	for depth := 2; depth <= t.Depth && len(nodes) > 0; depth++ {
+		discount *= t.Discount
+		children := []planNode{}
+		for _, node := range nodes {
+			if (b.PlannerExpansions > 0 && plan.Expanded >= b.PlannerExpansions) || (!deadline.IsZero() && time.Now().After(deadline)) {
+				plan.BudgetExceeded = true
+				break
+			}
+			plan.Expanded++
+			virtual, effects := node.parent.virtualBotAfter(node.candidate, node.effects)
+			virtual.Logger = virtualLogger
+			virtual.planDeadline = deadline
+			evaluated := virtual.evaluateCandidates()
+			if virtual.planningExpired() {
+				// Partially evaluated states would bias the plan towards skills evaluated first
+				plan.BudgetExceeded = true
+				break
+			}
+			for _, candidate := range topCandidates(evaluated, t.BeamWidth) {
+				children = append(children, planNode{
+					parent:    virtual,
+					effects:   effects,
+					candidate: candidate,
+					steps:     append(append([]PlanStep{}, node.steps...), newPlanStep(candidate)),
+					score:     node.score + discount*candidate.score,
+					first:     node.first,
+				})
+			}
+		}
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].score > children[j].score
+		})
+		if len(children) > t.BeamWidth {
+			children = children[:t.BeamWidth]
+		}
+		if len(children) > 0 && children[0].score > plan.Score {
+			plan.first = children[0].first
+			plan.Steps = children[0].steps
+			plan.Score = children[0].score
+		}
+		nodes = children
+		if plan.BudgetExceeded {
+			break
+		}
+	}
+	return plan
+}
+
+// planningExpired stops the evaluation of virtual bots at the planner deadline (see evaluateCandidates)
+func (b *Bot) planningExpired() bool {
+	return !b.planDeadline.IsZero() && time.Now().After(b.planDeadline)
+}
+
+func newPlanStep(candidate skillCandidate) PlanStep {
+	return PlanStep{
+		SkillName:      candidate.skill.Name,
+		TargetName:     candidate.target.GetName(),
+		TargetPosition: candidate.target.GetPosition(),
+		Score:          candidate.score,
+	}
+}
+
+// topCandidates returns up to n candidates with positive score, best first (stable for equal scores)
+func topCandidates(candidates []skillCandidate, n int) []skillCandidate {
+	top := []skillCandidate{}
+	for _, candidate := range candidates {
+		if candidate.score > 0 {
+			top = append(top, candidate)
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].score > top[j].score
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// virtualBotAfter returns a copy of the bot as it would be in the next tick after using the skill
+// The game state is shared, only the monster itself is copied
+func (b *Bot) virtualBotAfter(candidate skillCandidate, effects []plannedEffect) (*Bot, []plannedEffect) {
+	skill := candidate.skill
+	monster := *b.Details.Monster
+	attributes := *monster.Attributes
+	maxAttributes := monster.MaxAttributes
+
+	// Expire effects from previous steps
+	active := []plannedEffect{}
+	for _, effect := range effects {
+		effect.ticks--
+		if effect.ticks <= 0 {
+			subtractAttributes(&attributes, effect.attributes)
+			continue
+		}
+		active = append(active, effect)
+	}
+
+	attributes.Life -= skill.Cost.Life
+	attributes.Stamina -= skill.Cost.Stamina
+	attributes.Mana -= skill.Cost.Mana
+	if skill.CasterEffects != nil && skill.CasterEffects.Attributes != nil {
+		delta := b.skillAttributesValue(*skill.CasterEffects.Attributes)
+		attributes.Life += delta.Life
+		attributes.Stamina += delta.Stamina
+		attributes.Mana += delta.Mana
+		delta.Life, delta.Stamina, delta.Mana = 0, 0, 0
+		duration := int32(0)
+		if skill.Duration != nil {
+			duration = int32(b.calculateAttributesValue(*skill.Duration))
+		}
+		if duration > 0 && delta != (swagger.DungeonsandtrollsAttributes{}) {
+			addAttributes(&attributes, delta)
+			active = append(active, plannedEffect{attributes: delta, ticks: duration})
+		}
+	}
+	if maxAttributes != nil {
+		attributes.Life = minFloat32(attributes.Life, maxAttributes.Life)
+		attributes.Stamina = minFloat32(attributes.Stamina, maxAttributes.Stamina)
+		attributes.Mana = minFloat32(attributes.Mana, maxAttributes.Mana)
+	}
+	monster.Attributes = &attributes
+
+	position := *b.Details.Position
+	if skill.CasterEffects != nil && skill.CasterEffects.Flags.Movement {
+		if targetPosition := b.getSkillTargetPosition(&skill, &candidate.target); targetPosition != nil {
+			position = *targetPosition
+		}
+	}
+	mapObjects := *b.Details.MapObjects
+	mapObjects.Position = &position
+	mapObjects.Monsters = []swagger.DungeonsandtrollsMonster{monster}
+
+	virtual := *b
+	virtual.Trace = nil
+	virtual.Details.Monster = &mapObjects.Monsters[0]
+	virtual.Details.MapObjects = &mapObjects
+	virtual.Details.Index = 0
+	virtual.Details.Position = &position
+	virtual.BotState.Self = NewMonsterMapObject(mapObjects, 0)
+	if position != *b.Details.Position {
+		virtual.BotState.MapExtended = virtual.calculateDistanceAndLineOfSight(virtual.Details.Level, position)
+	}
+	return &virtual, active
+}
+
+func (b *Bot) skillAttributesValue(attrs swagger.DungeonsandtrollsSkillAttributes) swagger.DungeonsandtrollsAttributes {
+	value := func(a *swagger.DungeonsandtrollsAttributes) float32 {
+		if a == nil {
+			return 0
+		}
+		return b.calculateAttributesValue(*a)
+	}
+	return swagger.DungeonsandtrollsAttributes{
+		Strength:       value(attrs.Strength),
+		Dexterity:      value(attrs.Dexterity),
+		Intelligence:   value(attrs.Intelligence),
+		Willpower:      value(attrs.Willpower),
+		Constitution:   value(attrs.Constitution),
+		SlashResist:    value(attrs.SlashResist),
+		PierceResist:   value(attrs.PierceResist),
+		FireResist:     value(attrs.FireResist),
+		PoisonResist:   value(attrs.PoisonResist),
+		ElectricResist: value(attrs.ElectricResist),
+		Life:           value(attrs.Life),
+		Stamina:        value(attrs.Stamina),
+		Mana:           value(attrs.Mana),
+	}
+}
+
+func addAttributes(attrs *swagger.DungeonsandtrollsAttributes, delta swagger.DungeonsandtrollsAttributes) {
+	attrs.Strength += delta.Strength
+	attrs.Dexterity += delta.Dexterity
+	attrs.Intelligence += delta.Intelligence
+	attrs.Willpower += delta.Willpower
+	attrs.Constitution += delta.Constitution
+	attrs.SlashResist += delta.SlashResist
+	attrs.PierceResist += delta.PierceResist
+	attrs.FireResist += delta.FireResist
+	attrs.PoisonResist += delta.PoisonResist
+	attrs.ElectricResist += delta.ElectricResist
+}
+
+func subtractAttributes(attrs *swagger.DungeonsandtrollsAttributes, delta swagger.DungeonsandtrollsAttributes) {
+	delta.Strength, delta.Dexterity, delta.Intelligence, delta.Willpower, delta.Constitution =
+		-delta.Strength, -delta.Dexterity, -delta.Intelligence, -delta.Willpower, -delta.Constitution
+	delta.SlashResist, delta.PierceResist, delta.FireResist, delta.PoisonResist, delta.ElectricResist =
+		-delta.SlashResist, -delta.PierceResist, -delta.FireResist, -delta.PoisonResist, -delta.ElectricResist
+	addAttributes(attrs, delta)
+}
+
+func minFloat32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// plannerBot returns monster-1 fighting the player next to it and its scored candidates
+func plannerBot(t *testing.T) (*Bot, []skillCandidate) {
+	t.Helper()
+	d := newTestDispatcher()
+	state := loadArena(t)
+	state.Tick = 1
+	moveCharacter(state, "player-1", makePosition(9, 2))
+	// In combat so that the only follow-ups without stamina are moves (Rest requires being out of combat)
+	findMonster(state, "monster-1").LastDamageTaken = 0
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	bot := d.Bots["monster-1"]
+	return bot, bot.evaluateCandidates()
+}
+
+// bestCandidate returns the candidate with the highest score
+func bestCandidate(candidates []skillCandidate) skillCandidate {
+	best := candidates[0]
+	for _, candidate := range candidates {
+		if candidate.score > best.score {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// withExhaust adds a skill scored slightly better than the best one that costs all stamina
+// Using it first leaves no stamina for the follow-up attack
+func withExhaust(candidates []skillCandidate) []skillCandidate {
+	exhaust := bestCandidate(candidates)
+	cost := *exhaust.skill.Cost
+	cost.Stamina = 40
+	exhaust.skill.Cost = &cost
+	exhaust.skill.Name = "Exhaust"
+	exhaust.score += 0.1
+	exhaust.index = len(candidates)
+	return append(candidates, exhaust)
+}
+
+func TestPlanSkillSequence(t *testing.T) {
+	tests := []struct {
+		name       string
+		tuning     func(tuning *PlannerTuning)
+		expansions int
+		candidates func(candidates []skillCandidate) []skillCandidate
+		// Empty when no plan is expected
+		first          string
+		steps          int
+		budgetExceeded bool
+	}{
+		{
+			name:       "disabled with depth 1",
+			tuning:     func(tuning *PlannerTuning) { tuning.Depth = 1 },
+			expansions: DeterministicPlannerExpansions,
+		},
+		{
+			name:       "no candidates",
+			expansions: DeterministicPlannerExpansions,
+			candidates: func(candidates []skillCandidate) []skillCandidate { return nil },
+		},
+		{
+			name:       "no candidates with positive score",
+			expansions: DeterministicPlannerExpansions,
+			candidates: func(candidates []skillCandidate) []skillCandidate {
+				for i := range candidates {
+					candidates[i].score = -1
+				}
+				return candidates
+			},
+		},
+		{
+			name:       "attack twice",
+			expansions: DeterministicPlannerExpansions,
+			first:      "Slash",
+			steps:      2,
+		},
+		{
+			name:       "three steps",
+			tuning:     func(tuning *PlannerTuning) { tuning.Depth = 3 },
+			expansions: DeterministicPlannerExpansions,
+			first:      "Slash",
+			steps:      3,
+		},
+		{
+			name:       "better sequence beats the best first step",
+			expansions: DeterministicPlannerExpansions,
+			candidates: withExhaust,
+			first:      "Slash",
+			steps:      2,
+		},
+		{
+			name:       "narrow beam expands only the best first step",
+			tuning:     func(tuning *PlannerTuning) { tuning.BeamWidth = 1 },
+			expansions: DeterministicPlannerExpansions,
+			candidates: withExhaust,
+			first:      "Exhaust",
+			steps:      2,
+		},
+		{
+			name:       "following steps worth nothing without discount",
+			tuning:     func(tuning *PlannerTuning) { tuning.Discount = 0 },
+			expansions: DeterministicPlannerExpansions,
+			candidates: withExhaust,
+			first:      "Exhaust",
+			steps:      1,
+		},
+		{
+			// Only Exhaust is expanded
+			name:           "expansion budget",
+			expansions:     1,
+			candidates:     withExhaust,
+			first:          "Exhaust",
+			steps:          2,
+			budgetExceeded: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bot, candidates := plannerBot(t)
+			tuning := *bot.Tuning
+			if test.tuning != nil {
+				test.tuning(&tuning.Planner)
+			}
+			bot.Tuning = &tuning
+			bot.PlannerExpansions = test.expansions
+			if test.candidates != nil {
+				candidates = test.candidates(candidates)
+			}
+			plan := bot.planSkillSequence(candidates)
+			if test.first == "" {
+				if plan != nil {
+					t.Fatalf("expected no plan, got %+v", plan)
+				}
+				return
+			}
+			if plan == nil {
+				t.Fatal("expected plan")
+			}
+			if first := candidates[plan.first]; first.skill.Name != test.first || plan.Steps[0].SkillName != test.first {
+				t.Errorf("expected %s first, got %s (steps %+v)", test.first, first.skill.Name, plan.Steps)
+			}
+			if len(plan.Steps) != test.steps || plan.BudgetExceeded != test.budgetExceeded {
+				t.Errorf("expected %d steps (budget exceeded %v), got %+v", test.steps, test.budgetExceeded, plan)
+			}
+			if plan.Expanded > test.expansions {
+				t.Errorf("expanded %d states with budget %d", plan.Expanded, test.expansions)
+			}
+			score := float32(0)
+			discount := float32(1)
+			for _, step := range plan.Steps {
+				score += discount * step.Score
+				discount *= tuning.Planner.Discount
+			}
+			if math.Abs(float64(score-plan.Score)) > 1e-4 {
+				t.Errorf("expected discounted score %v, got %v", score, plan.Score)
+			}
+			// Plans are never worse than the best single skill
+			if plan.Score < bestCandidate(candidates).score {
+				t.Errorf("plan score %v below the best candidate %v", plan.Score, bestCandidate(candidates).score)
+			}
+		})
+	}
+}
+
+func TestVirtualBotAfter(t *testing.T) {
+	bot, candidates := plannerBot(t)
+	for _, candidate := range candidates {
+		virtual, _ := bot.virtualBotAfter(candidate, nil)
+		attributes := virtual.Details.Monster.Attributes
+		if expected := bot.Details.Monster.Attributes.Stamina - candidate.skill.Cost.Stamina; candidate.skill.Name == "Slash" && attributes.Stamina != expected {
+			t.Errorf("expected stamina %v after %s, got %v", expected, candidate.skill.Name, attributes.Stamina)
+		}
+		// Rest can't restore more than the maximum
+		if attributes.Stamina > virtual.Details.Monster.MaxAttributes.Stamina || attributes.Mana > virtual.Details.Monster.MaxAttributes.Mana {
+			t.Errorf("attributes above the maximum after %s: %+v", candidate.skill.Name, *attributes)
+		}
+		position := *bot.Details.Position
+		if candidate.skill.CasterEffects.Flags.Movement {
+			position = *candidate.target.GetPosition()
+		}
+		if *virtual.Details.Position != position || *virtual.BotState.Self.GetPosition() != position {
+			t.Errorf("expected virtual bot at %v after %s to %v, got %v", position, candidate.skill.Name, *candidate.target.GetPosition(), *virtual.Details.Position)
+		}
+		// The real monster is left untouched
+		if *bot.Details.Position != makePosition(10, 2) || bot.Details.Monster.Attributes.Stamina != 40 {
+			t.Fatalf("real monster changed by %s", candidate.skill.Name)
+		}
+	}
+}
+
+func TestTopCandidates(t *testing.T) {
+	candidate := func(index int, score float32) skillCandidate {
+		return skillCandidate{skill: swagger.DungeonsandtrollsSkill{Name: "skill"}, index: index, score: score}
+	}
+	candidates := []skillCandidate{candidate(0, 0.5), candidate(1, 0), candidate(2, 2), candidate(3, -1), candidate(4, 0.5), candidate(5, 1)}
+	tests := []struct {
+		n       int
+		indexes []int
+	}{
+		{1, []int{2}},
+		// Equal scores keep their order
+		{3, []int{2, 5, 0}},
+		{4, []int{2, 5, 0, 4}},
+		// Candidates without positive score are never planned
+		{10, []int{2, 5, 0, 4}},
+	}
+	for _, test := range tests {
+		top := topCandidates(candidates, test.n)
+		indexes := []int{}
+		for _, candidate := range top {
+			indexes = append(indexes, candidate.index)
+		}
+		if len(indexes) != len(test.indexes) {
+			t.Errorf("n %d: expected %v, got %v", test.n, test.indexes, indexes)
+			continue
+		}
+		for i := range indexes {
+			if indexes[i] != test.indexes[i] {
+				t.Errorf("n %d: expected %v, got %v", test.n, test.indexes, indexes)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/metrics"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/prettyprint"
+	"go.uber.org/zap"
+)
+
+// CommandBatching selects how commands are grouped into requests
+type CommandBatching string
+
+const (
+	// One request per monster as soon as the bot decides (lowest latency)
+	BatchPerMonster CommandBatching = "monster"
+	// One request per level when all bots on the level decided
+	BatchPerLevel CommandBatching = "level"
+	// One request per tick when all bots decided (fewest requests)
+	BatchPerTick CommandBatching = "tick"
+)
+
+var commandBatchings = []CommandBatching{BatchPerMonster, BatchPerLevel, BatchPerTick}
+
+func ParseCommandBatching(value string) (CommandBatching, error) {
+	for _, batching := range commandBatchings {
+		if CommandBatching(value) == batching {
+			return batching, nil
+		}
+	}
+	return "", fmt.Errorf("command batching must be one of %v (got %q)", commandBatchings, value)
+}
+
+const (
+	DefaultCommandRetries      = 2
+	DefaultCommandRetryBackoff = 50 * time.Millisecond
+	// Game ticks take about a second, older commands would be applied to a different game state
+	DefaultCommandTTL = time.Second
+)
+
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+	// Dropped without (another) attempt because the tick is over
+	DeliveryStale = "stale"
+	// Still retried after the tick deadline (the final status is stored later, see storeLateDeliveries)
+	DeliveryPending = "pending"
+)
+
+// DeliveryStatus of the command of one monster in one tick
+type DeliveryStatus struct {
+	Tick     int32     `json:"tick"`
+	Status   string    `json:"status"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// SendError is returned by the server sender, transient errors are retried within the tick
+type SendError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *SendError) Error() string {
+	if e.StatusCode == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("status code %d: %v", e.StatusCode, e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// Transient is true for network errors (no response), server errors and rate limiting
+func (e *SendError) Transient() bool {
+	return e.StatusCode == 0 || e.StatusCode >= 500 || e.StatusCode == 429
+}
+
+// Errors of custom senders are retried only when they implement Transient() bool
+func isTransient(err error) bool {
+	var transient interface{ Transient() bool }
+	return errors.As(err, &transient) && transient.Transient()
+}
+
+// commandSink batches the commands of one tick, delivers them and collects delivery statuses
+type commandSink struct {
+	dispatcher *BotDispatcher
+	tick       int32
+	batching   CommandBatching
+	// Commands are dropped as stale after this time
+	expires       time.Time
+	tickStartTime time.Time
+
+	lock sync.Mutex
+	// Commands waiting for their batch by level
+	pending  map[int32]map[string]swagger.DungeonsandtrollsCommandsBatch
+	statuses map[string]DeliveryStatus
+	wg       sync.WaitGroup
+}
+
+func (d *BotDispatcher) newCommandSink(tick int32) *commandSink {
+	batching := d.CommandBatching
+	if batching == "" {
+		batching = BatchPerMonster
+	}
+	return &commandSink{
+		dispatcher:    d,
+		tick:          tick,
+		batching:      batching,
+		expires:       d.TickStartTime.Add(d.CommandTTL),
+		tickStartTime: d.TickStartTime,
+		pending:       map[int32]map[string]swagger.DungeonsandtrollsCommandsBatch{},
+		statuses:      map[string]DeliveryStatus{},
+	}
+}
+
+// Add queues the command, it is sent right away when batching per monster
+func (s *commandSink) Add(level int32, monsterId string, cmd swagger.DungeonsandtrollsCommandsBatch, logger *zap.SugaredLogger) {
+	if s.batching == BatchPerMonster {
+		s.send(map[string]swagger.DungeonsandtrollsCommandsBatch{monsterId: cmd}, logger)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.pending[level] == nil {
+		s.pending[level] = map[string]swagger.DungeonsandtrollsCommandsBatch{}
+	}
+	s.pending[level][monsterId] = cmd
+}
+
+// FlushLevel sends the commands of the level when batching per level
+func (s *commandSink) FlushLevel(level int32, logger *zap.SugaredLogger) {
+	if s.batching != BatchPerLevel {
+		return
+	}
+	s.lock.Lock()
+	commands := s.pending[level]
+	delete(s.pending, level)
+	s.lock.Unlock()
+	s.send(commands, logger)
+}
+
+// Flush sends all remaining commands in one request
+func (s *commandSink) Flush(logger *zap.SugaredLogger) {
+	s.lock.Lock()
+	commands := map[string]swagger.DungeonsandtrollsCommandsBatch{}
+	for _, levelCommands := range s.pending {
+		for id, cmd := range levelCommands {
+			commands[id] = cmd
+		}
+	}
+	s.pending = map[int32]map[string]swagger.DungeonsandtrollsCommandsBatch{}
+	s.lock.Unlock()
+	s.send(commands, logger)
+}
+
+// Wait waits for all deliveries and returns the delivery status by monster ID
+func (s *commandSink) Wait() map[string]DeliveryStatus {
+	s.wg.Wait()
+	return s.Statuses()
+}
+
+// WaitUntil waits for all deliveries until the deadline (zero for no deadline)
+// Returns false if some deliveries are still pending
+func (s *commandSink) WaitUntil(deadline time.Time) (map[string]DeliveryStatus, bool) {
+	if deadline.IsZero() {
+		return s.Wait(), true
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return s.Statuses(), true
+	case <-time.After(time.Until(deadline)):
+		return s.Statuses(), false
+	}
+}
+
+// Statuses returns a copy of the delivery statuses known so far
+func (s *commandSink) Statuses() map[string]DeliveryStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	statuses := make(map[string]DeliveryStatus, len(s.statuses))
+	for id, status := range s.statuses {
+		statuses[id] = status
+	}
+	return statuses
+}
+
+// send delivers the batch in the background (synchronously for custom senders)
+func (s *commandSink) send(commands map[string]swagger.DungeonsandtrollsCommandsBatch, logger *zap.SugaredLogger) {
+	if len(commands) == 0 {
+		return
+	}
+	cmds := swagger.DungeonsandtrollsCommandsForMonsters{Commands: commands}
+	s.lock.Lock()
+	for id := range commands {
+		s.statuses[id] = DeliveryStatus{Tick: s.tick, Status: DeliveryPending}
+	}
+	s.lock.Unlock()
+	if s.dispatcher.Sender != nil {
+		s.deliver(cmds, logger)
+		return
+	}
+	s.wg.Add(1)
+	s.dispatcher.sends.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.dispatcher.sends.Done()
+		s.deliver(cmds, logger)
+	}()
+}
+
+// deliver retries transient failures until the command expires
+func (s *commandSink) deliver(cmds swagger.DungeonsandtrollsCommandsForMonsters, logger *zap.SugaredLogger) {
+	d := s.dispatcher
+	status := DeliveryStatus{Tick: s.tick}
+	backoff := d.CommandRetryBackoff
+	for {
+		if s.isStale(time.Now()) {
+			status.Status = DeliveryStale
+			break
+		}
+		status.Attempts++
+		err := d.sendMonsterCommands(cmds, s.tickStartTime, logger)
+		if err == nil {
+			status.Status = DeliveryDelivered
+			status.Error = ""
+			break
+		}
+		status.Status = DeliveryFailed
+		status.Error = err.Error()
+		if !isTransient(err) || status.Attempts > d.CommandRetries {
+			break
+		}
+		if s.isStale(time.Now().Add(backoff)) {
+			status.Status = DeliveryStale
+			break
+		}
+		logger.Warnw("Retrying to send commands",
+			"attempts", status.Attempts,
+			"backoff", backoff,
+			zap.Error(err),
+		)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	status.Time = time.Now()
+	if status.Status == DeliveryStale {
+		logger.Warnw("Dropping stale commands",
+			"commandsTick", s.tick,
+			"tick", d.Tick(),
+			"attempts", status.Attempts,
+			"monstersCount", len(cmds.Commands),
+		)
+	}
+	metrics.CommandDeliveries.WithLabelValues(status.Status).Add(float64(len(cmds.Commands)))
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for id, cmd := range cmds.Commands {
+		s.statuses[id] = status
+		if status.Status == DeliveryDelivered {
+			metrics.CountCommand(prettyprint.CommandType(&cmd))
+		}
+	}
+}
+
+// Commands from previous ticks or past their TTL would be applied to a different game state
+func (s *commandSink) isStale(at time.Time) bool {
+	if s.tick < s.dispatcher.Tick() {
+		return true
+	}
+	return s.dispatcher.CommandTTL > 0 && at.After(s.expires)
+}
+
+// DeliverySummary counts the deliveries by status
+func DeliverySummary(statuses map[string]DeliveryStatus) map[string]int {
+	summary := map[string]int{}
+	for _, status := range statuses {
+		summary[status.Status]++
+	}
+	return summary
+}
+
+// failedDeliveries returns IDs of monsters whose commands were not delivered (sorted)
+func failedDeliveries(statuses map[string]DeliveryStatus) []string {
+	ids := []string{}
+	for id, status := range statuses {
+		if status.Status != DeliveryDelivered && status.Status != DeliveryPending {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"go.uber.org/zap"
+)
+
+// Retries past the tick deadline finish in the background and their status is stored later
+func TestHandleTickDoesNotWaitForLateDeliveries(t *testing.T) {
+	requests := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// First attempt of both monsters is slow and fails
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			time.Sleep(300 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	cfg := swagger.NewConfiguration()
+	cfg.BasePath = server.URL
+	d := NewBotDispatcher(swagger.NewAPIClient(cfg), context.Background(), zap.NewNop().Sugar(), "test")
+	d.TickDeadline = 100 * time.Millisecond
+	d.CommandTTL = 0
+
+	state := loadArena(t)
+	state.Tick = 1
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	stats := d.LastTickStats
+	if stats.Duration >= 300*time.Millisecond {
+		t.Errorf("tick waited for the deliveries (%v)", stats.Duration)
+	}
+	if stats.Deliveries[DeliveryPending] != 2 {
+		t.Errorf("expected 2 pending deliveries, got %v", stats.Deliveries)
+	}
+
+	// The late statuses are stored once all deliveries of the tick finished
+	delivered := func(id string) bool {
+		snapshot, found := d.BotSnapshot(id)
+		return found && snapshot.Delivery != nil && snapshot.Delivery.Status == DeliveryDelivered && snapshot.Delivery.Attempts == 2
+	}
+	for deadline := time.Now().Add(5 * time.Second); !delivered("monster-1") || !delivered("monster-2"); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			snapshot, _ := d.BotSnapshot("monster-1")
+			t.Fatalf("expected monsters delivered in 2 attempts, got %+v", snapshot.Delivery)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package bot
 
 import (
 	"math"
-	"math/rand"
 
 	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
 )
@@ -25,6 +24,15 @@ type SkillResult struct {
 	// MovementFriendly float32
 
 	Random float32
+
+	// Focus fire, ally support and tile reservation bonuses (see Coordinator)
+	Coordination float32
+	// Retaliation against hostiles with high threat (see ThreatTable)
+	Threat float32
+	// Attacking players approaching or standing on the guarded object (see guardian.go)
+	Guardian float32
+	// Healing and buffing allies by triage priority (see support.go)
+	Support float32
 }
 
 func (sr *SkillResult) Add(other SkillResult) *SkillResult {
@@ -39,6 +47,10 @@ func (sr *SkillResult) Add(other SkillResult) *SkillResult {
 	sr.ResistsSelf += other.ResistsSelf
 	sr.MovementSelf += other.MovementSelf
 	sr.Random += other.Random
+	sr.Coordination += other.Coordination
+	sr.Threat += other.Threat
+	sr.Guardian += other.Guardian
+	sr.Support += other.Support
 	return sr
 }
 
@@ -61,17 +73,16 @@ func (b *Bot) isLegalSkillTargetCombination(skill swagger.DungeonsandtrollsSkill
 	if target.IsEmpty() && *skill.Target != swagger.POSITION_SkillTarget {
 		// Target CHARACTER can't be used with empty targets
 		// Target NONE is possible but useless - we use "self" to evaluate "target NONE" skills
-		b.Logger.Infow("Skipping skill evaluation for empty target")
 		return false
 	}
 	return true
 }
 
-func (b *Bot) evaluateSkill(skill swagger.DungeonsandtrollsSkill, target MapObject) SkillResult {
+// Returns rejection reason (and empty result) when the skill can't be used on the target
+func (b *Bot) evaluateSkill(skill swagger.DungeonsandtrollsSkill, target MapObject) (SkillResult, string) {
 	empty := SkillResult{}
 	if !b.areAttributeRequirementMet(*skill.Cost) {
-		b.Logger.Infow("Skill attributes cost requirement not met")
-		return empty
+		return empty, RejectionCostNotMet
 	}
 
 	casterPosition := b.Details.Position
@@ -82,24 +93,22 @@ func (b *Bot) evaluateSkill(skill swagger.DungeonsandtrollsSkill, target MapObje
 		"manhattanDistance", manhattanDistance(*casterPosition, *targetPosition),
 	)
 	if manhattanDistance(*casterPosition, *targetPosition) > int32(b.calculateAttributesValue(*skill.Range_)) {
-		b.Logger.Infow("Target out of skill range")
-		return empty
+		return empty, RejectionOutOfRange
 	}
-	if skill.Flags.RequiresLineOfSight && !b.BotState.MapExtended[*targetPosition].lineOfSight {
-		b.Logger.Infow("Target is not in line of sight")
-		return empty
+	if skill.Flags.RequiresLineOfSight && !b.BotState.MapExtended.At(*targetPosition).lineOfSight {
+		return empty, RejectionNoLineOfSight
 	}
 	// TODO: Check out of combat
 
 	radius := int32(b.calculateAttributesValue(*skill.Radius))
 
 	// Eval yourself
-	b.Logger.Infow("Eval for caster")
+	b.Logger.Debugw("Eval for caster")
 	result := b.evalEffectFor(&b.BotState.Self, skill.CasterEffects, &skill, false)
-	result.Random = rand.Float32()
+	result.Random = b.Rand.Float32()
 	// Eval movement for self
 	if skill.CasterEffects.Flags.Movement {
-		result.MovementSelf = float32(b.scoreMovementDiff(targetPosition)) / 3
+		result.MovementSelf = float32(b.scoreMovementDiff(targetPosition)) / b.Tuning.Movement.SkillMovementDivisor
 	}
 	// Eval ground effect around caster
 	if skill.CasterEffects.Flags.GroundEffect {
@@ -108,43 +117,43 @@ func (b *Bot) evaluateSkill(skill swagger.DungeonsandtrollsSkill, target MapObje
 			target_ := targets[i]
 			result.Add(b.evalEffectFor(&target_, skill.TargetEffects, &skill, true))
 		}
-		return result
+		return result, ""
 	}
 	// Radius 0 special case
 	if radius <= 0 {
 		// Eval target if character
 		if *skill.Target == swagger.CHARACTER_SkillTarget {
-			b.Logger.Infow("Eval for character target",
+			b.Logger.Debugw("Eval for character target",
 				"target", target.GetName(),
 				"resultBefore", result,
 			)
 			result.Add(b.evalEffectFor(&target, skill.TargetEffects, &skill, true))
-			b.Logger.Infow("AFTER Eval for character target",
+			b.Logger.Debugw("AFTER Eval for character target",
 				"target", target.GetName(),
 				"resultAfter", result,
 			)
 		} else if *skill.Target == swagger.POSITION_SkillTarget {
 			targets := b.findTargetsInRadius(*targetPosition, radius)
-			b.Logger.Infow("Eval for position target",
+			b.Logger.Debugw("Eval for position target",
 				"numTargets", len(targets),
 			)
 			for i := range targets {
 				target_ := targets[i]
 				if target_.GetId() == b.BotState.Self.GetId() {
-					b.Logger.Infow("No eval for self")
+					b.Logger.Debugw("No eval for self")
 					continue
 				}
 				result.Add(b.evalEffectFor(&target_, skill.TargetEffects, &skill, true))
 			}
 		} else {
-			b.Logger.Infow("No Eval for none target")
+			b.Logger.Debugw("No Eval for none target")
 		}
-		return result
+		return result, ""
 	}
 	// Eval AoE / ground effect around target
 	targets := b.findTargetsInRadius(*targetPosition, radius)
 	for i := range targets {
-		b.Logger.Infow("Eval for AoE / ground effect",
+		b.Logger.Debugw("Eval for AoE / ground effect",
 			"numTargets", len(targets),
 		)
 		target_ := targets[i]
@@ -153,7 +162,7 @@ func (b *Bot) evaluateSkill(skill swagger.DungeonsandtrollsSkill, target MapObje
 		}
 		result.Add(b.evalEffectFor(&target_, skill.TargetEffects, &skill, true))
 	}
-	return result
+	return result, ""
 }
 
 func (b *Bot) evalEffectFor(target *MapObject, effect *swagger.DungeonsandtrollsSkillEffect, skill *swagger.DungeonsandtrollsSkill, withDamage bool) SkillResult {
@@ -173,28 +182,29 @@ func (b *Bot) evalEffectFor(target *MapObject, effect *swagger.Dungeonsandtrolls
 		// withCost
 		vitalsScore, buffsScore, resistsScore = b.scoreVitalsWithCost(effect.Attributes, skill)
 	}
+	t := b.Tuning.Effects
 	if effect.Flags.Stun && !b.GetStunInfo(*target).IsImmune {
 		if target.GetId() == b.Details.Id {
-			vitalsScore -= 0.4
+			vitalsScore -= t.StunSelfPenalty
 		} else if b.IsHostile(*target) {
-			vitalsScore -= 0.3
+			vitalsScore -= t.StunHostilePenalty
 		} else {
-			vitalsScore -= 0.1
+			vitalsScore -= t.StunFriendlyPenalty
 		}
 	}
 	if effect.Flags.Movement {
 		if b.IsHostile(*target) {
-			vitalsScore -= 0.3
+			vitalsScore -= t.MovementHostilePenalty
 		} else if b.IsFriendly(*target) {
-			vitalsScore += 0.15
+			vitalsScore += t.MovementFriendlyBonus
 		}
 	}
 	if effect.Flags.Knockback {
-		vitalsScore -= 0.2
+		vitalsScore -= t.KnockbackPenalty
 	}
 	vitalsSummons := float32(0)
 	if effect.Summons != nil && len(effect.Summons) > 0 {
-		vitalsSummons += 0.7
+		vitalsSummons += t.SummonsBonus
 	}
 	// XXX: Maybe make bigger targets worth more
 	//      Not relevant because players are on the same level
@@ -238,7 +248,7 @@ func (b *Bot) findTargetsInRange(position swagger.DungeonsandtrollsPosition, dis
 			if !b.isInBounds(b.Details.Level, pos) || manhattanDistance(pos, position) > dist {
 				continue
 			}
-			targets = append(targets, extractTargets(b.BotState.MapExtended[pos].mapObjects)...)
+			targets = append(targets, extractTargets(b.BotState.MapExtended.At(pos).mapObjects)...)
 		}
 	}
 	return targets
@@ -256,7 +266,7 @@ func (b *Bot) findTargetsInRangeAsMap(position swagger.DungeonsandtrollsPosition
 		for x := xStart; x <= xEnd; x++ {
 			pos := makePosition(x, y)
 			distance := manhattanDistance(pos, position)
-			tileInfo, found := b.BotState.MapExtended[pos]
+			tileInfo, found := b.BotState.MapExtended.Lookup(pos)
 			if !found || distance > dist || !tileInfo.lineOfSight {
 				continue
 			}
@@ -267,7 +277,7 @@ func (b *Bot) findTargetsInRangeAsMap(position swagger.DungeonsandtrollsPosition
 				targetNames = append(targetNames, target_.GetName())
 			}
 			if len(targets[int(distance)]) > 0 {
-				b.Logger.Infow("Targets added?",
+				b.Logger.Debugw("Targets added?",
 					"position", pos,
 					"targetNames", targetNames,
 					"targetCount", len(targets[int(distance)]),
@@ -295,7 +305,7 @@ func (b *Bot) findTargetsInRadius(position swagger.DungeonsandtrollsPosition, di
 			if !b.isInBounds(b.Details.Level, pos) || euclidDistance(pos, position) > dist {
 				continue
 			}
-			targets = append(targets, extractTargets(b.BotState.MapExtended[pos].mapObjects)...)
+			targets = append(targets, extractTargets(b.BotState.MapExtended.At(pos).mapObjects)...)
 		}
 	}
 	return targets
@@ -329,48 +339,51 @@ func (b *Bot) scoreMovementDiff(position *swagger.DungeonsandtrollsPosition) flo
 }
 
 func (b *Bot) scoreMovement(position *swagger.DungeonsandtrollsPosition) float32 {
+	t := b.Tuning.Movement
 	distances := b.calculateDistancesForPosition(position)
 	distances.NumCloseFriendly += 1
 	distances.NumCloseHostiles += 1
-	if distances.NumCloseHostiles > 10 {
-		distances.NumCloseHostiles = 10
+	if distances.NumCloseHostiles > t.MaxCloseCount {
+		distances.NumCloseHostiles = t.MaxCloseCount
 	}
-	if distances.NumCloseFriendly > 10 {
-		distances.NumCloseFriendly = 10
+	if distances.NumCloseFriendly > t.MaxCloseCount {
+		distances.NumCloseFriendly = t.MaxCloseCount
 	}
-	if distances.DistanceToSpawn > 10 {
-		distances.DistanceToSpawn = 10
+	if distances.DistanceToSpawn > t.MaxSpawnDistance {
+		distances.DistanceToSpawn = t.MaxSpawnDistance
 	}
-	scoreClosestHostile := 10 / float32(distances.DistanceToClosestHostile+10)
-	if distances.DistanceToClosestHostile < 2 {
-		scoreClosestHostile -= 0.08
+	w := b.behaviorWeights()
+	scoreClosestHostile := t.ClosestHostileHalfDistance / (float32(distances.DistanceToClosestHostile) + t.ClosestHostileHalfDistance)
+	// Adjacency penalties would turn into bonuses when keeping away from hostiles
+	if distances.DistanceToClosestHostile < 2 && w.ClosestHostile > 0 {
+		scoreClosestHostile -= t.ClosestHostileAdjacentPenalty
 		if distances.DistanceToClosestHostile == 0 {
-			scoreClosestHostile -= 0.06
+			scoreClosestHostile -= t.ClosestHostileSameTilePenalty
 		}
 	}
-	scoreClosestFriendly := 10 / float32(distances.DistanceToClosestFriendly+10)
+	scoreClosestFriendly := t.ClosestFriendlyHalfDistance / (float32(distances.DistanceToClosestFriendly) + t.ClosestFriendlyHalfDistance)
 	if distances.DistanceToClosestFriendly < 2 {
-		scoreClosestFriendly -= 0.21
+		scoreClosestFriendly -= t.ClosestFriendlyAdjacentPenalty
 		if distances.DistanceToClosestFriendly == 0 {
-			scoreClosestFriendly -= 0.31
+			scoreClosestFriendly -= t.ClosestFriendlySameTilePenalty
 		}
 	}
-	scoreTargetPosition := 20 / float32(distances.DistanceToTargetPosition+20)
+	scoreTargetPosition := t.TargetPositionHalfDistance / (float32(distances.DistanceToTargetPosition) + t.TargetPositionHalfDistance)
 
-	scoreDistToSelf := float32(distances.DistanceToSelf) / 40
-	scoreDistToSpawn := float32(distances.DistanceToSpawn) / 10
-	scoreNumHostiles := float32(distances.NumCloseHostiles) / 10
-	scoreNumFriendly := float32(distances.NumCloseFriendly) / 10
+	scoreDistToSelf := float32(distances.DistanceToSelf) / t.DistanceToSelfDivisor
+	scoreDistToSpawn := float32(distances.DistanceToSpawn) / float32(t.MaxSpawnDistance)
+	scoreNumHostiles := float32(distances.NumCloseHostiles) / float32(t.MaxCloseCount)
+	scoreNumFriendly := float32(distances.NumCloseFriendly) / float32(t.MaxCloseCount)
 
 	scorePosition := float32(0)
-	tileInfo, found := b.BotState.MapExtended[*position]
+	tileInfo, found := b.BotState.MapExtended.Lookup(*position)
 	if found {
 		if tileInfo.mapObjects.IsStairs || tileInfo.mapObjects.IsSpawn {
-			scorePosition -= 0.7
+			scorePosition -= t.StairsOrSpawnPenalty
 		}
 		for _, monster := range tileInfo.mapObjects.Monsters {
 			if monster.Id != b.Details.Monster.Id {
-				scorePosition -= 0.12
+				scorePosition -= t.OtherMonsterPenalty
 			}
 		}
 		for _, effect := range tileInfo.mapObjects.Effects {
@@ -378,19 +391,30 @@ func (b *Bot) scoreMovement(position *swagger.DungeonsandtrollsPosition) float32
 		}
 	}
 
+	// Only fleeing and kiting monsters have a danger map
+	scoreDanger := b.BotState.Danger.At(*position)
+	scoreKiteDistance := b.scoreKiteDistance(position)
+	// Only guardians block paths
+	scoreBlocking := b.scoreBlocking(position)
+	scoreSupportPosition := b.scoreSupportPosition(position)
+
 	vitalsSelf := b.getCurrentVitals()
-	vitalsCoef := (vitalsSelf - 4) / 5 // assuming 0-10
+	vitalsCoef := (vitalsSelf - t.VitalsCoefOffset) / t.VitalsCoefDivisor // assuming 0-10
 	// TODO: use distances and vitals
-	result := b.Config.Restlessness*scoreDistToSelf +
-		-scoreDistToSpawn*4.5 +
-		scoreClosestHostile*6 +
-		scoreClosestFriendly*2 +
-		scoreTargetPosition*3 +
-		vitalsCoef*scoreNumHostiles*3 +
-		scoreNumFriendly*4 +
+	result := w.Restlessness*b.Config.Restlessness*scoreDistToSelf +
+		-w.Spawn*scoreDistToSpawn*t.DistanceToSpawnWeight +
+		w.ClosestHostile*scoreClosestHostile*t.ClosestHostileWeight +
+		w.ClosestFriendly*scoreClosestFriendly*t.ClosestFriendlyWeight +
+		scoreTargetPosition*t.TargetPositionWeight +
+		vitalsCoef*scoreNumHostiles*t.NumHostilesWeight +
+		scoreNumFriendly*t.NumFriendlyWeight +
+		-w.Danger*scoreDanger*b.Tuning.Danger.MovementWeight +
+		scoreKiteDistance +
+		w.Block*scoreBlocking*b.Tuning.Guardian.BlockWeight +
+		w.Support*scoreSupportPosition +
 		scorePosition
 
-	b.Logger.Infow("Evaluated movement score for self",
+	b.Logger.Debugw("Evaluated movement score for self",
 		"result.MovementSelf", result,
 		"distances", distances,
 		"myPosition", b.Details.Position,
@@ -403,8 +427,12 @@ func (b *Bot) scoreMovement(position *swagger.DungeonsandtrollsPosition) float32
 		"distanceToTargetPosition", distances.DistanceToTargetPosition,
 		"scoreNumHostiles", scoreNumHostiles,
 		"scoreNumFriendly", scoreNumFriendly,
+		"scoreBlocking", scoreBlocking,
+		"scoreSupportPosition", scoreSupportPosition,
 		"vitalsSelf", vitalsSelf,
 		"vitalsCoef", vitalsCoef,
+		"scoreDanger", scoreDanger,
+		"scoreKiteDistance", scoreKiteDistance,
 	)
 	return result
 }
@@ -436,7 +464,7 @@ func (b *Bot) evalGroundEffect(effect *swagger.DungeonsandtrollsEffect) float32
 	}
 	vitals, buffs, resists := b.scoreVitalsFor(&b.BotState.Self, &wrappedAttrs, &damageAttrs, -1, &skill)
 	total := vitals + buffs + resists
-	b.Logger.Infow("Evaluated ground effect",
+	b.Logger.Debugw("Evaluated ground effect",
 		"effect", effect,
 		"vitalsScore", vitals,
 		"buffsScore", buffs,
@@ -465,8 +493,6 @@ type Distances struct {
 	NumCloseFriendly int
 }
 
-const CLOSE_DISTANCE = 12
-
 func (b *Bot) calculateDistancesForPosition(position *swagger.DungeonsandtrollsPosition) Distances {
 	dists := Distances{
 		DistanceToSelf:            manhattanDistance(*b.Details.Position, *position),
@@ -479,19 +505,19 @@ func (b *Bot) calculateDistancesForPosition(position *swagger.DungeonsandtrollsP
 	var tileInfo MapCellExt
 	found := false
 	if b.BotState.TargetPosition != nil {
-		tileInfo, found = b.BotState.MapExtended[*b.BotState.TargetPosition]
+		tileInfo, found = b.BotState.MapExtended.Lookup(*b.BotState.TargetPosition)
 	}
 	if found && tileInfo.lineOfSight {
 		dists.DistanceToTargetPosition = manhattanDistance(*position, *b.BotState.TargetPosition)
 	}
 	for _, obj := range b.Details.CurrentMap.Objects {
 		if obj.IsSpawn {
-			tileInfo, found := b.BotState.MapExtended[*obj.Position]
+			tileInfo, found := b.BotState.MapExtended.Lookup(*obj.Position)
 			if found {
 				dists.DistanceToSpawn = int32(tileInfo.distance)
 			}
 		}
-		if !b.BotState.MapExtended[*obj.Position].lineOfSight {
+		if !b.BotState.MapExtended.At(*obj.Position).lineOfSight {
 			// Skip position without line of sight
 			continue
 		}
@@ -510,12 +536,13 @@ func (b *Bot) calculateDistancesForPosition(position *swagger.DungeonsandtrollsP
 				dists.NumCloseFriendly += len(obj.Players)
 			}
 		}
-		if dist > CLOSE_DISTANCE {
+		if dist > b.Tuning.Movement.CloseDistance {
 			continue
 		}
 
 		for i, monster := range obj.Monsters {
-			if monster.Faction == "neutral" {
+			// Self is already counted in NumCloseFriendly
+			if monster.Faction == "neutral" || monster.Id == b.Details.Monster.Id {
 				continue
 			}
 			mo := NewMonsterMapObject(obj, i)
@@ -126,7 +126,9 @@ func (b *Bot) useSkill(skill swagger.DungeonsandtrollsSkill, target MapObject) *
 		"target", target.GetName(),
 		"targetPosition", target.GetPosition(),
 	)
+	b.recordSkillUse(skill, target)
 	if skill.CasterEffects.Flags.Movement {
+		b.reserveDestination(b.enteredPosition(skill, target))
 		if b.BotState.TargetPosition == nil {
 			stretchedPosition := b.stretchMovePosition(*target.GetPosition())
 			b.BotState.TargetPosition = &stretchedPosition
@@ -256,11 +258,14 @@ func (b *Bot) filterActiveSkills(skills []swagger.DungeonsandtrollsSkill) []swag
 	return filtered
 }
 
+// Skills filtered out by the two filters below are traced as rejected for the monster itself
 func (b *Bot) filterRequirementsMetSkills(skills []swagger.DungeonsandtrollsSkill) []swagger.DungeonsandtrollsSkill {
 	filtered := []swagger.DungeonsandtrollsSkill{}
 	for _, skill := range skills {
 		if b.areAttributeRequirementMet(*skill.Cost) {
 			filtered = append(filtered, skill)
+		} else {
+			b.Trace.AddEvaluation(skill, b.BotState.Self, nil, 0, RejectionCostNotMet)
 		}
 	}
 	return filtered
@@ -275,6 +280,8 @@ func (b *Bot) filterCastableWithOOCSkills(skills []swagger.DungeonsandtrollsSkil
 	for _, skill := range skills {
 		if !skill.Flags.RequiresOutOfCombat {
 			filtered = append(filtered, skill)
+		} else {
+			b.Trace.AddEvaluation(skill, b.BotState.Self, nil, 0, RejectionInCombat)
 		}
 	}
 	return filtered
@@ -297,13 +304,31 @@ func (b *Bot) filterDamageSkills(skills []swagger.DungeonsandtrollsSkill) []swag
 func (b *Bot) filterHealingSkills(skills []swagger.DungeonsandtrollsSkill) []swagger.DungeonsandtrollsSkill {
 	filtered := []swagger.DungeonsandtrollsSkill{}
 	for _, skill := range skills {
-		if b.calculateAttributesValue(*skill.TargetEffects.Attributes.Life) < 0 {
+		if b.skillHealing(skill) > 0 {
 			filtered = append(filtered, skill)
 		}
 	}
 	return filtered
 }
 
+// Buff skills
+
+// Skills raising strength, dexterity, ... or resists of their targets
+func (b *Bot) filterBuffSkills(skills []swagger.DungeonsandtrollsSkill) []swagger.DungeonsandtrollsSkill {
+	filtered := []swagger.DungeonsandtrollsSkill{}
+	for _, skill := range skills {
+		signature := b.effectSignature(targetEffectAttributes(skill))
+		// Life, stamina and mana are the last three
+		for _, sign := range signature[:10] {
+			if sign > 0 {
+				filtered = append(filtered, skill)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
 // Movement skills
 
 func (b *Bot) filterMovementSkills(skills []swagger.DungeonsandtrollsSkill) []swagger.DungeonsandtrollsSkill {
@@ -0,0 +1,283 @@
+package bot
+
+import (
+	"math"
+	"sort"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+const RejectionActiveEffect = "target already under the effect"
+
+// AllyTriage rates how much an ally needs healing and buffs (see Tuning.Support)
+type AllyTriage struct {
+	// Ratio of max life (0-1)
+	MissingLife float32 `json:"missingLife"`
+	// Damage hostiles in reach can deal next tick relative to max life (0-1)
+	IncomingDamage float32 `json:"incomingDamage"`
+	// Importance of the ally algorithm (see Tuning.Support.RoleImportance)
+	Role     float32                           `json:"role"`
+	Priority float32                           `json:"priority"`
+	Position swagger.DungeonsandtrollsPosition `json:"position"`
+}
+
+// TriageTable maps ally IDs to triage, it is rebuilt every tick for monsters with healing or buff skills
+type TriageTable map[string]AllyTriage
+
+func (b *Bot) updateTriage() {
+	b.BotState.Triage = nil
+	b.BotState.SupportRange = 0
+	skills := b.supportSkills()
+	if b.Config.Support <= 0 || len(skills) == 0 {
+		return
+	}
+	for _, skill := range skills {
+		if skill.Range_ == nil {
+			continue
+		}
+		if skillRange := int32(b.calculateAttributesValue(*skill.Range_)); skillRange > b.BotState.SupportRange {
+			b.BotState.SupportRange = skillRange
+		}
+	}
+	triage := TriageTable{}
+	for _, ally := range b.BotState.Objects.Friendly {
+		if ally.GetId() == b.MonsterId {
+			continue
+		}
+		attrs, maxAttrs := ally.GetAttributes(), ally.GetMaxAttributes()
+		if attrs == nil || maxAttrs == nil || maxAttrs.Life <= 0 || attrs.Life <= 0 {
+			continue
+		}
+		triage[ally.GetId()] = b.triageAlly(ally, *attrs, *maxAttrs)
+	}
+	b.BotState.Triage = triage
+	if len(triage) > 0 {
+		b.Logger.Debugw("Allies triaged",
+			"triage", triage,
+			"mostNeedyAllyId", triage.MostNeedy(),
+		)
+	}
+}
+
+func (b *Bot) triageAlly(ally MapObject, attrs, maxAttrs swagger.DungeonsandtrollsAttributes) AllyTriage {
+	t := b.Tuning.Support
+	triage := AllyTriage{
+		MissingLife:    1 - attrs.Life/maxAttrs.Life,
+		IncomingDamage: b.incomingDamage(ally) / maxAttrs.Life,
+		Role:           t.DefaultRoleImportance,
+		Position:       *ally.GetPosition(),
+	}
+	if triage.IncomingDamage > 1 {
+		triage.IncomingDamage = 1
+	}
+	if ally.Type == MapObjectTypeMonster {
+		if role, found := t.RoleImportance[ally.MapObjects.Monsters[ally.Index].Algorithm]; found {
+			triage.Role = role
+		}
+	}
+	triage.Priority = t.MissingLifeWeight*triage.MissingLife + t.IncomingDamageWeight*triage.IncomingDamage + t.RoleWeight*triage.Role
+	return triage
+}
+
+// incomingDamage anticipates damage of hostiles that can hit the ally next tick (in range or Tuning.Danger.ReachMargin tiles away)
+func (b *Bot) incomingDamage(ally MapObject) float32 {
+	layout := b.levelCache().Layout
+	allyPosition := *ally.GetPosition()
+	damage := float32(0)
+	for _, hostile := range b.BotState.Objects.Hostile {
+		hostilePosition := *hostile.GetPosition()
+		reach := b.hostileRange(hostile) + b.Tuning.Danger.ReachMargin
+		if manhattanDistance(hostilePosition, allyPosition) > reach || layout.Distance(hostilePosition, allyPosition) > reach {
+			continue
+		}
+		damage += b.hostileDamage(hostile)
+	}
+	return damage
+}
+
+// hostileDamage is the damage of the strongest damage skill of the hostile (resists are ignored)
+func (b *Bot) hostileDamage(hostile MapObject) float32 {
+	attrs := hostile.GetAttributes()
+	if attrs == nil {
+		return 0
+	}
+	damage := float32(0)
+	for _, skill := range getAllSkills(hostile.GetEquippedItems()) {
+		if (skill.Flags != nil && skill.Flags.Passive) || skill.DamageAmount == nil {
+			continue
+		}
+		damage = float32(math.Max(float64(damage), float64(calculateAttributesValue(*attrs, *skill.DamageAmount))))
+	}
+	return damage
+}
+
+// MostNeedy returns the ID of the ally with the highest priority ("" for empty table)
+func (tt TriageTable) MostNeedy() string {
+	ids := make([]string, 0, len(tt))
+	for id := range tt {
+		ids = append(ids, id)
+	}
+	// Deterministic tie breaking
+	sort.Strings(ids)
+	bestId := ""
+	for _, id := range ids {
+		if bestId == "" || tt[id].Priority > tt[bestId].Priority {
+			bestId = id
+		}
+	}
+	return bestId
+}
+
+// scoreSupportPosition pulls support monsters towards the most needy ally until it is in range of their support skills
+func (b *Bot) scoreSupportPosition(position *swagger.DungeonsandtrollsPosition) float32 {
+	supportRange := b.BotState.SupportRange
+	triage, found := b.needyAlly()
+	if supportRange <= 0 || !found {
+		return 0
+	}
+	score := b.Tuning.Support.PositionWeight * b.Config.Support * triage.Priority
+	distance := manhattanDistance(*position, triage.Position)
+	if distance <= supportRange {
+		return score
+	}
+	return score * float32(supportRange) / float32(distance)
+}
+
+// needyAlly is the ally with the highest priority that is hurt or about to be hurt
+func (b *Bot) needyAlly() (AllyTriage, bool) {
+	id := b.BotState.Triage.MostNeedy()
+	if id == "" {
+		return AllyTriage{}, false
+	}
+	triage := b.BotState.Triage[id]
+	return triage, triage.MissingLife+triage.IncomingDamage > 0
+}
+
+// supportKiteRange lets kiting support monsters come closer when the needy ally is out of support range
+func (b *Bot) supportKiteRange(kiteRange int32) int32 {
+	supportRange := b.BotState.SupportRange
+	triage, found := b.needyAlly()
+	if !found || supportRange <= 0 || supportRange >= kiteRange || manhattanDistance(*b.Details.Position, triage.Position) <= supportRange {
+		return kiteRange
+	}
+	b.Logger.Debugw("Kiting closer to support ally",
+		"kiteRange", kiteRange,
+		"supportRange", supportRange,
+		"allyPosition", triage.Position,
+	)
+	return supportRange
+}
+
+// Active skills that heal or buff targets
+func (b *Bot) supportSkills() []swagger.DungeonsandtrollsSkill {
+	skills := b.filterActiveSkills(getAllSkills(b.Details.Monster.EquippedItems))
+	return append(b.filterHealingSkills(skills), b.filterBuffSkills(skills)...)
+}
+
+// supportBonus prefers healing and buffing allies with the highest triage priority
+// Healing more than the ally misses (including the anticipated damage) is penalized
+func (b *Bot) supportBonus(skill swagger.DungeonsandtrollsSkill, target MapObject, result SkillResult) float32 {
+	if target.IsEmpty() || result.VitalsFriendly+result.BuffsFriendly+result.ResistsFriendly <= 0 {
+		return 0
+	}
+	triage, found := b.BotState.Triage[target.GetId()]
+	if !found {
+		return 0
+	}
+	t := b.Tuning.Support
+	bonus := t.TriageBonus * triage.Priority * b.Config.Support
+	healing := b.skillHealing(skill)
+	maxAttrs := target.GetMaxAttributes()
+	if healing <= 0 || maxAttrs == nil {
+		return bonus
+	}
+	need := float32(math.Min(1, float64(triage.MissingLife+triage.IncomingDamage))) * maxAttrs.Life
+	if healing <= need {
+		return bonus
+	}
+	wasted := (healing - need) / healing
+	return bonus*(1-wasted) - t.OverhealPenalty*wasted
+}
+
+// skillHealing is the life the skill restores to its target (over its whole duration)
+func (b *Bot) skillHealing(skill swagger.DungeonsandtrollsSkill) float32 {
+	healing := b.calculateAttributesValue(*targetEffectAttributes(skill).Life)
+	if healing <= 0 {
+		return 0
+	}
+	if skill.Duration != nil {
+		if duration := b.calculateAttributesValue(*skill.Duration); duration > 0 {
+			healing *= duration
+		}
+	}
+	return healing
+}
+
+// supportRejection rejects buffs and heals over time on allies already under an active effect of the same skill
+func (b *Bot) supportRejection(skill swagger.DungeonsandtrollsSkill, target MapObject) string {
+	if target.IsEmpty() || b.IsHostile(target) || skill.Duration == nil || b.calculateAttributesValue(*skill.Duration) <= 0 {
+		return ""
+	}
+	if skill.DamageAmount != nil && b.calculateAttributesValue(*skill.DamageAmount) > 0 {
+		return ""
+	}
+	signature := b.effectSignature(targetEffectAttributes(skill))
+	if signature == ([13]int8{}) {
+		return ""
+	}
+	for _, effect := range target.GetEffects() {
+		if effect.Duration > 0 && effect.Effects != nil && attributesSignature(*effect.Effects) == signature {
+			b.Logger.Debugw("Target already under the effect",
+				"skillName", skill.Name,
+				"targetName", target.GetName(),
+				"effectDuration", effect.Duration,
+				"effectCasterId", effect.CasterId,
+			)
+			return RejectionActiveEffect
+		}
+	}
+	return ""
+}
+
+func targetEffectAttributes(skill swagger.DungeonsandtrollsSkill) *swagger.DungeonsandtrollsSkillAttributes {
+	if skill.TargetEffects == nil || skill.TargetEffects.Attributes == nil {
+		return fillSkillAttributes(swagger.DungeonsandtrollsSkillAttributes{})
+	}
+	return fillSkillAttributes(*skill.TargetEffects.Attributes)
+}
+
+// Effects don't know which skill created them, effects changing the same attributes in the same direction are considered the same
+func (b *Bot) effectSignature(attrs *swagger.DungeonsandtrollsSkillAttributes) [13]int8 {
+	return attributesSignature(swagger.DungeonsandtrollsAttributes{
+		Strength:       b.calculateAttributesValue(*attrs.Strength),
+		Dexterity:      b.calculateAttributesValue(*attrs.Dexterity),
+		Intelligence:   b.calculateAttributesValue(*attrs.Intelligence),
+		Willpower:      b.calculateAttributesValue(*attrs.Willpower),
+		Constitution:   b.calculateAttributesValue(*attrs.Constitution),
+		SlashResist:    b.calculateAttributesValue(*attrs.SlashResist),
+		PierceResist:   b.calculateAttributesValue(*attrs.PierceResist),
+		FireResist:     b.calculateAttributesValue(*attrs.FireResist),
+		PoisonResist:   b.calculateAttributesValue(*attrs.PoisonResist),
+		ElectricResist: b.calculateAttributesValue(*attrs.ElectricResist),
+		Life:           b.calculateAttributesValue(*attrs.Life),
+		Stamina:        b.calculateAttributesValue(*attrs.Stamina),
+		Mana:           b.calculateAttributesValue(*attrs.Mana),
+	})
+}
+
+func attributesSignature(attrs swagger.DungeonsandtrollsAttributes) [13]int8 {
+	values := []float32{
+		attrs.Strength, attrs.Dexterity, attrs.Intelligence, attrs.Willpower, attrs.Constitution,
+		attrs.SlashResist, attrs.PierceResist, attrs.FireResist, attrs.PoisonResist, attrs.ElectricResist,
+		attrs.Life, attrs.Stamina, attrs.Mana,
+	}
+	signature := [13]int8{}
+	for i, value := range values {
+		if value > 0 {
+			signature[i] = 1
+		} else if value < 0 {
+			signature[i] = -1
+		}
+	}
+	return signature
+}
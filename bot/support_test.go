@@ -0,0 +1,255 @@
+package bot
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// runHealer runs a tick of the arena and returns monster-2 (the healer with Mend healing 15 life)
+func runHealer(t *testing.T, profiles string, change func(state *swagger.DungeonsandtrollsGameState)) *Bot {
+	t.Helper()
+	d := newTestDispatcher()
+	if profiles != "" {
+		parsed, err := ParseConfigProfiles([]byte(profiles))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Profiles = parsed
+	}
+	state := loadArena(t)
+	state.Tick = 1
+	if change != nil {
+		change(state)
+	}
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return d.Bots["monster-2"]
+}
+
+// friendlyObject returns the ally of the bot
+func friendlyObject(t *testing.T, bot *Bot, id string) MapObject {
+	t.Helper()
+	for _, friendly := range bot.BotState.Objects.Friendly {
+		if friendly.GetId() == id {
+			return friendly
+		}
+	}
+	t.Fatalf("ally %s not found", id)
+	return MapObject{}
+}
+
+func skillByName(t *testing.T, bot *Bot, name string) swagger.DungeonsandtrollsSkill {
+	t.Helper()
+	for _, skill := range getAllSkills(bot.Details.Monster.EquippedItems) {
+		if skill.Name == name {
+			return skill
+		}
+	}
+	t.Fatalf("skill %s not found", name)
+	return swagger.DungeonsandtrollsSkill{}
+}
+
+// hurt sets life of monster-1 (max life 60)
+func hurt(state *swagger.DungeonsandtrollsGameState, life float32) {
+	monster := findMonster(state, "monster-1")
+	monster.Attributes.Life = life
+	monster.LifePercentage = life / monster.MaxAttributes.Life
+}
+
+func TestTriage(t *testing.T) {
+	// Monster-1 is a berserker (role importance 0.5) with 60 max life, the player hits for 10
+	tests := []struct {
+		name     string
+		profiles string
+		change   func(state *swagger.DungeonsandtrollsGameState)
+		// Priority of monster-1, negative when no triage is expected
+		priority float32
+	}{
+		{
+			name:     "role only",
+			priority: 0.3 * 0.5,
+		},
+		{
+			name:     "missing life",
+			change:   func(state *swagger.DungeonsandtrollsGameState) { hurt(state, 30) },
+			priority: 0.5 + 0.3*0.5,
+		},
+		{
+			name:     "incoming damage of adjacent hostile",
+			change:   func(state *swagger.DungeonsandtrollsGameState) { moveCharacter(state, "player-1", makePosition(9, 2)) },
+			priority: 0.7*10.0/60 + 0.3*0.5,
+		},
+		{
+			name:     "incoming damage within reach margin",
+			change:   func(state *swagger.DungeonsandtrollsGameState) { moveCharacter(state, "player-1", makePosition(8, 2)) },
+			priority: 0.7*10.0/60 + 0.3*0.5,
+		},
+		{
+			name:     "hostile out of reach",
+			change:   func(state *swagger.DungeonsandtrollsGameState) { moveCharacter(state, "player-1", makePosition(7, 1)) },
+			priority: 0.3 * 0.5,
+		},
+		{
+			name: "hurt and attacked",
+			change: func(state *swagger.DungeonsandtrollsGameState) {
+				hurt(state, 30)
+				moveCharacter(state, "player-1", makePosition(9, 2))
+			},
+			priority: 0.5 + 0.7*10.0/60 + 0.3*0.5,
+		},
+		{
+			name:     "no support",
+			profiles: `{"healer": {"support": 0}}`,
+			priority: -1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bot := runHealer(t, test.profiles, test.change)
+			triage := bot.BotState.Triage
+			if test.priority < 0 {
+				if triage != nil || bot.BotState.SupportRange != 0 {
+					t.Errorf("unexpected triage %v (support range %d)", triage, bot.BotState.SupportRange)
+				}
+				return
+			}
+			// Mend has range 4, the healer doesn't triage itself
+			if bot.BotState.SupportRange != 4 || len(triage) != 1 {
+				t.Fatalf("expected triage of monster-1 with support range 4, got %v (support range %d)", triage, bot.BotState.SupportRange)
+			}
+			if priority := triage["monster-1"].Priority; math.Abs(float64(priority-test.priority)) > 1e-4 {
+				t.Errorf("expected priority %v, got %v (%+v)", test.priority, priority, triage["monster-1"])
+			}
+			if triage.MostNeedy() != "monster-1" {
+				t.Errorf("expected monster-1 most needy, got %q", triage.MostNeedy())
+			}
+		})
+	}
+}
+
+func TestSupportBonusOverheal(t *testing.T) {
+	// Bonus = 0.2 (triage bonus) * priority * 1.5 (support), overheal penalty 0.3, Mend heals 15
+	tests := []struct {
+		name   string
+		change func(state *swagger.DungeonsandtrollsGameState)
+		bonus  float32
+	}{
+		{
+			name:   "heal fully used",
+			change: func(state *swagger.DungeonsandtrollsGameState) { hurt(state, 30) },
+			bonus:  0.2 * 0.65 * 1.5,
+		},
+		{
+			name:   "heal exactly the missing life",
+			change: func(state *swagger.DungeonsandtrollsGameState) { hurt(state, 45) },
+			bonus:  0.2 * 0.4 * 1.5,
+		},
+		{
+			// 9 of 15 wasted
+			name:   "overheal",
+			change: func(state *swagger.DungeonsandtrollsGameState) { hurt(state, 54) },
+			bonus:  0.2*0.25*1.5*0.4 - 0.3*0.6,
+		},
+		{
+			name:  "full life",
+			bonus: -0.3,
+		},
+		{
+			// Anticipated damage of 10, 5 of 15 wasted
+			name:   "full life about to be hit",
+			change: func(state *swagger.DungeonsandtrollsGameState) { moveCharacter(state, "player-1", makePosition(9, 2)) },
+			bonus:  0.2*(0.7*10.0/60+0.15)*1.5*(2.0/3) - 0.3/3,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bot := runHealer(t, "", test.change)
+			target := friendlyObject(t, bot, "monster-1")
+			bonus := bot.supportBonus(skillByName(t, bot, "Mend"), target, SkillResult{VitalsFriendly: 1})
+			if math.Abs(float64(bonus-test.bonus)) > 1e-4 {
+				t.Errorf("expected bonus %v, got %v", test.bonus, bonus)
+			}
+		})
+	}
+	// Skills that don't help allies get no bonus
+	bot := runHealer(t, "", func(state *swagger.DungeonsandtrollsGameState) { hurt(state, 30) })
+	if bonus := bot.supportBonus(skillByName(t, bot, "Mend"), friendlyObject(t, bot, "monster-1"), SkillResult{}); bonus != 0 {
+		t.Errorf("expected no bonus without friendly effect, got %v", bonus)
+	}
+}
+
+func TestSupportRejection(t *testing.T) {
+	constant := func(value float32) *swagger.DungeonsandtrollsAttributes {
+		return &swagger.DungeonsandtrollsAttributes{Constant: value}
+	}
+	// Buffs strength for 3 ticks
+	bless := swagger.DungeonsandtrollsSkill{
+		Name:     "Bless",
+		Duration: constant(3),
+		TargetEffects: &swagger.DungeonsandtrollsSkillEffect{
+			Attributes: &swagger.DungeonsandtrollsSkillAttributes{Strength: constant(2)},
+		},
+	}
+	// Heals 5 life per tick for 3 ticks
+	regenerate := swagger.DungeonsandtrollsSkill{
+		Name:     "Regenerate",
+		Duration: constant(3),
+		TargetEffects: &swagger.DungeonsandtrollsSkillEffect{
+			Attributes: &swagger.DungeonsandtrollsSkillAttributes{Life: constant(5)},
+		},
+	}
+	// Burns strength and life over time
+	curse := swagger.DungeonsandtrollsSkill{
+		Name:         "Curse",
+		Duration:     constant(3),
+		DamageAmount: constant(4),
+		TargetEffects: &swagger.DungeonsandtrollsSkillEffect{
+			Attributes: &swagger.DungeonsandtrollsSkillAttributes{Strength: constant(-2)},
+		},
+	}
+	effect := func(duration int32, attributes swagger.DungeonsandtrollsAttributes) swagger.DungeonsandtrollsEffect {
+		return swagger.DungeonsandtrollsEffect{Duration: duration, Effects: &attributes, CasterId: "monster-3"}
+	}
+	tests := []struct {
+		name     string
+		skill    swagger.DungeonsandtrollsSkill
+		effects  []swagger.DungeonsandtrollsEffect
+		target   string
+		rejected bool
+	}{
+		{"buff", bless, nil, "monster-1", false},
+		{"re-buff", bless, []swagger.DungeonsandtrollsEffect{effect(2, swagger.DungeonsandtrollsAttributes{Strength: 5})}, "monster-1", true},
+		{"buff after the effect expired", bless, []swagger.DungeonsandtrollsEffect{effect(0, swagger.DungeonsandtrollsAttributes{Strength: 5})}, "monster-1", false},
+		{"buff under a debuff", bless, []swagger.DungeonsandtrollsEffect{effect(2, swagger.DungeonsandtrollsAttributes{Strength: -5})}, "monster-1", false},
+		{"buff under another buff", bless, []swagger.DungeonsandtrollsEffect{effect(2, swagger.DungeonsandtrollsAttributes{Strength: 2, Dexterity: 2})}, "monster-1", false},
+		{"heal over time twice", regenerate, []swagger.DungeonsandtrollsEffect{effect(1, swagger.DungeonsandtrollsAttributes{Life: 3})}, "monster-1", true},
+		// Instant heals can be repeated, see supportBonus for overheal
+		{"instant heal", swagger.DungeonsandtrollsSkill{}, []swagger.DungeonsandtrollsEffect{effect(1, swagger.DungeonsandtrollsAttributes{Life: 3})}, "monster-1", false},
+		{"damage over time on a hostile", curse, nil, "player-1", false},
+		{"damaging skill on an ally", curse, []swagger.DungeonsandtrollsEffect{effect(2, swagger.DungeonsandtrollsAttributes{Strength: -2})}, "monster-1", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bot := runHealer(t, "", func(state *swagger.DungeonsandtrollsGameState) {
+				findMonster(state, "monster-1").Effects = test.effects
+			})
+			skill := test.skill
+			if skill.Name == "" {
+				skill = skillByName(t, bot, "Mend")
+			}
+			target := MapObject{}
+			if test.target == "player-1" {
+				target = bot.BotState.Objects.Players[0]
+			} else {
+				target = friendlyObject(t, bot, test.target)
+			}
+			if rejected := bot.supportRejection(skill, target) == RejectionActiveEffect; rejected != test.rejected {
+				t.Errorf("expected rejected %v, got %v", test.rejected, rejected)
+			}
+		})
+	}
+}
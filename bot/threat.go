@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"math"
+
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/pathfinding"
+)
+
+// ThreatTable maps hostile character IDs to accumulated threat (aggro)
+// It is persisted in BotState across ticks and decays every tick (see Tuning.Threat)
+type ThreatTable map[string]float32
+
+// Highest returns the ID with the highest threat ("" for empty table)
+func (tt ThreatTable) Highest() (string, float32) {
+	bestId, bestThreat := "", float32(0)
+	for id, threat := range tt {
+		// Deterministic tie breaking
+		if threat > bestThreat || (threat == bestThreat && bestId != "" && id < bestId) {
+			bestId, bestThreat = id, threat
+		}
+	}
+	return bestId, bestThreat
+}
+
+func (b *Bot) updateThreat() {
+	hostiles := map[string]MapObject{}
+	for _, hostile := range b.BotState.Objects.Hostile {
+		hostiles[hostile.GetId()] = hostile
+	}
+	threat := ThreatTable{}
+	// Decay and forget hostiles that left the level
+	for id, value := range b.BotState.Threat {
+		value *= b.Tuning.Threat.Decay
+		if _, found := hostiles[id]; found && value >= b.Tuning.Threat.MinThreat {
+			threat[id] = value
+		}
+	}
+	b.addDamageThreat(threat, hostiles)
+	b.addProximityThreat(threat)
+	b.addHealingThreat(threat)
+	b.BotState.Threat = threat
+	if len(threat) > 0 {
+		b.Logger.Debugw("Threat updated",
+			"threat", threat,
+		)
+	}
+}
+
+// addDamageThreat attributes life lost since the previous tick to hostiles
+func (b *Bot) addDamageThreat(threat ThreatTable, hostiles map[string]MapObject) {
+	t := b.Tuning.Threat
+	monster := b.Details.Monster
+	previous := b.PrevDetails.Monster
+	if previous == nil || previous.Attributes == nil || monster.Attributes == nil {
+		return
+	}
+	damage := previous.Attributes.Life - monster.Attributes.Life
+	// Life can also be paid as a skill cost
+	if damage <= 0 || monster.LastDamageTaken > b.Tuning.Behavior.CombatTicks {
+		return
+	}
+	// Ground effects know who cast them
+	for _, effect := range b.Details.MapObjects.Effects {
+		if _, found := hostiles[effect.CasterId]; !found {
+			continue
+		}
+		effectDamage := float32(math.Min(float64(pathfinding.EffectDamage(effect)), float64(damage)))
+		if effectDamage <= 0 {
+			continue
+		}
+		threat[effect.CasterId] += t.DamageWeight * effectDamage
+		damage -= effectDamage
+	}
+	if damage <= 0 {
+		return
+	}
+	// The rest is split between hostiles in sight, closer hostiles are more likely to be the attackers
+	weights := map[string]float32{}
+	totalWeight := float32(0)
+	for id, hostile := range hostiles {
+		cell := b.BotState.MapExtended.At(*hostile.GetPosition())
+		if !cell.lineOfSight {
+			continue
+		}
+		weight := 1 / float32(1+manhattanDistance(*b.Details.Position, *hostile.GetPosition()))
+		weights[id] = weight
+		totalWeight += weight
+	}
+	for id, weight := range weights {
+		threat[id] += t.DamageWeight * damage * weight / totalWeight
+	}
+	b.Logger.Debugw("Damage taken",
+		"damage", previous.Attributes.Life-monster.Attributes.Life,
+		"suspectsCount", len(weights),
+	)
+}
+
+// addProximityThreat adds threat for hostiles in sight within Tuning.Threat.ProximityRange
+func (b *Bot) addProximityThreat(threat ThreatTable) {
+	t := b.Tuning.Threat
+	for _, hostile := range b.BotState.Objects.Hostile {
+		cell := b.BotState.MapExtended.At(*hostile.GetPosition())
+		if !cell.lineOfSight {
+			continue
+		}
+		distance := manhattanDistance(*b.Details.Position, *hostile.GetPosition())
+		if distance > t.ProximityRange {
+			continue
+		}
+		threat[hostile.GetId()] += t.ProximityWeight * (1 - float32(distance)/float32(t.ProximityRange+1))
+	}
+}
+
+// addHealingThreat attributes life gained by hostiles to hostile players close to them
+func (b *Bot) addHealingThreat(threat ThreatTable) {
+	t := b.Tuning.Threat
+	if b.PrevDetails.CurrentMap == nil {
+		return
+	}
+	previousLife := map[string]float32{}
+	for _, object := range b.PrevDetails.CurrentMap.Objects {
+		for _, player := range object.Players {
+			if player.Attributes != nil {
+				previousLife[player.Id] = player.Attributes.Life
+			}
+		}
+		for _, monster := range object.Monsters {
+			if monster.Attributes != nil {
+				previousLife[monster.Id] = monster.Attributes.Life
+			}
+		}
+	}
+	for _, healed := range b.BotState.Objects.Hostile {
+		life, found := previousLife[healed.GetId()]
+		if !found || healed.GetAttributes() == nil {
+			continue
+		}
+		healing := healed.GetAttributes().Life - life
+		if healing <= 0 {
+			continue
+		}
+		healers := []MapObject{}
+		for _, player := range b.BotState.Objects.Hostile {
+			if player.Type != MapObjectTypePlayer {
+				continue
+			}
+			if manhattanDistance(*player.GetPosition(), *healed.GetPosition()) <= t.HealingRange {
+				healers = append(healers, player)
+			}
+		}
+		for _, healer := range healers {
+			threat[healer.GetId()] += t.HealingWeight * healing / float32(len(healers))
+		}
+	}
+}
+
+// threatBonus makes monsters retaliate against whoever is hurting them
+func (b *Bot) threatBonus(target MapObject, result SkillResult) float32 {
+	if result.VitalsHostile >= 0 || target.IsEmpty() {
+		return 0
+	}
+	_, highest := b.BotState.Threat.Highest()
+	if highest <= 0 {
+		return 0
+	}
+	return b.Tuning.Threat.TargetBonus * b.BotState.Threat[target.GetId()] / highest
+}
+
+// pickThreatTarget returns the enemy with the highest threat (nil if no enemy has any threat)
+func (b *Bot) pickThreatTarget(enemies []MapObject) *MapObject {
+	var best *MapObject
+	bestThreat := float32(0)
+	for i := range enemies {
+		threat := b.BotState.Threat[enemies[i].GetId()]
+		if threat > bestThreat {
+			best = &enemies[i]
+			bestThreat = threat
+		}
+	}
+	return best
+}
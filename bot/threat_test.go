@@ -0,0 +1,269 @@
+package bot
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// clonePlayer adds a copy of the player with another ID at the position (attributes are copied too)
+func clonePlayer(state *swagger.DungeonsandtrollsGameState, id string, cloneId string, position swagger.DungeonsandtrollsPosition) {
+	for l := range state.Map_.Levels {
+		level := &state.Map_.Levels[l]
+		for o := range level.Objects {
+			for _, player := range level.Objects[o].Players {
+				if player.Id == id {
+					player.Id = cloneId
+					attributes := *player.Attributes
+					player.Attributes = &attributes
+					level.Objects[o].Players = append(level.Objects[o].Players, player)
+					moveCharacter(state, cloneId, position)
+					return
+				}
+			}
+		}
+	}
+}
+
+// findPlayer returns the player in the game state (nil when it's not on any level)
+func findPlayer(state *swagger.DungeonsandtrollsGameState, id string) *swagger.DungeonsandtrollsCharacter {
+	for l := range state.Map_.Levels {
+		for o := range state.Map_.Levels[l].Objects {
+			for p := range state.Map_.Levels[l].Objects[o].Players {
+				if state.Map_.Levels[l].Objects[o].Players[p].Id == id {
+					return &state.Map_.Levels[l].Objects[o].Players[p]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// damageMonster takes life of the monster as if it was hit this tick
+func damageMonster(state *swagger.DungeonsandtrollsGameState, id string, damage float32) {
+	monster := findMonster(state, id)
+	monster.Attributes.Life -= damage
+	monster.LifePercentage = monster.Attributes.Life / monster.MaxAttributes.Life
+	monster.LastDamageTaken = 0
+}
+
+func TestThreatTable(t *testing.T) {
+	// Monster-1 is at (10, 2), the wall at x = 7 blocks line of sight for y = 2..4
+	tests := []struct {
+		name string
+		// Changes of the default threat tuning
+		tuning func(tuning *ThreatTuning)
+		// Changes of the arena in consecutive ticks
+		ticks  []func(state *swagger.DungeonsandtrollsGameState)
+		threat ThreatTable
+	}{
+		{
+			name: "no threat out of proximity range",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				nil,
+			},
+			threat: ThreatTable{},
+		},
+		{
+			name: "proximity scaled by distance",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(8, 1))
+				},
+			},
+			// 1 - 3 / 6
+			threat: ThreatTable{"player-1": 0.5},
+		},
+		{
+			name: "decay",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(8, 1))
+				},
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(6, 1))
+				},
+			},
+			// 0.5 * 0.9 + (1 - 5 / 6)
+			threat: ThreatTable{"player-1": 0.45 + 1.0/6},
+		},
+		{
+			name: "decay while out of sight",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(8, 1))
+				},
+				nil,
+				nil,
+			},
+			threat: ThreatTable{"player-1": 0.5 * 0.9 * 0.9},
+		},
+		{
+			name:   "forget below min threat",
+			tuning: func(tuning *ThreatTuning) { tuning.MinThreat = 0.4 },
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(8, 1))
+				},
+				nil,
+				nil,
+				// 0.5 * 0.9^3 < 0.4
+				nil,
+			},
+			threat: ThreatTable{},
+		},
+		{
+			name: "forget hostiles that left the level",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					clonePlayer(state, "player-1", "player-2", makePosition(8, 1))
+				},
+				nil,
+			},
+			threat: ThreatTable{},
+		},
+		{
+			name:   "damage split by distance",
+			tuning: func(tuning *ThreatTuning) { tuning.ProximityWeight = 0 },
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(9, 2))
+					clonePlayer(state, "player-1", "player-2", makePosition(10, 5))
+				},
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(9, 2))
+					clonePlayer(state, "player-1", "player-2", makePosition(10, 5))
+					damageMonster(state, "monster-1", 9)
+				},
+			},
+			// Weights 1 / 2 and 1 / 4
+			threat: ThreatTable{"player-1": 6, "player-2": 3},
+		},
+		{
+			name:   "damage split only between hostiles in sight",
+			tuning: func(tuning *ThreatTuning) { tuning.ProximityWeight = 0 },
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(9, 2))
+					clonePlayer(state, "player-1", "player-2", makePosition(6, 2))
+				},
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(9, 2))
+					clonePlayer(state, "player-1", "player-2", makePosition(6, 2))
+					damageMonster(state, "monster-1", 9)
+				},
+			},
+			threat: ThreatTable{"player-1": 9},
+		},
+		{
+			name:   "ground effect damage attributed to the caster",
+			tuning: func(tuning *ThreatTuning) { tuning.ProximityWeight = 0 },
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(9, 2))
+					clonePlayer(state, "player-1", "player-2", makePosition(10, 5))
+				},
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(9, 2))
+					clonePlayer(state, "player-1", "player-2", makePosition(10, 5))
+					damageMonster(state, "monster-1", 9)
+					tile := tileOf(&state.Map_.Levels[0], makePosition(10, 2))
+					tile.Effects = append(tile.Effects, swagger.DungeonsandtrollsEffect{
+						CasterId:     "player-2",
+						DamageAmount: 3,
+						Effects:      &swagger.DungeonsandtrollsAttributes{},
+					})
+				},
+			},
+			// 3 from the effect, the remaining 6 split with weights 1 / 2 and 1 / 4
+			threat: ThreatTable{"player-1": 4, "player-2": 5},
+		},
+		{
+			name: "skill costs are not damage",
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(9, 2))
+				},
+				func(state *swagger.DungeonsandtrollsGameState) {
+					moveCharacter(state, "player-1", makePosition(9, 2))
+					damageMonster(state, "monster-1", 9)
+					findMonster(state, "monster-1").LastDamageTaken = 10
+				},
+			},
+			// Only proximity 1 - 1 / 6 in both ticks
+			threat: ThreatTable{"player-1": 5.0 / 6 * 1.9},
+		},
+		{
+			name:   "healing split between players close to the healed hostile",
+			tuning: func(tuning *ThreatTuning) { tuning.ProximityWeight = 0 },
+			ticks: []func(state *swagger.DungeonsandtrollsGameState){
+				func(state *swagger.DungeonsandtrollsGameState) {
+					clonePlayer(state, "player-1", "player-2", makePosition(2, 6))
+					clonePlayer(state, "player-1", "player-3", makePosition(12, 7))
+					findPlayer(state, "player-1").Attributes.Life = 50
+				},
+				func(state *swagger.DungeonsandtrollsGameState) {
+					clonePlayer(state, "player-1", "player-2", makePosition(2, 6))
+					clonePlayer(state, "player-1", "player-3", makePosition(12, 7))
+					findPlayer(state, "player-1").Attributes.Life = 70
+				},
+			},
+			// 0.5 * 20 split between player-1 and player-2, player-3 is out of healing range
+			threat: ThreatTable{"player-1": 5, "player-2": 5},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newTestDispatcher()
+			tuning := DefaultTuning()
+			if test.tuning != nil {
+				test.tuning(&tuning.Threat)
+			}
+			d.Tuning = &tuning
+			for i, change := range test.ticks {
+				state := loadArena(t)
+				state.Tick = int32(i + 1)
+				if change != nil {
+					change(state)
+				}
+				if err := d.HandleTick(state, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			threat := d.Bots["monster-1"].BotState.Threat
+			if len(threat) != len(test.threat) {
+				t.Fatalf("expected threat %v, got %v", test.threat, threat)
+			}
+			for id, expected := range test.threat {
+				if math.Abs(float64(threat[id]-expected)) > 1e-4 {
+					t.Errorf("expected threat %v, got %v", test.threat, threat)
+				}
+			}
+		})
+	}
+}
+
+func TestThreatTableHighest(t *testing.T) {
+	tests := []struct {
+		name   string
+		threat ThreatTable
+		id     string
+	}{
+		{"empty", ThreatTable{}, ""},
+		{"single", ThreatTable{"player-2": 0.5}, "player-2"},
+		{"highest", ThreatTable{"player-1": 0.5, "player-2": 2, "player-3": 1}, "player-2"},
+		{"tie broken by ID", ThreatTable{"player-3": 1, "player-2": 1, "player-1": 0.5}, "player-2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Map iteration order is random
+			for i := 0; i < 20; i++ {
+				if id, _ := test.threat.Highest(); id != test.id {
+					t.Fatalf("expected %q, got %q", test.id, id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// Reasons why a skill + target combination was not scored
+const (
+	RejectionIllegalTarget = "illegal target"
+	RejectionCostNotMet    = "cost not met"
+	RejectionInCombat      = "requires out of combat"
+	RejectionOutOfRange    = "out of range"
+	RejectionNoLineOfSight = "no line of sight"
+)
+
+// DecisionTrace explains why the monster chose its action in one tick
+// It is only collected for monsters with the debug flag (see BotDispatcher.DebugMonsters)
+type DecisionTrace struct {
+	Tick        int32                              `json:"tick"`
+	MonsterId   string                             `json:"monsterId"`
+	MonsterName string                             `json:"monsterName"`
+	Algorithm   string                             `json:"algorithm"`
+	Position    *swagger.DungeonsandtrollsPosition `json:"position"`
+	Config      Config                             `json:"config"`
+	Behavior    Behavior                           `json:"behavior"`
+	Threat      ThreatTable                        `json:"threat,omitempty"`
+	Triage      TriageTable                        `json:"triage,omitempty"`
+
+	Evaluations []SkillEvaluation `json:"evaluations"`
+	// Winner is nil when no skill was chosen (see Fallback)
+	Winner   *SkillEvaluation                        `json:"winner"`
+	Fallback string                                  `json:"fallback,omitempty"`
+	Command  *swagger.DungeonsandtrollsCommandsBatch `json:"command"`
+	// Plan is set when the lookahead planner is enabled (see Tuning.Planner)
+	Plan *Plan `json:"plan,omitempty"`
+}
+
+// SkillEvaluation is one evaluated skill + target combination
+// Result and Score are empty for rejected combinations
+type SkillEvaluation struct {
+	SkillId        string                             `json:"skillId"`
+	SkillName      string                             `json:"skillName"`
+	TargetId       string                             `json:"targetId,omitempty"`
+	TargetName     string                             `json:"targetName"`
+	TargetPosition *swagger.DungeonsandtrollsPosition `json:"targetPosition"`
+	Result         *SkillResult                       `json:"result,omitempty"`
+	Score          float32                            `json:"score"`
+	Rejection      string                             `json:"rejection,omitempty"`
+}
+
+func (b *Bot) newDecisionTrace() *DecisionTrace {
+	return &DecisionTrace{
+		Tick:        b.GameState.Tick,
+		MonsterId:   b.MonsterId,
+		MonsterName: b.Details.Name,
+		Algorithm:   b.Details.Monster.Algorithm,
+		Position:    b.Details.Position,
+		Config:      b.Config,
+		Evaluations: []SkillEvaluation{},
+	}
+}
+
+// All trace methods are no-ops on nil trace (monster without debug flag)
+
+// AddEvaluation records the evaluation and returns its index for MarkWinner
+func (t *DecisionTrace) AddEvaluation(skill swagger.DungeonsandtrollsSkill, target MapObject, result *SkillResult, score float32, rejection string) int {
+	if t == nil {
+		return -1
+	}
+	evaluation := SkillEvaluation{
+		SkillId:        skill.Id,
+		SkillName:      skill.Name,
+		TargetName:     target.GetName(),
+		TargetPosition: target.MapObjects.Position,
+		Score:          score,
+		Rejection:      rejection,
+	}
+	if !target.IsEmpty() {
+		evaluation.TargetId = target.GetId()
+	}
+	if result != nil {
+		resultCopy := *result
+		evaluation.Result = &resultCopy
+	}
+	t.Evaluations = append(t.Evaluations, evaluation)
+	return len(t.Evaluations) - 1
+}
+
+func (t *DecisionTrace) MarkWinner(index int) {
+	if t == nil || index < 0 {
+		return
+	}
+	winner := t.Evaluations[index]
+	t.Winner = &winner
+}
+
+func (t *DecisionTrace) SetFallback(fallback string) {
+	if t == nil {
+		return
+	}
+	t.Fallback = fallback
+}
+
+func (t *DecisionTrace) SetPlan(plan *Plan) {
+	if t == nil {
+		return
+	}
+	t.Plan = plan
+}
+
+func (t *DecisionTrace) SetCommand(cmd *swagger.DungeonsandtrollsCommandsBatch) {
+	if t == nil {
+		return
+	}
+	t.Command = cmd
+}
+
+// Rejected returns number of rejections by reason
+func (t *DecisionTrace) Rejected() map[string]int {
+	rejected := map[string]int{}
+	if t == nil {
+		return rejected
+	}
+	for _, evaluation := range t.Evaluations {
+		if evaluation.Rejection != "" {
+			rejected[evaluation.Rejection]++
+		}
+	}
+	return rejected
+}
+
+// WriteToDir dumps the trace as <dir>/trace-<tick>-<monsterId>.json
+func (t *DecisionTrace) WriteToDir(dir string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding decision trace: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("trace-%d-%s.json", t.Tick, t.MonsterId))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing decision trace: %w", err)
+	}
+	return nil
+}
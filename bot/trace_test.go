@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// Monsters without debug flag have no trace
+func TestNilTraceIsNoop(t *testing.T) {
+	var trace *DecisionTrace
+	skill := getDefaultMoveSkill()
+	if index := trace.AddEvaluation(skill, MapObject{Type: MapObjectTypeEmpty}, nil, 0, RejectionOutOfRange); index != -1 {
+		t.Errorf("expected index -1, got %d", index)
+	}
+	trace.MarkWinner(0)
+	trace.SetFallback("none")
+	trace.SetPlan(&Plan{})
+	trace.SetCommand(nil)
+	if rejected := trace.Rejected(); len(rejected) != 0 {
+		t.Errorf("expected no rejections, got %v", rejected)
+	}
+}
+
+func TestTraceWinnerAndRejections(t *testing.T) {
+	trace := &DecisionTrace{Tick: 3, MonsterId: "monster-1", Evaluations: []SkillEvaluation{}}
+	position := makePosition(1, 2)
+	target := MapObject{Type: MapObjectTypeEmpty, MapObjects: swagger.DungeonsandtrollsMapObjects{Position: &position}}
+	skill := getDefaultMoveSkill()
+	trace.AddEvaluation(skill, target, nil, 0, RejectionOutOfRange)
+	trace.AddEvaluation(skill, target, nil, 0, RejectionOutOfRange)
+	trace.AddEvaluation(skill, target, nil, 0, RejectionCostNotMet)
+	winner := trace.AddEvaluation(skill, target, &SkillResult{MovementSelf: 1}, 2, "")
+	trace.MarkWinner(winner)
+	if trace.Winner == nil || trace.Winner.Score != 2 || trace.Winner.Result.MovementSelf != 1 {
+		t.Errorf("unexpected winner %+v", trace.Winner)
+	}
+	rejected := trace.Rejected()
+	if rejected[RejectionOutOfRange] != 2 || rejected[RejectionCostNotMet] != 1 || len(rejected) != 2 {
+		t.Errorf("unexpected rejections %v", rejected)
+	}
+
+	dir := t.TempDir()
+	if err := trace.WriteToDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "trace-3-monster-1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := DecisionTrace{}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if len(written.Evaluations) != 4 || written.Winner == nil || *written.Winner.TargetPosition != position {
+		t.Errorf("unexpected written trace %+v", written)
+	}
+}
+
+func evaluationsOf(trace *DecisionTrace, skillName string) []SkillEvaluation {
+	evaluations := []SkillEvaluation{}
+	for _, evaluation := range trace.Evaluations {
+		if evaluation.SkillName == skillName {
+			evaluations = append(evaluations, evaluation)
+		}
+	}
+	return evaluations
+}
+
+func TestTraceRecordsFilteredSkills(t *testing.T) {
+	d := newTestDispatcher()
+	d.DebugMonsters = []string{"*"}
+	state := loadArena(t)
+	state.Tick = 1
+	// The healer is out of mana and in combat, the other monster is out of combat
+	healer := findMonster(state, "monster-2")
+	healer.Attributes.Mana = 0
+	healer.LastDamageTaken = 0
+	if err := d.HandleTick(state, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	trace := d.LastTrace("monster-2")
+	if trace == nil {
+		t.Fatal("no trace of a debugged monster")
+	}
+	for _, skillName := range []string{"Fire Bolt", "Mend"} {
+		evaluations := evaluationsOf(trace, skillName)
+		if len(evaluations) != 1 || evaluations[0].Rejection != RejectionCostNotMet {
+			t.Errorf("expected %s rejected once because of cost, got %+v", skillName, evaluations)
+		}
+	}
+	if evaluations := evaluationsOf(trace, "Rest"); len(evaluations) != 1 || evaluations[0].Rejection != RejectionInCombat {
+		t.Errorf("expected Rest rejected in combat, got %+v", evaluations)
+	}
+
+	// Skills without target are evaluated once and the rest of the skills is evaluated too
+	trace = d.LastTrace("monster-1")
+	if evaluations := evaluationsOf(trace, "Rest"); len(evaluations) != 1 {
+		t.Errorf("expected Rest evaluated once, got %+v", evaluations)
+	}
+	if evaluations := evaluationsOf(trace, "Slash"); len(evaluations) == 0 {
+		t.Error("Slash not evaluated")
+	}
+	if evaluations := evaluationsOf(trace, getDefaultMoveSkill().Name); len(evaluations) == 0 {
+		t.Error("default move not evaluated")
+	}
+}
@@ -0,0 +1,761 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/pathfinding"
+	"go.uber.org/zap"
+)
+
+// Tuning holds the scoring constants used by skill and movement evaluation
+// Values are loaded from a JSON file over DefaultTuning() so the file only needs to contain overrides
+type Tuning struct {
+	Vitals  VitalsTuning  `json:"vitals"`
+	Buffs   BuffsTuning   `json:"buffs"`
+	Resists ResistsTuning `json:"resists"`
+
+	Effects  EffectsTuning  `json:"effects"`
+	Movement MovementTuning `json:"movement"`
+	Behavior BehaviorTuning `json:"behavior"`
+	Danger   DangerTuning   `json:"danger"`
+	Idle     IdleTuning     `json:"idle"`
+	Guardian GuardianTuning `json:"guardian"`
+
+	Coordination CoordinationTuning `json:"coordination"`
+	Threat       ThreatTuning       `json:"threat"`
+	Support      SupportTuning      `json:"support"`
+	Planner      PlannerTuning      `json:"planner"`
+
+	Pathfinding pathfinding.TileCosts `json:"pathfinding"`
+
+	// Maximum random increase of combined score and damage (in percent)
+	ScoreRandomizationPercent  float32 `json:"scoreRandomizationPercent"`
+	DamageRandomizationPercent float32 `json:"damageRandomizationPercent"`
+	// Added to any hostile vitals loss so that dealing damage beats doing nothing
+	HostileDamageBonus float32 `json:"hostileDamageBonus"`
+}
+
+// Score = Weight * log(x*Curve + 1) / log(Curve + 1), see scorePercentageOnACurveMinMax()
+type CurveTuning struct {
+	Weight           float32 `json:"weight"`
+	Curve            float32 `json:"curve"`
+	KillingBlowBonus float32 `json:"killingBlowBonus"`
+}
+
+type VitalsTuning struct {
+	Life    CurveTuning `json:"life"`
+	Stamina CurveTuning `json:"stamina"`
+	Mana    CurveTuning `json:"mana"`
+}
+
+type BuffsTuning struct {
+	Curve            float32 `json:"curve"`
+	KillingBlowBonus float32 `json:"killingBlowBonus"`
+	// Attribute value that counts as 100 %
+	MaxValue float32 `json:"maxValue"`
+
+	Strength     float32 `json:"strength"`
+	Dexterity    float32 `json:"dexterity"`
+	Intelligence float32 `json:"intelligence"`
+	Willpower    float32 `json:"willpower"`
+	Constitution float32 `json:"constitution"`
+}
+
+type ResistsTuning struct {
+	Curve            float32 `json:"curve"`
+	KillingBlowBonus float32 `json:"killingBlowBonus"`
+	// Resist value that counts as 100 %
+	MaxValue float32 `json:"maxValue"`
+
+	Slash    float32 `json:"slash"`
+	Pierce   float32 `json:"pierce"`
+	Fire     float32 `json:"fire"`
+	Poison   float32 `json:"poison"`
+	Electric float32 `json:"electric"`
+}
+
+type EffectsTuning struct {
+	StunSelfPenalty     float32 `json:"stunSelfPenalty"`
+	StunHostilePenalty  float32 `json:"stunHostilePenalty"`
+	StunFriendlyPenalty float32 `json:"stunFriendlyPenalty"`
+
+	MovementHostilePenalty float32 `json:"movementHostilePenalty"`
+	MovementFriendlyBonus  float32 `json:"movementFriendlyBonus"`
+
+	KnockbackPenalty float32 `json:"knockbackPenalty"`
+	SummonsBonus     float32 `json:"summonsBonus"`
+}
+
+type MovementTuning struct {
+	// Characters closer than this are counted as close hostiles / friendlies
+	CloseDistance int32 `json:"closeDistance"`
+	// Caps for counted values
+	MaxCloseCount    int   `json:"maxCloseCount"`
+	MaxSpawnDistance int32 `json:"maxSpawnDistance"`
+
+	// Movement score of a skill is divided by this
+	SkillMovementDivisor float32 `json:"skillMovementDivisor"`
+	// Paths are searched only up to this cost (see Tuning.Pathfinding)
+	PathSearchMaxCost float32 `json:"pathSearchMaxCost"`
+
+	ClosestHostileHalfDistance    float32 `json:"closestHostileHalfDistance"`
+	ClosestHostileAdjacentPenalty float32 `json:"closestHostileAdjacentPenalty"`
+	ClosestHostileSameTilePenalty float32 `json:"closestHostileSameTilePenalty"`
+
+	ClosestFriendlyHalfDistance    float32 `json:"closestFriendlyHalfDistance"`
+	ClosestFriendlyAdjacentPenalty float32 `json:"closestFriendlyAdjacentPenalty"`
+	ClosestFriendlySameTilePenalty float32 `json:"closestFriendlySameTilePenalty"`
+
+	TargetPositionHalfDistance float32 `json:"targetPositionHalfDistance"`
+	DistanceToSelfDivisor      float32 `json:"distanceToSelfDivisor"`
+
+	StairsOrSpawnPenalty  float32 `json:"stairsOrSpawnPenalty"`
+	OtherMonsterPenalty   float32 `json:"otherMonsterPenalty"`
+	VitalsCoefOffset      float32 `json:"vitalsCoefOffset"`
+	VitalsCoefDivisor     float32 `json:"vitalsCoefDivisor"`
+	DistanceToSpawnWeight float32 `json:"distanceToSpawnWeight"`
+	ClosestHostileWeight  float32 `json:"closestHostileWeight"`
+	ClosestFriendlyWeight float32 `json:"closestFriendlyWeight"`
+	TargetPositionWeight  float32 `json:"targetPositionWeight"`
+	NumHostilesWeight     float32 `json:"numHostilesWeight"`
+	NumFriendlyWeight     float32 `json:"numFriendlyWeight"`
+}
+
+// BehaviorTuning holds behavior transition thresholds and score weights (see behavior.go)
+type BehaviorTuning struct {
+	// Hostiles closer than EngageDistance start a fight which ends when they are further than DisengageDistance
+	EngageDistance    int32 `json:"engageDistance"`
+	DisengageDistance int32 `json:"disengageDistance"`
+	// Monster is in combat for this many ticks after taking damage
+	CombatTicks int32 `json:"combatTicks"`
+	// Fleeing monster fights again when vitals recover above Config.FleeVitals + RecoverVitalsMargin
+	RecoverVitalsMargin float32 `json:"recoverVitalsMargin"`
+	// Regroup with friendlies further than RegroupDistance when vitals drop below RegroupVitals
+	RegroupVitals   float32 `json:"regroupVitals"`
+	RegroupDistance int32   `json:"regroupDistance"`
+	// Return home (see rememberHome) after a fight that ended further than ReturnToSpawnDistance
+	ReturnToSpawnDistance int32 `json:"returnToSpawnDistance"`
+	SpawnReachedDistance  int32 `json:"spawnReachedDistance"`
+	GuardRadius           int32 `json:"guardRadius"`
+	// Monster fleeing because of a sharp life drop (see Config.FleeLifeDrop) keeps fleeing for at least FleeMinTicks
+	FleeMinTicks int `json:"fleeMinTicks"`
+	// Monsters whose most damaging skill has at least this range kite instead of engaging (0 disables kiting)
+	KiteMinRange int32 `json:"kiteMinRange"`
+	// Movement score penalty per tile off the kite range, divided by the range
+	KiteDistanceWeight float32 `json:"kiteDistanceWeight"`
+	// Monsters away from home evade back after EvadeIdleTicks without hostiles in sight (0 only evades when the leash breaks)
+	EvadeIdleTicks int `json:"evadeIdleTicks"`
+	// Evading monster rests at home until its vitals recover to EvadeRestVitals, at most EvadeMaxRestTicks
+	EvadeRestVitals   float32 `json:"evadeRestVitals"`
+	EvadeMaxRestTicks int     `json:"evadeMaxRestTicks"`
+
+	Weights BehaviorWeightsTuning `json:"weights"`
+}
+
+// DangerTuning configures danger maps and the choice of safe tiles (see danger.go)
+type DangerTuning struct {
+	// Tiles up to SearchRadius from the monster are rated
+	SearchRadius int32 `json:"searchRadius"`
+	// Hostiles walk up to ReachMargin tiles before attacking, such tiles count ApproachDanger
+	ReachMargin    int32   `json:"reachMargin"`
+	ApproachDanger float32 `json:"approachDanger"`
+	// Range of hostiles without known damage skills
+	DefaultHostileRange int32 `json:"defaultHostileRange"`
+	// Movement score penalty per danger (multiplied by BehaviorWeights.Danger)
+	MovementWeight float32 `json:"movementWeight"`
+
+	// Safe tile score
+	DangerWeight         float32 `json:"dangerWeight"`
+	PathCostWeight       float32 `json:"pathCostWeight"`
+	HiddenBonus          float32 `json:"hiddenBonus"`
+	FriendlyWeight       float32 `json:"friendlyWeight"`
+	FriendlyHalfDistance float32 `json:"friendlyHalfDistance"`
+}
+
+// IdleTuning configures wandering, patrols and sentries of monsters with no hostiles in sight (see idle.go)
+type IdleTuning struct {
+	// Idle monsters start walking to a random tile within WanderRadius of home with probability WanderChance * Config.Restlessness
+	WanderRadius  int32   `json:"wanderRadius"`
+	WanderChance  float32 `json:"wanderChance"`
+	WanderTimeout int     `json:"wanderTimeout"`
+	// Patrol routes visit up to PatrolMaxWaypoints waypoints within PatrolRadius (walking distance) of home
+	PatrolRadius          int32 `json:"patrolRadius"`
+	PatrolMaxWaypoints    int   `json:"patrolMaxWaypoints"`
+	PatrolReachedDistance int32 `json:"patrolReachedDistance"`
+	// Waypoints are centers of rooms with at least MinRoomTiles and doorways, at least WaypointSpacing apart
+	MinRoomTiles    int   `json:"minRoomTiles"`
+	WaypointSpacing int32 `json:"waypointSpacing"`
+}
+
+// GuardianTuning configures monsters protecting stairs, portals and chests (see guardian.go)
+type GuardianTuning struct {
+	// Objects further than BindRadius (walking distance) from home are not guarded
+	BindRadius int32 `json:"bindRadius"`
+	// Players within ThreatRadius (walking distance) of the guarded object are intruders
+	ThreatRadius int32 `json:"threatRadius"`
+	// Tiles at most PathSlack steps off the shortest path of an intruder block it
+	PathSlack int32 `json:"pathSlack"`
+	// Movement score for blocking all intruders (multiplied by BehaviorWeights.Block)
+	BlockWeight float32 `json:"blockWeight"`
+	// Score bonus for damaging players on the guarded object (next to guarded chests)
+	OnObjectBonus float32 `json:"onObjectBonus"`
+	// Score bonus for damaging players approaching the guarded object, scaled down linearly up to ThreatRadius
+	ApproachBonus float32 `json:"approachBonus"`
+}
+
+// CoordinationTuning holds score bonuses for following level assignments (see coordinator.go)
+type CoordinationTuning struct {
+	// Damaging the focus fire target (hostile with the lowest effective HP weighted by path distance)
+	FocusFireBonus float32 `json:"focusFireBonus"`
+	// Effective HP added to focus fire candidates per tile of path distance from the closest monster of the faction
+	FocusDistanceWeight float32 `json:"focusDistanceWeight"`
+	// Helping a recently damaged ally, multiplied by its missing life and Config.Support
+	AllyUnderAttackBonus float32 `json:"allyUnderAttackBonus"`
+	// Moving to a tile another monster already moves to
+	ReservedTilePenalty float32 `json:"reservedTilePenalty"`
+}
+
+// ThreatTuning holds threat table constants (see threat.go)
+type ThreatTuning struct {
+	// Threat is multiplied by Decay every tick and forgotten below MinThreat
+	Decay     float32 `json:"decay"`
+	MinThreat float32 `json:"minThreat"`
+	// Per point of life lost
+	DamageWeight float32 `json:"damageWeight"`
+	// Per tick for hostiles in sight, scaled down linearly up to ProximityRange
+	ProximityWeight float32 `json:"proximityWeight"`
+	ProximityRange  int32   `json:"proximityRange"`
+	// Per point of life healed, split between hostile players within HealingRange of the healed hostile
+	HealingWeight float32 `json:"healingWeight"`
+	HealingRange  int32   `json:"healingRange"`
+	// Score bonus for damaging the hostile with the highest threat (scaled by relative threat)
+	TargetBonus float32 `json:"targetBonus"`
+}
+
+// SupportTuning configures triage of allies for healing and buffs (see support.go)
+type SupportTuning struct {
+	// Priority = MissingLifeWeight * missing life + IncomingDamageWeight * anticipated damage + RoleWeight * role importance
+	MissingLifeWeight    float32 `json:"missingLifeWeight"`
+	IncomingDamageWeight float32 `json:"incomingDamageWeight"`
+	RoleWeight           float32 `json:"roleWeight"`
+	// Role importance by ally algorithm (DefaultRoleImportance for other algorithms and players)
+	RoleImportance        map[string]float32 `json:"roleImportance"`
+	DefaultRoleImportance float32            `json:"defaultRoleImportance"`
+	// Score bonus per priority for healing or buffing the ally (multiplied by Config.Support)
+	TriageBonus float32 `json:"triageBonus"`
+	// Score penalty for healing more than the ally misses (scaled by the wasted ratio of the heal)
+	OverhealPenalty float32 `json:"overhealPenalty"`
+	// Movement score per priority for staying in support range of the most needy ally (multiplied by Config.Support)
+	PositionWeight float32 `json:"positionWeight"`
+}
+
+// PlannerTuning configures the lookahead planner (see planner.go)
+type PlannerTuning struct {
+	// Number of skills in a planned sequence (1 disables the planner)
+	Depth     int `json:"depth"`
+	BeamWidth int `json:"beamWidth"`
+	// Score of each following step is multiplied by Discount
+	Discount float32 `json:"discount"`
+	// Planning time per monster and tick (the tick deadline is never exceeded)
+	BudgetMs float32 `json:"budgetMs"`
+}
+
+type BehaviorWeightsTuning struct {
+	Idle          BehaviorWeights `json:"idle"`
+	Patrol        BehaviorWeights `json:"patrol"`
+	Engage        BehaviorWeights `json:"engage"`
+	Flee          BehaviorWeights `json:"flee"`
+	Kite          BehaviorWeights `json:"kite"`
+	Regroup       BehaviorWeights `json:"regroup"`
+	Guard         BehaviorWeights `json:"guard"`
+	ReturnToSpawn BehaviorWeights `json:"returnToSpawn"`
+	Evade         BehaviorWeights `json:"evade"`
+}
+
+// BehaviorWeights multiply config coefficients and movement score components
+// Negative ClosestHostile makes the monster keep away from hostiles
+type BehaviorWeights struct {
+	Aggression   float32 `json:"aggression"`
+	Preservation float32 `json:"preservation"`
+	Support      float32 `json:"support"`
+	Movement     float32 `json:"movement"`
+
+	Restlessness    float32 `json:"restlessness"`
+	ClosestHostile  float32 `json:"closestHostile"`
+	ClosestFriendly float32 `json:"closestFriendly"`
+	Spawn           float32 `json:"spawn"`
+	// Keeping away from tiles hostiles can hit (only fleeing and kiting monsters have a danger map)
+	Danger float32 `json:"danger"`
+	// Standing in the way of players heading to the guarded object (only guardians, see Config.GuardObject)
+	Block float32 `json:"block"`
+}
+
+func neutralBehaviorWeights() BehaviorWeights {
+	return BehaviorWeights{
+		Aggression:      1,
+		Preservation:    1,
+		Support:         1,
+		Movement:        1,
+		Restlessness:    1,
+		ClosestHostile:  1,
+		ClosestFriendly: 1,
+		Spawn:           1,
+		Danger:          0,
+		Block:           0,
+	}
+}
+
+func defaultBehaviorWeights() BehaviorWeightsTuning {
+	idle := neutralBehaviorWeights()
+	idle.Restlessness = 0.5
+
+	patrol := neutralBehaviorWeights()
+	patrol.Restlessness = 2
+	patrol.Spawn = 0.5
+
+	engage := neutralBehaviorWeights()
+	engage.Aggression = 1.2
+	engage.Spawn = 0.5
+	engage.Block = 1
+
+	flee := neutralBehaviorWeights()
+	flee.Aggression = 0.3
+	flee.Preservation = 2
+	flee.Movement = 2
+	flee.ClosestHostile = -1
+	flee.ClosestFriendly = 1.5
+	flee.Danger = 1
+
+	// Distance to the closest hostile is kept by scoreKiteDistance
+	kite := neutralBehaviorWeights()
+	kite.Aggression = 1.2
+	kite.ClosestHostile = 0
+	kite.Spawn = 0.5
+	kite.Danger = 0.5
+	kite.Block = 0.5
+
+	regroup := neutralBehaviorWeights()
+	regroup.Aggression = 0.7
+	regroup.Support = 1.5
+	regroup.ClosestFriendly = 2
+
+	guard := neutralBehaviorWeights()
+	guard.Restlessness = 0.2
+	guard.Spawn = 0
+	guard.Block = 1
+
+	returnToSpawn := neutralBehaviorWeights()
+	returnToSpawn.Aggression = 0.5
+	returnToSpawn.Movement = 1.5
+	returnToSpawn.Spawn = 2
+
+	// Attacks are rejected while evading, rest and heal instead
+	evade := neutralBehaviorWeights()
+	evade.Aggression = 0
+	evade.Preservation = 2
+	evade.Restlessness = 0
+	evade.Spawn = 2
+
+	return BehaviorWeightsTuning{
+		Idle:          idle,
+		Patrol:        patrol,
+		Engage:        engage,
+		Flee:          flee,
+		Kite:          kite,
+		Regroup:       regroup,
+		Guard:         guard,
+		ReturnToSpawn: returnToSpawn,
+		Evade:         evade,
+	}
+}
+
+func DefaultTuning() Tuning {
+	return Tuning{
+		Vitals: VitalsTuning{
+			Life:    CurveTuning{Weight: 7.5, Curve: 75, KillingBlowBonus: 0.5},
+			Stamina: CurveTuning{Weight: 1.75, Curve: 25, KillingBlowBonus: 0.2},
+			Mana:    CurveTuning{Weight: 1.2, Curve: 10, KillingBlowBonus: 0.2},
+		},
+		Buffs: BuffsTuning{
+			Curve:            25,
+			KillingBlowBonus: 0.2,
+			MaxValue:         50,
+			Strength:         4,
+			Dexterity:        2,
+			Intelligence:     2,
+			Willpower:        1,
+			Constitution:     1,
+		},
+		Resists: ResistsTuning{
+			Curve:            25,
+			KillingBlowBonus: 0.2,
+			MaxValue:         40,
+			Slash:            2.5,
+			Pierce:           4,
+			Fire:             1.5,
+			Poison:           1,
+			Electric:         1,
+		},
+		Effects: EffectsTuning{
+			StunSelfPenalty:        0.4,
+			StunHostilePenalty:     0.3,
+			StunFriendlyPenalty:    0.1,
+			MovementHostilePenalty: 0.3,
+			MovementFriendlyBonus:  0.15,
+			KnockbackPenalty:       0.2,
+			SummonsBonus:           0.7,
+		},
+		Movement: MovementTuning{
+			CloseDistance:    12,
+			MaxCloseCount:    10,
+			MaxSpawnDistance: 10,
+
+			SkillMovementDivisor: 3,
+			PathSearchMaxCost:    40,
+
+			ClosestHostileHalfDistance:    10,
+			ClosestHostileAdjacentPenalty: 0.08,
+			ClosestHostileSameTilePenalty: 0.06,
+
+			ClosestFriendlyHalfDistance:    10,
+			ClosestFriendlyAdjacentPenalty: 0.21,
+			ClosestFriendlySameTilePenalty: 0.31,
+
+			TargetPositionHalfDistance: 20,
+			DistanceToSelfDivisor:      40,
+
+			StairsOrSpawnPenalty:  0.7,
+			OtherMonsterPenalty:   0.12,
+			VitalsCoefOffset:      4,
+			VitalsCoefDivisor:     5,
+			DistanceToSpawnWeight: 4.5,
+			ClosestHostileWeight:  6,
+			ClosestFriendlyWeight: 2,
+			TargetPositionWeight:  3,
+			NumHostilesWeight:     3,
+			NumFriendlyWeight:     4,
+		},
+		Behavior: BehaviorTuning{
+			EngageDistance:        8,
+			DisengageDistance:     12,
+			CombatTicks:           2,
+			RecoverVitalsMargin:   0.25,
+			RegroupVitals:         0.5,
+			RegroupDistance:       4,
+			ReturnToSpawnDistance: 10,
+			SpawnReachedDistance:  2,
+			GuardRadius:           3,
+			FleeMinTicks:          2,
+			KiteMinRange:          3,
+			KiteDistanceWeight:    2,
+			EvadeIdleTicks:        10,
+			EvadeRestVitals:       0.9,
+			EvadeMaxRestTicks:     10,
+			Weights:               defaultBehaviorWeights(),
+		},
+		Danger: DangerTuning{
+			SearchRadius:         6,
+			ReachMargin:          1,
+			ApproachDanger:       0.5,
+			DefaultHostileRange:  1,
+			MovementWeight:       1.5,
+			DangerWeight:         2,
+			PathCostWeight:       0.1,
+			HiddenBonus:          0.5,
+			FriendlyWeight:       1,
+			FriendlyHalfDistance: 4,
+		},
+		Idle: IdleTuning{
+			WanderRadius:          4,
+			WanderChance:          0.25,
+			WanderTimeout:         6,
+			PatrolRadius:          15,
+			PatrolMaxWaypoints:    4,
+			PatrolReachedDistance: 1,
+			MinRoomTiles:          4,
+			WaypointSpacing:       4,
+		},
+		Guardian: GuardianTuning{
+			BindRadius:    10,
+			ThreatRadius:  6,
+			PathSlack:     1,
+			BlockWeight:   1.5,
+			OnObjectBonus: 0.6,
+			ApproachBonus: 0.4,
+		},
+		Coordination: CoordinationTuning{
+			FocusFireBonus:       0.3,
+			FocusDistanceWeight:  5,
+			AllyUnderAttackBonus: 0.2,
+			ReservedTilePenalty:  0.5,
+		},
+		Threat: ThreatTuning{
+			Decay:           0.9,
+			MinThreat:       0.1,
+			DamageWeight:    1,
+			ProximityWeight: 1,
+			ProximityRange:  5,
+			HealingWeight:   0.5,
+			HealingRange:    5,
+			TargetBonus:     0.3,
+		},
+		Support: SupportTuning{
+			MissingLifeWeight:    1,
+			IncomingDamageWeight: 0.7,
+			RoleWeight:           0.3,
+			RoleImportance: map[string]float32{
+				"healer":    1,
+				"sniper":    0.7,
+				"guard":     0.6,
+				"guardian":  0.6,
+				"berserker": 0.5,
+				"coward":    0.2,
+			},
+			DefaultRoleImportance: 0.4,
+			TriageBonus:           0.2,
+			OverhealPenalty:       0.3,
+			PositionWeight:        0.3,
+		},
+		Planner: PlannerTuning{
+			Depth:     2,
+			BeamWidth: 3,
+			Discount:  0.7,
+			BudgetMs:  5,
+		},
+		Pathfinding: pathfinding.TileCosts{
+			Base:                1,
+			HarmfulEffect:       5,
+			HarmfulEffectDamage: 0.5,
+			FriendlyMonster:     2,
+			Door:                1,
+			StairsOrSpawn:       1,
+		},
+		ScoreRandomizationPercent:  20,
+		DamageRandomizationPercent: 20,
+		HostileDamageBonus:         0.1,
+	}
+}
+
+func (t Tuning) Validate() error {
+	curves := map[string]float32{
+		"vitals.life.curve":    t.Vitals.Life.Curve,
+		"vitals.stamina.curve": t.Vitals.Stamina.Curve,
+		"vitals.mana.curve":    t.Vitals.Mana.Curve,
+		"buffs.curve":          t.Buffs.Curve,
+		"resists.curve":        t.Resists.Curve,
+	}
+	for name, curve := range curves {
+		if curve <= 0 {
+			return fmt.Errorf("%s must be positive (got %v)", name, curve)
+		}
+	}
+	divisors := map[string]float32{
+		"buffs.maxValue":                       t.Buffs.MaxValue,
+		"resists.maxValue":                     t.Resists.MaxValue,
+		"movement.skillMovementDivisor":        t.Movement.SkillMovementDivisor,
+		"movement.pathSearchMaxCost":           t.Movement.PathSearchMaxCost,
+		"movement.distanceToSelfDivisor":       t.Movement.DistanceToSelfDivisor,
+		"movement.vitalsCoefDivisor":           t.Movement.VitalsCoefDivisor,
+		"movement.closestHostileHalfDistance":  t.Movement.ClosestHostileHalfDistance,
+		"movement.closestFriendlyHalfDistance": t.Movement.ClosestFriendlyHalfDistance,
+		"movement.targetPositionHalfDistance":  t.Movement.TargetPositionHalfDistance,
+		"danger.friendlyHalfDistance":          t.Danger.FriendlyHalfDistance,
+	}
+	for name, divisor := range divisors {
+		if divisor <= 0 {
+			return fmt.Errorf("%s must be positive (got %v)", name, divisor)
+		}
+	}
+	if t.Movement.CloseDistance < 0 {
+		return fmt.Errorf("movement.closeDistance must not be negative (got %v)", t.Movement.CloseDistance)
+	}
+	if t.Movement.MaxCloseCount <= 0 {
+		return fmt.Errorf("movement.maxCloseCount must be positive (got %v)", t.Movement.MaxCloseCount)
+	}
+	if t.Movement.MaxSpawnDistance <= 0 {
+		return fmt.Errorf("movement.maxSpawnDistance must be positive (got %v)", t.Movement.MaxSpawnDistance)
+	}
+	if t.Behavior.DisengageDistance < t.Behavior.EngageDistance {
+		return fmt.Errorf("behavior.disengageDistance must not be lower than behavior.engageDistance")
+	}
+	if t.Behavior.FleeMinTicks < 0 || t.Behavior.KiteMinRange < 0 || t.Behavior.KiteDistanceWeight < 0 {
+		return fmt.Errorf("behavior.fleeMinTicks, behavior.kiteMinRange and behavior.kiteDistanceWeight must not be negative")
+	}
+	if t.Behavior.EvadeIdleTicks < 0 || t.Behavior.EvadeMaxRestTicks < 0 {
+		return fmt.Errorf("behavior.evadeIdleTicks and behavior.evadeMaxRestTicks must not be negative")
+	}
+	if t.Behavior.EvadeRestVitals < 0 || t.Behavior.EvadeRestVitals > 1 {
+		return fmt.Errorf("behavior.evadeRestVitals must be between 0 and 1 (got %v)", t.Behavior.EvadeRestVitals)
+	}
+	if t.Danger.SearchRadius < 0 || t.Danger.ReachMargin < 0 || t.Danger.DefaultHostileRange < 0 {
+		return fmt.Errorf("danger.searchRadius, danger.reachMargin and danger.defaultHostileRange must not be negative")
+	}
+	if t.Danger.ApproachDanger < 0 || t.Danger.MovementWeight < 0 || t.Danger.DangerWeight < 0 || t.Danger.PathCostWeight < 0 || t.Danger.HiddenBonus < 0 || t.Danger.FriendlyWeight < 0 {
+		return fmt.Errorf("danger weights must not be negative")
+	}
+	if t.Idle.WanderRadius < 1 {
+		return fmt.Errorf("idle.wanderRadius must be positive (got %v)", t.Idle.WanderRadius)
+	}
+	if t.Idle.WanderChance < 0 || t.Idle.WanderTimeout < 0 || t.Idle.PatrolRadius < 0 || t.Idle.PatrolMaxWaypoints < 0 || t.Idle.PatrolReachedDistance < 0 || t.Idle.MinRoomTiles < 0 || t.Idle.WaypointSpacing < 0 {
+		return fmt.Errorf("idle tuning must not be negative")
+	}
+	if t.Guardian.BindRadius < 0 || t.Guardian.ThreatRadius < 0 || t.Guardian.PathSlack < 0 {
+		return fmt.Errorf("guardian.bindRadius, guardian.threatRadius and guardian.pathSlack must not be negative")
+	}
+	if t.Guardian.BlockWeight < 0 || t.Guardian.OnObjectBonus < 0 || t.Guardian.ApproachBonus < 0 {
+		return fmt.Errorf("guardian weights must not be negative")
+	}
+	if t.Coordination.FocusFireBonus < 0 || t.Coordination.FocusDistanceWeight < 0 || t.Coordination.AllyUnderAttackBonus < 0 || t.Coordination.ReservedTilePenalty < 0 {
+		return fmt.Errorf("coordination bonuses must not be negative")
+	}
+	if t.Threat.Decay < 0 || t.Threat.Decay > 1 {
+		return fmt.Errorf("threat.decay must be between 0 and 1 (got %v)", t.Threat.Decay)
+	}
+	if t.Threat.MinThreat <= 0 {
+		return fmt.Errorf("threat.minThreat must be positive (got %v)", t.Threat.MinThreat)
+	}
+	if t.Threat.DamageWeight < 0 || t.Threat.ProximityWeight < 0 || t.Threat.HealingWeight < 0 || t.Threat.TargetBonus < 0 {
+		return fmt.Errorf("threat weights must not be negative")
+	}
+	if t.Threat.ProximityRange < 0 || t.Threat.HealingRange < 0 {
+		return fmt.Errorf("threat ranges must not be negative")
+	}
+	if t.Support.MissingLifeWeight < 0 || t.Support.IncomingDamageWeight < 0 || t.Support.RoleWeight < 0 || t.Support.TriageBonus < 0 || t.Support.OverhealPenalty < 0 || t.Support.PositionWeight < 0 {
+		return fmt.Errorf("support weights must not be negative")
+	}
+	for algorithm, importance := range t.Support.RoleImportance {
+		if importance < 0 {
+			return fmt.Errorf("support.roleImportance of %q must not be negative (got %v)", algorithm, importance)
+		}
+	}
+	if t.Support.DefaultRoleImportance < 0 {
+		return fmt.Errorf("support.defaultRoleImportance must not be negative (got %v)", t.Support.DefaultRoleImportance)
+	}
+	if t.Planner.Depth < 1 || t.Planner.Depth > 3 {
+		return fmt.Errorf("planner.depth must be between 1 and 3 (got %v)", t.Planner.Depth)
+	}
+	if t.Planner.BeamWidth < 1 {
+		return fmt.Errorf("planner.beamWidth must be positive (got %v)", t.Planner.BeamWidth)
+	}
+	if t.Planner.Discount < 0 || t.Planner.Discount > 1 {
+		return fmt.Errorf("planner.discount must be between 0 and 1 (got %v)", t.Planner.Discount)
+	}
+	if t.Planner.BudgetMs < 0 {
+		return fmt.Errorf("planner.budgetMs must not be negative (got %v)", t.Planner.BudgetMs)
+	}
+	if t.Pathfinding.Base <= 0 {
+		return fmt.Errorf("pathfinding.base must be positive (got %v)", t.Pathfinding.Base)
+	}
+	pathCosts := t.Pathfinding
+	if pathCosts.HarmfulEffect < 0 || pathCosts.HarmfulEffectDamage < 0 || pathCosts.FriendlyMonster < 0 || pathCosts.Door < 0 || pathCosts.StairsOrSpawn < 0 {
+		return fmt.Errorf("pathfinding costs must not be negative")
+	}
+	if t.ScoreRandomizationPercent < 0 || t.DamageRandomizationPercent < 0 {
+		return fmt.Errorf("randomization percentages must not be negative")
+	}
+	return nil
+}
+
+func ParseTuning(data []byte) (Tuning, error) {
+	tuning := DefaultTuning()
+	if err := json.Unmarshal(data, &tuning); err != nil {
+		return Tuning{}, fmt.Errorf("parsing tuning: %w", err)
+	}
+	if err := tuning.Validate(); err != nil {
+		return Tuning{}, fmt.Errorf("invalid tuning: %w", err)
+	}
+	return tuning, nil
+}
+
+// TuningFile keeps the last valid tuning loaded from a file
+// Reloads happen between ticks when the file changes or a reload is requested (e.g. on SIGHUP)
+type TuningFile struct {
+	Path string
+
+	lock            sync.Mutex
+	tuning          *Tuning
+	modTime         time.Time
+	reloadRequested atomic.Bool
+}
+
+func LoadTuningFile(path string) (*TuningFile, error) {
+	tf := &TuningFile{Path: path}
+	if err := tf.load(); err != nil {
+		return nil, err
+	}
+	return tf, nil
+}
+
+func (tf *TuningFile) Current() *Tuning {
+	tf.lock.Lock()
+	defer tf.lock.Unlock()
+	return tf.tuning
+}
+
+func (tf *TuningFile) RequestReload() {
+	tf.reloadRequested.Store(true)
+}
+
+// ReloadIfChanged keeps the previous tuning if the new file is invalid
+func (tf *TuningFile) ReloadIfChanged(logger *zap.SugaredLogger) {
+	forced := tf.reloadRequested.Swap(false)
+	if !forced {
+		info, err := os.Stat(tf.Path)
+		if err != nil {
+			logger.Errorw("Can't stat tuning file",
+				"path", tf.Path,
+				zap.Error(err),
+			)
+			return
+		}
+		tf.lock.Lock()
+		changed := !info.ModTime().Equal(tf.modTime)
+		tf.lock.Unlock()
+		if !changed {
+			return
+		}
+	}
+	if err := tf.load(); err != nil {
+		// Don't retry until the file changes again
+		if info, statErr := os.Stat(tf.Path); statErr == nil {
+			tf.lock.Lock()
+			tf.modTime = info.ModTime()
+			tf.lock.Unlock()
+		}
+		logger.Errorw("Can't reload tuning file, keeping previous tuning",
+			"path", tf.Path,
+			zap.Error(err),
+		)
+		return
+	}
+	logger.Infow("Tuning file reloaded",
+		"path", tf.Path,
+		"forced", forced,
+		"tuning", tf.Current(),
+	)
+}
+
+func (tf *TuningFile) load() error {
+	info, err := os.Stat(tf.Path)
+	if err != nil {
+		return fmt.Errorf("reading tuning file: %w", err)
+	}
+	data, err := os.ReadFile(tf.Path)
+	if err != nil {
+		return fmt.Errorf("reading tuning file: %w", err)
+	}
+	tuning, err := ParseTuning(data)
+	if err != nil {
+		return err
+	}
+	tf.lock.Lock()
+	defer tf.lock.Unlock()
+	tf.tuning = &tuning
+	tf.modTime = info.ModTime()
+	return nil
+}
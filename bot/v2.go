@@ -1,14 +1,75 @@
 package bot
 
 import (
-	"math/rand"
+	"sort"
 
 	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
 )
 
 func (b *Bot) bestSkill() *swagger.DungeonsandtrollsCommandsBatch {
+	if move := b.evadeMove(); move != nil {
+		b.Trace.SetFallback("evade home")
+		return move
+	}
+	candidates := b.evaluateCandidates()
+	var best *skillCandidate
+	bestScore := float32(0)
+	for i := range candidates {
+		if candidates[i].score > bestScore {
+			best = &candidates[i]
+			bestScore = candidates[i].score
+		}
+	}
+	// Plans without positive score would override the fallbacks below
+	if plan := b.planSkillSequence(candidates); plan != nil && plan.Score > 0 {
+		b.Trace.SetPlan(plan)
+		planned := &candidates[plan.first]
+		if planned != best {
+			b.Logger.Infow("Planner chose a different first action",
+				"skillName", planned.skill.Name,
+				"targetName", planned.target.GetName(),
+				"plan", plan,
+			)
+		}
+		best = planned
+	}
+	// Idle monsters walk only when there is nothing better to do than moving
+	if best == nil || best.skill.CasterEffects.Flags.Movement {
+		if move := b.idleMove(); move != nil {
+			b.Trace.SetFallback("idle move (" + string(b.BotState.Behavior) + ")")
+			return move
+		}
+	}
+
+	if best == nil {
+		b.Logger.Warnw("No skill chosen")
+		move := b.moveTowardsEnemy(b.BotState.Objects.Hostile)
+		if move != nil {
+			b.Trace.SetFallback("move towards enemy")
+			return move
+		}
+		b.Trace.SetFallback("none")
+		return nil
+	}
+	b.Trace.MarkWinner(best.evaluation)
+	b.Logger.Infow("Best skill + target combination!!!",
+		"skillName", best.skill.Name,
+		"skill", best.skill,
+		"result", best.result,
+		"resultCombinedScore", best.score,
+		"targetId", best.target.GetId(),
+		"targetName", best.target.GetName(),
+		"targetFaction", best.target.GetFaction(),
+		"position", best.target.MapObjects.Position,
+		"myPosition", b.Details.Position,
+	)
+	return b.useSkill(best.skill, best.target)
+}
+
+// evaluateCandidates scores all castable skill + target combinations (rejected ones are only traced)
+func (b *Bot) evaluateCandidates() []skillCandidate {
 	allSkills := b.filterActiveSkills(getAllSkills(b.Details.Monster.EquippedItems))
-	b.Logger.Infow("All skills",
+	b.Logger.Debugw("All skills",
 		"skills", allSkills,
 		"numSkills", len(allSkills),
 	)
@@ -23,12 +84,11 @@ func (b *Bot) bestSkill() *swagger.DungeonsandtrollsCommandsBatch {
 		skill := oocSkills[s]
 		skillNames = append(skillNames, skill.Name)
 	}
-	b.Logger.Infow("Castable skills",
+	b.Logger.Debugw("Castable skills",
 		"skillNames", skillNames,
 		"skills", oocSkills,
 		"numSkills", len(oocSkills),
 	)
-	// TODO: big drop -> move to safety
 	skillsByRange := map[int][]swagger.DungeonsandtrollsSkill{}
 
 	maxRange := 0
@@ -42,15 +102,15 @@ func (b *Bot) bestSkill() *swagger.DungeonsandtrollsCommandsBatch {
 	}
 
 	targetsByRange := b.findTargetsInRangeAsMap(*b.Details.Position, int32(maxRange))
-	b.Logger.Infow("Max range",
+	b.Logger.Debugw("Max range",
 		"maxRange", maxRange,
 	)
 	emptyTargets := b.getEmptyPositionsAsTargets(int32(maxRange))
 	for t := range emptyTargets {
 		target := emptyTargets[t]
-		dist := b.BotState.MapExtended[*target.MapObjects.Position].distance
+		dist := b.BotState.MapExtended.At(*target.MapObjects.Position).distance
 		targetsByRange[dist] = append(targetsByRange[dist], target)
-		b.Logger.Infow("Adding empty target",
+		b.Logger.Debugw("Adding empty target",
 			"position", target.MapObjects.Position,
 			"myPosition", b.Details.Position,
 			"distance", dist,
@@ -65,154 +125,133 @@ func (b *Bot) bestSkill() *swagger.DungeonsandtrollsCommandsBatch {
 	for _, target := range allTargets {
 		targetNames = append(targetNames, target.GetName())
 	}
-	b.Logger.Infow("All targets in range",
+	b.Logger.Debugw("All targets in range",
 		"rangeBucketsCount", len(targetsByRange),
 		"allTargetsCount", len(allTargets),
 		"targetNames", targetNames,
 		"maxRange", maxRange,
 	)
 
-	bestResult := SkillResult{}
-	var bestSkill *swagger.DungeonsandtrollsSkill
-	var bestTarget *MapObject
+	candidates := []skillCandidate{}
+	addCandidate := func(skill swagger.DungeonsandtrollsSkill, target MapObject, result SkillResult) {
+		score := b.getCombinedVitalsScore(result)
+		candidates = append(candidates, skillCandidate{
+			skill:      skill,
+			target:     target,
+			result:     result,
+			score:      score,
+			evaluation: b.Trace.AddEvaluation(skill, target, &result, score, ""),
+			index:      len(candidates),
+		})
+	}
 
-	for skillRange, skills := range skillsByRange {
-		for targetRange, targets := range targetsByRange {
+	// Iterate in sorted order so that decisions are reproducible with a seeded RNG (see replay)
+	// Skills without target are evaluated once, on the monster itself
+	for _, skillRange := range sortedKeys(skillsByRange) {
+		for _, skill := range skillsByRange[skillRange] {
+			if *skill.Target != swagger.NONE_SkillTarget {
+				continue
+			}
+			if b.planningExpired() {
+				return candidates
+			}
+			target := b.BotState.Self
+			if !b.isLegalSkillTargetCombination(skill, target) {
+				b.Trace.AddEvaluation(skill, target, nil, 0, RejectionIllegalTarget)
+				continue
+			}
+			if rejection := b.supportRejection(skill, target); rejection != "" {
+				b.Trace.AddEvaluation(skill, target, nil, 0, rejection)
+				continue
+			}
+			result, rejection := b.evaluateSkill(skill, target)
+			if rejection != "" {
+				b.Trace.AddEvaluation(skill, target, nil, 0, rejection)
+				continue
+			}
+			addCandidate(skill, target, result)
+		}
+	}
+	for _, skillRange := range sortedKeys(skillsByRange) {
+		skills := skillsByRange[skillRange]
+		for _, targetRange := range sortedKeys(targetsByRange) {
+			targets := targetsByRange[targetRange]
 			if targetRange > skillRange {
 				continue
 			}
 			for s := range skills {
+				if b.planningExpired() {
+					return candidates
+				}
 				skill := skills[s]
 				if *skill.Target == swagger.NONE_SkillTarget {
-					target := b.BotState.Self
-					if !b.isLegalSkillTargetCombination(skill, target) {
-						continue
-					}
-					result := b.evaluateSkill(skill, target)
-					b.Logger.Infow("Skill (target none) evaluated",
-						"skillName", skill.Name,
-						"result", result,
-						"result.VitalsSelf", result.VitalsSelf,
-						"result.Random", result.Random,
-						"resultsCombinedScore", b.getCombinedVitalsScore(result),
-					)
-					if b.isBetterThanSkillResult(result, bestResult) {
-						b.Logger.Infow("New best skill (target none).",
-							"skillName", skill.Name,
-							"result", result,
-							"result.VitalsSelf", result.VitalsSelf,
-							"result.Random", result.Random,
-							"resultsCombinedScore", b.getCombinedVitalsScore(result),
-						)
-						bestResult = result
-						bestSkill = &skill
-						bestTarget = &b.BotState.Self
-					}
-					break
+					continue
 				}
 				for t := range targets {
 					target := targets[t]
+					if target.GetId() == b.MonsterId && *target.GetPosition() != *b.Details.Position {
+						// Planner moved the monster (see virtualBotAfter)
+						continue
+					}
 					if !b.isLegalSkillTargetCombination(skill, target) {
+						b.Trace.AddEvaluation(skill, target, nil, 0, RejectionIllegalTarget)
 						continue
 					}
-					result := b.evaluateSkill(skill, target)
-					if result.VitalsHostile < 0 {
-						result.VitalsHostile -= 0.1
+					if rejection := b.behaviorRejection(skill, target); rejection != "" {
+						b.Trace.AddEvaluation(skill, target, nil, 0, rejection)
+						continue
 					}
-					b.Logger.Infow("Skill + target evaluated",
-						"skillName", skill.Name,
-						"targetName", target.GetName(),
-						"targetPosition", target.MapObjects.Position,
-						"myPosition", b.Details.Position,
-						"result", result,
-						"result.VitalsSelf", result.VitalsSelf,
-						"result.VitalsFriendly", result.VitalsFriendly,
-						"result.VitalsHostile", result.VitalsHostile,
-						"result.MovementSelf", result.MovementSelf,
-						"result.Random", result.Random,
-						"resultsCombinedScore", b.getCombinedVitalsScore(result),
-					)
-					if b.isBetterThanSkillResult(result, bestResult) {
-						prevSkillName := "<no skill>"
-						if bestSkill != nil {
-							prevSkillName = bestSkill.Name
-						}
-						prevTargetName := "<no target>"
-						if bestTarget != nil {
-							prevTargetName = bestTarget.GetName()
-						}
-						b.Logger.Infow("New best skill + target combination.",
-							"targetPosition", target.MapObjects.Position,
-							"myPosition", b.Details.Position,
-
-							"skillName", skill.Name,
-							"targetName", target.GetName(),
-							"result", result,
-							"result.VitalsSelf", result.VitalsSelf,
-							"result.VitalsFriendly", result.VitalsFriendly,
-							"result.VitalsHostile", result.VitalsHostile,
-							"result.MovementSelf", result.MovementSelf,
-							"result.Random", result.Random,
-							"resultCombinedScore", b.getCombinedVitalsScore(result),
-
-							"previousSkillName", prevSkillName,
-							"previousTargetName", prevTargetName,
-							"previousResult", bestResult,
-							"previousResult.VitalsSelf", bestResult.VitalsSelf,
-							"previousResult.VitalsFriendly", bestResult.VitalsFriendly,
-							"previousResult.VitalsHostile", bestResult.VitalsHostile,
-							"previousResult.MovementSelf", bestResult.MovementSelf,
-							"previousResult.Random", bestResult.Random,
-							"previousResultCombinedScore", b.getCombinedVitalsScore(bestResult),
-						)
-						bestResult = result
-						bestSkill = &skill
-						bestTarget = &target
+					if rejection := b.supportRejection(skill, target); rejection != "" {
+						b.Trace.AddEvaluation(skill, target, nil, 0, rejection)
+						continue
 					}
+					result, rejection := b.evaluateSkill(skill, target)
+					if rejection != "" {
+						b.Trace.AddEvaluation(skill, target, nil, 0, rejection)
+						continue
+					}
+					if result.VitalsHostile < 0 {
+						result.VitalsHostile -= b.Tuning.HostileDamageBonus
+					}
+					result.Coordination = b.coordinationBonus(skill, target, result)
+					result.Threat = b.threatBonus(target, result)
+					result.Guardian = b.guardianBonus(target, result)
+					result.Support = b.supportBonus(skill, target, result)
+					addCandidate(skill, target, result)
 				}
 			}
 		}
 	}
+	return candidates
+}
 
-	if bestSkill == nil {
-		b.Logger.Warnw("No skill chosen")
-		move := b.moveTowardsEnemy(b.BotState.Objects.Hostile)
-		if move != nil {
-			return move
-		}
-		return nil //b.randomWalk()
+func sortedKeys[T any](m map[int]T) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
-	b.Logger.Infow("Best skill + target combination!!!",
-		"skillName", bestSkill.Name,
-		"skill", bestSkill,
-		"result", bestResult,
-		"resultCombinedScore", b.getCombinedVitalsScore(bestResult),
-		"targetId", bestTarget.GetId(),
-		"targetName", bestTarget.GetName(),
-		"targetFaction", bestTarget.GetFaction(),
-		"position", bestTarget.MapObjects.Position,
-		"myPosition", b.Details.Position,
-	)
-	return b.useSkill(*bestSkill, *bestTarget)
+	sort.Ints(keys)
+	return keys
 }
 
-// Adds up to 20% score
-func randomizeScore(score float32) float32 {
-	return randomizeScoreN(score, 20)
+// Adds up to ScoreRandomizationPercent (20% by default) score
+func (b *Bot) randomizeScore(score float32) float32 {
+	return b.randomizeScoreN(score, b.Tuning.ScoreRandomizationPercent)
 }
 
-func randomizeScoreN(score, maxPercentIncrease float32) float32 {
-	return score * (1 + rand.Float32()/100*maxPercentIncrease)
+func (b *Bot) randomizeScoreN(score, maxPercentIncrease float32) float32 {
+	return score * (1 + b.Rand.Float32()/100*maxPercentIncrease)
 }
 
 func (b *Bot) getCombinedVitalsScore(s SkillResult) float32 {
 	buffCoef := float32(1)
 	// XXX: Coefficients here can be tweaked for aggression vs. survival preference
-	baseScore := b.Config.Preservation*(s.VitalsSelf+buffCoef*s.BuffsSelf+buffCoef*s.ResistsSelf) +
-		b.Config.Support*(s.VitalsFriendly+buffCoef*s.BuffsFriendly+buffCoef*s.ResistsFriendly) +
-		-b.Config.Aggression*(s.VitalsHostile+buffCoef*s.BuffsHostile+buffCoef*s.ResistsHostile)
+	w := b.behaviorWeights()
+	baseScore := w.Preservation*b.Config.Preservation*(s.VitalsSelf+buffCoef*s.BuffsSelf+buffCoef*s.ResistsSelf) +
+		w.Support*b.Config.Support*(s.VitalsFriendly+buffCoef*s.BuffsFriendly+buffCoef*s.ResistsFriendly) +
+		-w.Aggression*b.Config.Aggression*(s.VitalsHostile+buffCoef*s.BuffsHostile+buffCoef*s.ResistsHostile)
 
-	return randomizeScore(baseScore) + s.MovementSelf + b.Config.Randomness*s.Random
+	return b.randomizeScore(baseScore) + w.Movement*s.MovementSelf + b.Config.Randomness*s.Random + s.Coordination + s.Threat + s.Guardian + s.Support
 }
 
 func (b *Bot) isBetterThanSkillResult(sk1, sk2 SkillResult) bool {
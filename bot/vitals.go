@@ -29,20 +29,23 @@ func (b *Bot) getResistForDamageType(target *MapObject, damageType swagger.Dunge
 }
 
 func (b *Bot) calculateDamage(target *MapObject, skill *swagger.DungeonsandtrollsSkill) float32 {
-	power := b.calculateAttributesValue(*skill.DamageAmount)
-	resist := b.getResistForDamageType(target, *skill.DamageType)
-	damage := float32(float64(power*10) / (float64(10) + math.Max(float64(resist), -5)))
-	damageFinal := randomizeScoreN(damage, 20)
-	b.Logger.Infow("Damage calculated",
+	damage := b.calculateExpectedDamage(target, skill)
+	damageFinal := b.randomizeScoreN(damage, b.Tuning.DamageRandomizationPercent)
+	b.Logger.Debugw("Damage calculated",
 		"targetName", target.GetName(),
-		"power", power,
-		"resist", resist,
 		"damage", damage,
 		"damageRandomized", damageFinal,
 	)
 	return damageFinal
 }
 
+// calculateExpectedDamage is the damage without randomization
+func (b *Bot) calculateExpectedDamage(target *MapObject, skill *swagger.DungeonsandtrollsSkill) float32 {
+	power := b.calculateAttributesValue(*skill.DamageAmount)
+	resist := b.getResistForDamageType(target, *skill.DamageType)
+	return float32(float64(power*10) / (float64(10) + math.Max(float64(resist), -5)))
+}
+
 func (b *Bot) scoreVitalsWithDamage(target *MapObject, skillAttributes *swagger.DungeonsandtrollsSkillAttributes, skill *swagger.DungeonsandtrollsSkill) (float32, float32, float32) {
 	damage := b.calculateDamage(target, skill)
 	damageAttrs := &swagger.DungeonsandtrollsAttributes{
@@ -77,7 +80,7 @@ func (b *Bot) scoreVitalsFor(target *MapObject, skillAttributes *swagger.Dungeon
 	targetMaxAttrs := target.GetMaxAttributes()
 	skillAttributes = fillSkillAttributes(*skillAttributes)
 
-	b.Logger.Infow("Debug scoreVitalsFor",
+	b.Logger.Debugw("Debug scoreVitalsFor",
 		"extraSign", extraSign,
 		"skillAttributes", skillAttributes,
 		"extraAttributes", extraAttributes,
@@ -107,7 +110,7 @@ func (b *Bot) scoreVitalsFor(target *MapObject, skillAttributes *swagger.Dungeon
 	scoreAfter := b.scoreVitalsFunc(lifePercentageAfter, staminaPercentageAfter, manaPercentageAfter)
 	scoreDiff := scoreAfter - score
 
-	b.Logger.Infow("Skill vitals score",
+	b.Logger.Debugw("Skill vitals score",
 		"skillName", skill.Name,
 		"skill", skill,
 		"skillAttributes", skillAttributes,
@@ -176,23 +179,26 @@ func (b *Bot) scorePercentageOnACurveMinMax(percentage, curve, killingBlowBonus,
 }
 
 func (b *Bot) scoreVitalsFunc(lifePercentage, staminaPercentage, manaPercentage float32) float32 {
-	return 7.5*b.scorePercentageOnACurve(lifePercentage, 75, 0.5) +
-		1.75*b.scorePercentageOnACurve(staminaPercentage, 25, 0.2) +
-		1.2*b.scorePercentageOnACurve(manaPercentage, 10, 0.2)
+	t := b.Tuning.Vitals
+	return t.Life.Weight*b.scorePercentageOnACurve(lifePercentage, t.Life.Curve, t.Life.KillingBlowBonus) +
+		t.Stamina.Weight*b.scorePercentageOnACurve(staminaPercentage, t.Stamina.Curve, t.Stamina.KillingBlowBonus) +
+		t.Mana.Weight*b.scorePercentageOnACurve(manaPercentage, t.Mana.Curve, t.Mana.KillingBlowBonus)
 }
 
 func (b *Bot) scoreBuffsFunc(strPercentage, dexPercentage, intPercentage, willPercentage, consPercentage float32) float32 {
+	t := b.Tuning.Buffs
 	f := func(percentage float32) float32 {
-		return b.scorePercentageOnACurveMinMax(percentage, 25, 0.2, 0, 4)
+		return b.scorePercentageOnACurveMinMax(percentage, t.Curve, t.KillingBlowBonus, 0, 4)
 	}
-	return 4*f(strPercentage) + 2*f(dexPercentage) + 2*f(intPercentage) + 1*f(willPercentage) + 1*f(consPercentage)
+	return t.Strength*f(strPercentage) + t.Dexterity*f(dexPercentage) + t.Intelligence*f(intPercentage) + t.Willpower*f(willPercentage) + t.Constitution*f(consPercentage)
 }
 
 func (b *Bot) scoreResistFunc(slashPercentage, piercePercentage, firePercentage, poisonPercentage, electricPercentage float32) float32 {
+	t := b.Tuning.Resists
 	f := func(percentage float32) float32 {
-		return b.scorePercentageOnACurveMinMax(percentage, 25, 0.2, 0, 4)
+		return b.scorePercentageOnACurveMinMax(percentage, t.Curve, t.KillingBlowBonus, 0, 4)
 	}
-	return 2.5*f(slashPercentage) + 4*f(piercePercentage) + 1.5*f(firePercentage) + f(poisonPercentage) + f(electricPercentage)
+	return t.Slash*f(slashPercentage) + t.Pierce*f(piercePercentage) + t.Fire*f(firePercentage) + t.Poison*f(poisonPercentage) + t.Electric*f(electricPercentage)
 }
 
 func (b *Bot) calculateAttributePercentages(value, maxValue, gain float32) (float32, float32) {
@@ -226,17 +232,17 @@ func (b *Bot) scoreBuffs(target *MapObject, skillAttributes *swagger.Dungeonsand
 	poisonResistGain := b.calculateAttributesValue(*skillAttributes.PoisonResist)
 	electricResistGain := b.calculateAttributesValue(*skillAttributes.ElectricResist)
 
-	strengthPercentage, strengthPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().Strength, 50, strengthGain)
-	dexterityPercentage, dexterityPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().Dexterity, 50, dexterityGain)
-	intelligencePercentage, intelligencePercentageAfter := b.calculateAttributePercentages(target.GetAttributes().Intelligence, 50, intelligenceGain)
-	willpowerPercentage, willpowerPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().Willpower, 50, willpowerGain)
-	constitutionPercentage, constitutionPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().Constitution, 50, constitutionGain)
+	strengthPercentage, strengthPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().Strength, b.Tuning.Buffs.MaxValue, strengthGain)
+	dexterityPercentage, dexterityPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().Dexterity, b.Tuning.Buffs.MaxValue, dexterityGain)
+	intelligencePercentage, intelligencePercentageAfter := b.calculateAttributePercentages(target.GetAttributes().Intelligence, b.Tuning.Buffs.MaxValue, intelligenceGain)
+	willpowerPercentage, willpowerPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().Willpower, b.Tuning.Buffs.MaxValue, willpowerGain)
+	constitutionPercentage, constitutionPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().Constitution, b.Tuning.Buffs.MaxValue, constitutionGain)
 
-	slashResistPercentage, slashResistPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().SlashResist, 40, slashResistGain)
-	pierceResistPercentage, pierceResistPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().PierceResist, 40, pierceResistGain)
-	fireResistPercentage, fireResistPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().FireResist, 40, fireResistGain)
-	poisonResistPercentage, poisonResistPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().PoisonResist, 40, poisonResistGain)
-	electricResistPercentage, electricResistPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().ElectricResist, 40, electricResistGain)
+	slashResistPercentage, slashResistPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().SlashResist, b.Tuning.Resists.MaxValue, slashResistGain)
+	pierceResistPercentage, pierceResistPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().PierceResist, b.Tuning.Resists.MaxValue, pierceResistGain)
+	fireResistPercentage, fireResistPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().FireResist, b.Tuning.Resists.MaxValue, fireResistGain)
+	poisonResistPercentage, poisonResistPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().PoisonResist, b.Tuning.Resists.MaxValue, poisonResistGain)
+	electricResistPercentage, electricResistPercentageAfter := b.calculateAttributePercentages(target.GetAttributes().ElectricResist, b.Tuning.Resists.MaxValue, electricResistGain)
 
 	scoreBuffs := b.scoreBuffsFunc(strengthPercentage, dexterityPercentage, intelligencePercentage, willpowerPercentage, constitutionPercentage)
 	scoreBuffsAfter := b.scoreBuffsFunc(strengthPercentageAfter, dexterityPercentageAfter, intelligencePercentageAfter, willpowerPercentageAfter, constitutionPercentageAfter)
@@ -245,7 +251,7 @@ func (b *Bot) scoreBuffs(target *MapObject, skillAttributes *swagger.Dungeonsand
 
 	scoreBuffsDiff := scoreBuffsAfter - scoreBuffs
 	scoreResistsDiff := scoreResistsAfter - scoreResists
-	b.Logger.Infow("Skill buffs score calculated",
+	b.Logger.Debugw("Skill buffs score calculated",
 		"skillName", skill.Name,
 		"strengthGain", strengthGain,
 		"dexterityGain", dexterityGain,
@@ -0,0 +1,145 @@
+package bot
+
+import (
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+type waypointsKey struct {
+	minRoomTiles int
+	spacing      int32
+}
+
+// Waypoints returns patrol points of the layout: centers of rooms and doorways (corridor ends opening into a room)
+// Rooms are areas of tiles with all 8 neighbours free, smaller rooms than minRoomTiles are skipped
+// Waypoints are at least spacing tiles apart and computed once per layout
+func (l *LevelLayout) Waypoints(minRoomTiles int, spacing int32) []swagger.DungeonsandtrollsPosition {
+	key := waypointsKey{minRoomTiles: minRoomTiles, spacing: spacing}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.waypoints != nil && l.waypointsKey == key {
+		return l.waypoints
+	}
+	candidates := append(l.roomCenters(minRoomTiles), l.doorways()...)
+	waypoints := []swagger.DungeonsandtrollsPosition{}
+	for _, candidate := range candidates {
+		tooClose := false
+		for _, waypoint := range waypoints {
+			if manhattanDistance(candidate, waypoint) < spacing {
+				tooClose = true
+				break
+			}
+		}
+		if !tooClose {
+			waypoints = append(waypoints, candidate)
+		}
+	}
+	l.waypoints = waypoints
+	l.waypointsKey = key
+	return waypoints
+}
+
+func (l *LevelLayout) isOpen(x, y int32) bool {
+	for dx := int32(-1); dx <= 1; dx++ {
+		for dy := int32(-1); dy <= 1; dy++ {
+			if l.isBlocked(x+dx, y+dy) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Free tile between two walls (horizontal returns the direction of the corridor)
+func (l *LevelLayout) isCorridor(x, y int32) (corridor bool, horizontal bool) {
+	if l.isBlocked(x, y) {
+		return false, false
+	}
+	left, right := !l.isBlocked(x-1, y), !l.isBlocked(x+1, y)
+	up, down := !l.isBlocked(x, y-1), !l.isBlocked(x, y+1)
+	if left && right && !up && !down {
+		return true, true
+	}
+	if up && down && !left && !right {
+		return true, false
+	}
+	return false, false
+}
+
+// roomCenters returns the tile closest to the centroid of each room (ordered by the first tile of the room)
+func (l *LevelLayout) roomCenters(minRoomTiles int) []swagger.DungeonsandtrollsPosition {
+	visited := make([]bool, len(l.blocked))
+	centers := []swagger.DungeonsandtrollsPosition{}
+	for y := int32(0); y < l.Height; y++ {
+		for x := int32(0); x < l.Width; x++ {
+			start := makePosition(x, y)
+			if visited[l.index(start)] || !l.isOpen(x, y) {
+				continue
+			}
+			// Flood fill over open tiles
+			room := []swagger.DungeonsandtrollsPosition{start}
+			visited[l.index(start)] = true
+			sumX, sumY := int64(0), int64(0)
+			for i := 0; i < len(room); i++ {
+				tile := room[i]
+				sumX += int64(tile.PositionX)
+				sumY += int64(tile.PositionY)
+				for _, neighbor := range getNeighbors(tile) {
+					if !l.InBounds(neighbor) || visited[l.index(neighbor)] || !l.isOpen(neighbor.PositionX, neighbor.PositionY) {
+						continue
+					}
+					visited[l.index(neighbor)] = true
+					room = append(room, neighbor)
+				}
+			}
+			if len(room) < minRoomTiles {
+				continue
+			}
+			centroid := makePosition(int32(sumX/int64(len(room))), int32(sumY/int64(len(room))))
+			center := room[0]
+			for _, tile := range room {
+				if manhattanDistance(tile, centroid) < manhattanDistance(center, centroid) {
+					center = tile
+				}
+			}
+			centers = append(centers, center)
+		}
+	}
+	return centers
+}
+
+// doorways returns corridor tiles whose neighbour along the corridor is a wider area
+func (l *LevelLayout) doorways() []swagger.DungeonsandtrollsPosition {
+	doorways := []swagger.DungeonsandtrollsPosition{}
+	for y := int32(0); y < l.Height; y++ {
+		for x := int32(0); x < l.Width; x++ {
+			corridor, horizontal := l.isCorridor(x, y)
+			if !corridor {
+				continue
+			}
+			ends := [][2]int32{{x, y - 1}, {x, y + 1}}
+			if horizontal {
+				ends = [][2]int32{{x - 1, y}, {x + 1, y}}
+			}
+			for _, end := range ends {
+				if l.isBlocked(end[0], end[1]) {
+					continue
+				}
+				if endCorridor, _ := l.isCorridor(end[0], end[1]); !endCorridor && l.freeNeighbors(end[0], end[1]) >= 3 {
+					doorways = append(doorways, makePosition(x, y))
+					break
+				}
+			}
+		}
+	}
+	return doorways
+}
+
+func (l *LevelLayout) freeNeighbors(x, y int32) int {
+	count := 0
+	for _, neighbor := range getNeighbors(makePosition(x, y)) {
+		if !l.isBlocked(neighbor.PositionX, neighbor.PositionY) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"testing"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+func TestWaypoints(t *testing.T) {
+	// Two rooms joined by a corridor, rooms have a single open tile each
+	corridor := NewLevelCache(newTestLevel(
+		"#############",
+		"#...#####...#",
+		"#...........#",
+		"#...#####...#",
+		"#############",
+	), nil).Layout
+	// Big room with a wall sticking out from the top
+	arena := NewLevelCache(&loadArena(t).Map_.Levels[0], nil).Layout
+
+	tests := []struct {
+		name         string
+		layout       *LevelLayout
+		minRoomTiles int
+		spacing      int32
+		waypoints    []swagger.DungeonsandtrollsPosition
+	}{
+		{
+			name:         "rooms before doorways",
+			layout:       corridor,
+			minRoomTiles: 1,
+			spacing:      1,
+			waypoints:    []swagger.DungeonsandtrollsPosition{makePosition(2, 2), makePosition(10, 2), makePosition(4, 2), makePosition(8, 2)},
+		},
+		{
+			name:         "doorways too close to rooms",
+			layout:       corridor,
+			minRoomTiles: 1,
+			spacing:      3,
+			waypoints:    []swagger.DungeonsandtrollsPosition{makePosition(2, 2), makePosition(10, 2)},
+		},
+		{
+			name:         "small rooms skipped",
+			layout:       corridor,
+			minRoomTiles: 2,
+			spacing:      1,
+			waypoints:    []swagger.DungeonsandtrollsPosition{makePosition(4, 2), makePosition(8, 2)},
+		},
+		{
+			name:         "all too close",
+			layout:       corridor,
+			minRoomTiles: 2,
+			spacing:      5,
+			waypoints:    []swagger.DungeonsandtrollsPosition{makePosition(4, 2)},
+		},
+		{
+			// Tiles between the top wall and the wall sticking out form a doorway
+			name:         "arena",
+			layout:       arena,
+			minRoomTiles: 4,
+			spacing:      4,
+			waypoints:    []swagger.DungeonsandtrollsPosition{makePosition(5, 4), makePosition(7, 1)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			waypoints := test.layout.Waypoints(test.minRoomTiles, test.spacing)
+			if !equalPositions(waypoints, test.waypoints) {
+				t.Errorf("expected waypoints %v, got %v", test.waypoints, waypoints)
+			}
+		})
+	}
+}
+
+func TestWaypointsCachedPerKey(t *testing.T) {
+	layout := NewLevelCache(&loadArena(t).Map_.Levels[0], nil).Layout
+	first := layout.Waypoints(4, 4)
+	if cached := layout.Waypoints(4, 4); &cached[0] != &first[0] {
+		t.Error("waypoints computed again for the same tuning")
+	}
+	// Tuning changes (see TuningFile) compute new waypoints
+	if waypoints := layout.Waypoints(100, 4); !equalPositions(waypoints, []swagger.DungeonsandtrollsPosition{makePosition(7, 1)}) {
+		t.Errorf("expected only the doorway without big rooms, got %v", waypoints)
+	}
+}
+
+func equalPositions(a, b []swagger.DungeonsandtrollsPosition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
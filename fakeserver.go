@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/fakeserver"
+	"go.uber.org/zap"
+)
+
+// runFakeServer serves fixture game states locally (use with DNT_BASE_URL=http://ADDR)
+// USAGE: ./dungeons-and-trolls-monsters-ai fake-server ADDR TICK_MS FIXTURE_JSON...
+func runFakeServer(logger *zap.Logger, args []string) {
+	if len(args) < 3 {
+		logger.Fatal("USAGE: ./dungeons-and-trolls-monsters-ai fake-server ADDR TICK_MS FIXTURE_JSON...")
+	}
+	tickMs, err := strconv.Atoi(args[1])
+	if err != nil {
+		logger.Fatal("Invalid tick duration", zap.Error(err))
+	}
+	fixtures, err := fakeserver.LoadFixtures(args[2:]...)
+	if err != nil {
+		logger.Fatal("Can't load fixtures", zap.Error(err))
+	}
+	server := fakeserver.New(fixtures, time.Duration(tickMs)*time.Millisecond)
+	defer server.Close()
+	logger.Info("Fake server listening",
+		zap.String("addr", args[0]),
+		zap.Int("tickMs", tickMs),
+		zap.Int("fixtures", len(fixtures)),
+	)
+	if err := server.ListenAndServe(args[0]); err != nil {
+		logger.Fatal("Fake server failed", zap.Error(err))
+	}
+}
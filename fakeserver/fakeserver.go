@@ -0,0 +1,322 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// Server is a fake D&T server implementing the endpoints used by the monster AI:
+//
+//	GET  /v1/game                    (blocking waits for the next tick)
+//	POST /v1/monsters-commands       (blocking waits for the next tick)
+//	POST /v1/respawn
+//	POST /logs                       (log shipping stub, see logship)
+//
+// Fixture game states are served one per tick (the last one repeats).
+// Ticks advance every TickDuration, or only through AdvanceTick() when TickDuration is 0.
+type Server struct {
+	APIKey       string
+	TickDuration time.Duration
+
+	lock         sync.Mutex
+	tickChanged  *sync.Cond
+	fixtures     []swagger.DungeonsandtrollsGameState
+	tick         int
+	commands     []SubmittedCommands
+	respawns     int
+	logEntries   []json.RawMessage
+	failures     []int
+	requestCount map[string]int
+	stop         chan struct{}
+	stopOnce     sync.Once
+}
+
+// SubmittedCommands are monster commands received by the server
+type SubmittedCommands struct {
+	Tick     int32
+	Blocking bool
+	Time     time.Time
+	Commands swagger.DungeonsandtrollsCommandsForMonsters
+}
+
+func New(fixtures []swagger.DungeonsandtrollsGameState, tickDuration time.Duration) *Server {
+	s := &Server{
+		TickDuration: tickDuration,
+		fixtures:     fixtures,
+		requestCount: map[string]int{},
+		stop:         make(chan struct{}),
+	}
+	s.tickChanged = sync.NewCond(&s.lock)
+	if tickDuration > 0 {
+		go s.runTicker()
+	}
+	return s
+}
+
+func (s *Server) runTicker() {
+	ticker := time.NewTicker(s.TickDuration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.AdvanceTick()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Close stops the ticker and releases all blocked requests
+func (s *Server) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		s.lock.Lock()
+		s.tickChanged.Broadcast()
+		s.lock.Unlock()
+	})
+}
+
+func (s *Server) AdvanceTick() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tick++
+	s.tickChanged.Broadcast()
+}
+
+func (s *Server) Tick() int32 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.currentTick()
+}
+
+// FailNextRequests makes the next requests (any endpoint) fail with the given status codes in order
+func (s *Server) FailNextRequests(statusCodes ...int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures = append(s.failures, statusCodes...)
+}
+
+// Commands returns all monster commands received so far
+func (s *Server) Commands() []SubmittedCommands {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]SubmittedCommands{}, s.commands...)
+}
+
+// CommandsForTick merges non-empty commands received during the tick by monster ID
+func (s *Server) CommandsForTick(tick int32) map[string]swagger.DungeonsandtrollsCommandsBatch {
+	merged := map[string]swagger.DungeonsandtrollsCommandsBatch{}
+	for _, submitted := range s.Commands() {
+		if submitted.Tick != tick {
+			continue
+		}
+		for id, cmd := range submitted.Commands.Commands {
+			merged[id] = cmd
+		}
+	}
+	return merged
+}
+
+func (s *Server) Respawns() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.respawns
+}
+
+// LogEntries returns all log entries shipped to the server so far
+func (s *Server) LogEntries() []json.RawMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]json.RawMessage{}, s.logEntries...)
+}
+
+// RequestCount returns number of requests per endpoint path (including failed ones)
+func (s *Server) RequestCount(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requestCount[path]
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/game", s.handleGame)
+	mux.HandleFunc("/v1/monsters-commands", s.handleMonstersCommands)
+	mux.HandleFunc("/v1/respawn", s.handleRespawn)
+	mux.HandleFunc("/logs", s.handleLogs)
+	return s.withChecks(mux)
+}
+
+// ListenAndServe serves the fake server until it fails
+func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, s.Handler())
+}
+
+func (s *Server) withChecks(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		s.requestCount[r.URL.Path]++
+		failure := 0
+		if len(s.failures) > 0 {
+			failure = s.failures[0]
+			s.failures = s.failures[1:]
+		}
+		s.lock.Unlock()
+		if failure != 0 {
+			writeError(w, failure, "injected failure")
+			return
+		}
+		// Log shipping is not part of the game API
+		if s.APIKey != "" && r.URL.Path != "/logs" && r.Header.Get("X-API-Key") != s.APIKey {
+			writeError(w, http.StatusForbidden, "invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleGame(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	s.lock.Lock()
+	if isBlocking(r) {
+		s.waitForNextTick()
+	}
+	state, err := s.currentState()
+	s.lock.Unlock()
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (s *Server) handleMonstersCommands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	commands := swagger.DungeonsandtrollsCommandsForMonsters{}
+	if err := json.NewDecoder(r.Body).Decode(&commands); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// The blocking parameter defaults to true on the server
+	blocking := r.URL.Query().Get("blocking") != "false"
+	s.lock.Lock()
+	s.commands = append(s.commands, SubmittedCommands{
+		Tick:     s.currentTick(),
+		Blocking: blocking,
+		Time:     time.Now(),
+		Commands: commands,
+	})
+	if blocking {
+		s.waitForNextTick()
+	}
+	s.lock.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Server) handleRespawn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	s.lock.Lock()
+	s.respawns++
+	s.lock.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// handleLogs accepts a JSON array of log entries (like Better Stack)
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	entries := []json.RawMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.lock.Lock()
+	s.logEntries = append(s.logEntries, entries...)
+	s.lock.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Must be called with lock held
+func (s *Server) waitForNextTick() {
+	tick := s.tick
+	for s.tick == tick {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		s.tickChanged.Wait()
+	}
+}
+
+// Must be called with lock held
+func (s *Server) currentState() (swagger.DungeonsandtrollsGameState, error) {
+	if len(s.fixtures) == 0 {
+		return swagger.DungeonsandtrollsGameState{}, fmt.Errorf("no fixtures loaded")
+	}
+	i := s.tick
+	if i >= len(s.fixtures) {
+		i = len(s.fixtures) - 1
+	}
+	state := s.fixtures[i]
+	state.Tick = s.currentTick()
+	return state, nil
+}
+
+// Must be called with lock held
+func (s *Server) currentTick() int32 {
+	tick := int32(s.tick)
+	if len(s.fixtures) > 0 {
+		tick += s.fixtures[0].Tick
+	}
+	return tick
+}
+
+func isBlocking(r *http.Request) bool {
+	return r.URL.Query().Get("blocking") == "true"
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"code":    status,
+		"message": message,
+	})
+}
+
+func LoadFixtures(paths ...string) ([]swagger.DungeonsandtrollsGameState, error) {
+	fixtures := []swagger.DungeonsandtrollsGameState{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading fixture: %w", err)
+		}
+		state := swagger.DungeonsandtrollsGameState{}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("parsing fixture %s: %w", path, err)
+		}
+		fixtures = append(fixtures, state)
+	}
+	return fixtures, nil
+}
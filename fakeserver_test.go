@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/bot"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/fakeserver"
+	"go.uber.org/zap"
+)
+
+// gameRequests records when the game state was requested
+type gameRequests struct {
+	lock  sync.Mutex
+	times []time.Time
+}
+
+func (g *gameRequests) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/game" {
+			g.lock.Lock()
+			g.times = append(g.times, time.Now())
+			g.lock.Unlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (g *gameRequests) get() []time.Time {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return append([]time.Time{}, g.times...)
+}
+
+func newFakeServer(t *testing.T) (*fakeserver.Server, *httptest.Server, *gameRequests) {
+	t.Helper()
+	fixtures, err := fakeserver.LoadFixtures("fixtures/arena.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := fakeserver.New(fixtures, 0)
+	server.APIKey = "test-key"
+	requests := &gameRequests{}
+	httpServer := httptest.NewServer(requests.wrap(server.Handler()))
+	t.Cleanup(func() {
+		// Blocked requests have to be released before the HTTP server can be closed
+		server.Close()
+		httpServer.Close()
+	})
+	return server, httpServer, requests
+}
+
+// waitFor polls the condition until it holds or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, condition func() bool) bool {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return false
+}
+
+// blockingWaits counts empty blocking command submissions (see runMonsterAI) during the tick
+func blockingWaits(server *fakeserver.Server, tick int32) int {
+	count := 0
+	for _, submitted := range server.Commands() {
+		if submitted.Tick == tick && submitted.Blocking && len(submitted.Commands.Commands) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func TestMonsterAIAgainstFakeServer(t *testing.T) {
+	server, httpServer, requests := newFakeServer(t)
+	// The first two game state fetches fail and are retried with backoff
+	server.FailNextRequests(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	cfg := swagger.NewConfiguration()
+	cfg.BasePath = httpServer.URL
+	client := swagger.NewAPIClient(cfg)
+	ctx := context.WithValue(context.Background(), swagger.ContextAPIKey, swagger.APIKey{Key: server.APIKey})
+	logger := zap.NewNop()
+	botDispatcher := bot.NewBotDispatcher(client, ctx, logger.Sugar(), "test")
+	botDispatcher.CommandTTL = 0
+
+	runCtx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		runMonsterAI(runCtx, client, botDispatcher, nil, logger, "test")
+	}()
+
+	firstTick := server.Tick()
+	for tick := firstTick; tick < firstTick+3; tick++ {
+		if !waitFor(t, 5*time.Second, func() bool { return blockingWaits(server, tick) > 0 }) {
+			t.Fatalf("tick %d: monster AI doesn't wait for the end of the tick", tick)
+		}
+		commands := server.CommandsForTick(tick)
+		if len(commands) == 0 {
+			t.Errorf("tick %d: no monster commands captured", tick)
+		}
+		for _, id := range []string{"monster-1", "monster-2"} {
+			if _, found := commands[id]; !found {
+				t.Errorf("tick %d: no command for %s", tick, id)
+			}
+		}
+		// The monster AI waits in the blocking request until the tick advances
+		fetches := server.RequestCount("/v1/game")
+		time.Sleep(50 * time.Millisecond)
+		if server.RequestCount("/v1/game") != fetches || blockingWaits(server, tick) != 1 {
+			t.Errorf("tick %d: monster AI didn't block until the next tick", tick)
+		}
+		server.AdvanceTick()
+	}
+	cancel()
+	server.Close()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("monster AI didn't stop after cancel")
+	}
+
+	times := requests.get()
+	if len(times) < 5 {
+		t.Fatalf("expected 2 failed and 3 successful game state fetches, got %d", len(times))
+	}
+	// Backoff starts at 300 ms and doubles
+	if gap := times[1].Sub(times[0]); gap < 300*time.Millisecond {
+		t.Errorf("first retry after %v, expected at least 300ms", gap)
+	}
+	if gap := times[2].Sub(times[1]); gap < 600*time.Millisecond {
+		t.Errorf("second retry after %v, expected at least 600ms", gap)
+	}
+	// Backoff is reset after a successful fetch
+	if gap := times[3].Sub(times[2]); gap > 500*time.Millisecond {
+		t.Errorf("fetch after a successful tick took %v", gap)
+	}
+}
+
+func TestFakeServerBlockingGame(t *testing.T) {
+	server, httpServer, _ := newFakeServer(t)
+	tick := server.Tick()
+
+	get := func(blocking string) (*http.Response, error) {
+		request, err := http.NewRequest(http.MethodGet, httpServer.URL+"/v1/game?blocking="+blocking, nil)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("X-API-Key", server.APIKey)
+		return http.DefaultClient.Do(request)
+	}
+
+	type result struct {
+		state swagger.DungeonsandtrollsGameState
+		err   error
+	}
+	results := make(chan result, 1)
+	go func() {
+		response, err := get("true")
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer response.Body.Close()
+		state := swagger.DungeonsandtrollsGameState{}
+		err = json.NewDecoder(response.Body).Decode(&state)
+		results <- result{state: state, err: err}
+	}()
+
+	if !waitFor(t, 5*time.Second, func() bool { return server.RequestCount("/v1/game") == 1 }) {
+		t.Fatal("blocking request not received")
+	}
+	select {
+	case <-results:
+		t.Fatal("blocking request returned before the tick advanced")
+	case <-time.After(50 * time.Millisecond):
+	}
+	// Non-blocking requests return the current tick immediately
+	response, err := get("false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("non-blocking request failed with %d", response.StatusCode)
+	}
+
+	server.AdvanceTick()
+	select {
+	case r := <-results:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if r.state.Tick != tick+1 {
+			t.Errorf("blocking request returned tick %d, expected %d", r.state.Tick, tick+1)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocking request not released by the next tick")
+	}
+}
@@ -3,12 +3,23 @@ module github.com/gdg-garage/dungeons-and-trolls-monsters-ai
 go 1.19
 
 require (
-	github.com/antihax/optional v1.0.0 // indirect
-	github.com/gdg-garage/dungeons-and-trolls-go-client v1.10.0 // indirect
+	github.com/antihax/optional v1.0.0
+	github.com/gdg-garage/dungeons-and-trolls-go-client v1.10.0
+	github.com/prometheus/client_golang v1.17.0
+	go.uber.org/zap v1.26.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.4 // indirect
+	github.com/prometheus/client_model v0.4.1-0.20230718164431-9a2bf3000d16 // indirect
+	github.com/prometheus/common v0.44.0 // indirect
+	github.com/prometheus/procfs v0.11.1 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
-	go.uber.org/zap v1.26.0 // indirect
 	golang.org/x/oauth2 v0.13.0 // indirect
+	golang.org/x/sys v0.11.0 // indirect
 	google.golang.org/appengine v1.6.8 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
 )
@@ -0,0 +1,303 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/metrics"
+	"go.uber.org/zap/zapcore"
+)
+
+const BetterStackURL = "https://in.logs.betterstack.com"
+
+// Config of the Shipper, zero values are replaced by defaults in NewShipper
+type Config struct {
+	URL string
+	// Sent as a bearer token (Better Stack source token)
+	Token string
+	// Entries are sent as one JSON array when BatchSize entries are buffered or after FlushInterval
+	BatchSize     int
+	FlushInterval time.Duration
+	// Entries logged while BufferSize entries wait for shipping are dropped
+	BufferSize int
+	// Failed batches are retried MaxRetries times with exponential backoff, then dropped
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Maximum time Sync and Close wait for the buffer to be shipped
+	FlushTimeout time.Duration
+	Client       *http.Client
+	// Shipping errors can't be logged through the shipped logger
+	ErrorLog *log.Logger
+}
+
+func DefaultConfig() Config {
+	return Config{
+		URL:            BetterStackURL,
+		BatchSize:      100,
+		FlushInterval:  time.Second,
+		BufferSize:     10000,
+		MaxRetries:     3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		FlushTimeout:   5 * time.Second,
+		Client:         &http.Client{Timeout: 10 * time.Second},
+		ErrorLog:       log.New(os.Stderr, "logship: ", log.LstdFlags),
+	}
+}
+
+// Stats are counted in log entries
+type Stats struct {
+	Shipped int64 `json:"shipped"`
+	// Buffer was full or the shipper was closed
+	Dropped int64 `json:"dropped"`
+	// Batch failed after all retries
+	Failed  int64 `json:"failed"`
+	Retries int64 `json:"retries"`
+}
+
+// Shipper is a zapcore.WriteSyncer shipping JSON log entries over HTTP in batches
+// Write never blocks on the network, entries are handed to a background goroutine through a bounded buffer
+type Shipper struct {
+	Config Config
+
+	entries chan []byte
+	flushes chan chan struct{}
+	done    chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+	stats   Stats
+	// Entries are enqueued under the read lock so that none is enqueued after run() drained the buffer on Close
+	lock     sync.RWMutex
+	isClosed bool
+}
+
+func NewShipper(config Config) *Shipper {
+	defaults := DefaultConfig()
+	if config.URL == "" {
+		config.URL = defaults.URL
+	}
+	if config.BatchSize < 1 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaults.FlushInterval
+	}
+	if config.BufferSize < 1 {
+		config.BufferSize = defaults.BufferSize
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaults.InitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaults.MaxBackoff
+	}
+	if config.FlushTimeout <= 0 {
+		config.FlushTimeout = defaults.FlushTimeout
+	}
+	if config.Client == nil {
+		config.Client = defaults.Client
+	}
+	if config.ErrorLog == nil {
+		config.ErrorLog = defaults.ErrorLog
+	}
+	s := &Shipper{
+		Config:  config,
+		entries: make(chan []byte, config.BufferSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Core returns a JSON zap core writing to the shipper (use zapcore.NewTee to keep the local output)
+func (s *Shipper) Core(encoderConfig zapcore.EncoderConfig, level zapcore.LevelEnabler) zapcore.Core {
+	return zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), s, level)
+}
+
+// Write buffers one encoded log entry, the entry is dropped when the buffer is full
+func (s *Shipper) Write(p []byte) (int, error) {
+	entry := bytes.TrimSpace(p)
+	if len(entry) == 0 {
+		return len(p), nil
+	}
+	// zap reuses the buffer
+	entry = append([]byte{}, entry...)
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.isClosed {
+		s.drop(1)
+		return len(p), nil
+	}
+	select {
+	case s.entries <- entry:
+	default:
+		s.drop(1)
+	}
+	return len(p), nil
+}
+
+// Sync ships everything buffered so far (waits up to FlushTimeout)
+func (s *Shipper) Sync() error {
+	flushed := make(chan struct{})
+	select {
+	case s.flushes <- flushed:
+	case <-s.done:
+		return nil
+	case <-time.After(s.Config.FlushTimeout):
+		return fmt.Errorf("log shipping flush timed out")
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-time.After(s.Config.FlushTimeout):
+		return fmt.Errorf("log shipping flush timed out")
+	}
+}
+
+// Close ships the buffered entries and stops the shipper, later entries are dropped
+func (s *Shipper) Close() error {
+	s.once.Do(func() {
+		s.lock.Lock()
+		s.isClosed = true
+		s.lock.Unlock()
+		close(s.closed)
+	})
+	select {
+	case <-s.done:
+		return nil
+	case <-time.After(s.Config.FlushTimeout):
+		return fmt.Errorf("log shipping flush timed out")
+	}
+}
+
+func (s *Shipper) Stats() Stats {
+	return Stats{
+		Shipped: atomic.LoadInt64(&s.stats.Shipped),
+		Dropped: atomic.LoadInt64(&s.stats.Dropped),
+		Failed:  atomic.LoadInt64(&s.stats.Failed),
+		Retries: atomic.LoadInt64(&s.stats.Retries),
+	}
+}
+
+func (s *Shipper) drop(count int) {
+	atomic.AddInt64(&s.stats.Dropped, int64(count))
+	metrics.LogEntriesDropped.Add(float64(count))
+}
+
+func (s *Shipper) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.Config.FlushInterval)
+	defer ticker.Stop()
+	batch := [][]byte{}
+	// Takes everything already buffered without waiting
+	drain := func() {
+		for {
+			select {
+			case entry := <-s.entries:
+				batch = append(batch, entry)
+				if len(batch) >= s.Config.BatchSize {
+					s.ship(batch)
+					batch = [][]byte{}
+				}
+			default:
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+			if len(batch) >= s.Config.BatchSize {
+				s.ship(batch)
+				batch = [][]byte{}
+			}
+		case <-ticker.C:
+			s.ship(batch)
+			batch = [][]byte{}
+		case flushed := <-s.flushes:
+			drain()
+			s.ship(batch)
+			batch = [][]byte{}
+			close(flushed)
+		case <-s.closed:
+			drain()
+			s.ship(batch)
+			return
+		}
+	}
+}
+
+// ship sends the batch as a JSON array, retrying server errors with exponential backoff
+func (s *Shipper) ship(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	body := append([]byte{'['}, bytes.Join(batch, []byte{','})...)
+	body = append(body, ']')
+	backoff := s.Config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(body)
+		if err == nil {
+			atomic.AddInt64(&s.stats.Shipped, int64(len(batch)))
+			metrics.LogEntriesShipped.Add(float64(len(batch)))
+			return
+		}
+		if !retry || attempt >= s.Config.MaxRetries {
+			s.Config.ErrorLog.Printf("dropping %d log entries after %d attempts: %v", len(batch), attempt+1, err)
+			atomic.AddInt64(&s.stats.Failed, int64(len(batch)))
+			s.drop(len(batch))
+			return
+		}
+		atomic.AddInt64(&s.stats.Retries, 1)
+		select {
+		case <-time.After(backoff):
+		case <-s.closed:
+			// Shutting down, retry once more without waiting
+		}
+		backoff *= 2
+		if backoff > s.Config.MaxBackoff {
+			backoff = s.Config.MaxBackoff
+		}
+	}
+}
+
+// post returns true if the request can be retried
+func (s *Shipper) post(body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Config.FlushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Config.Token)
+	}
+	resp, err := s.Config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	// Client errors (bad token, payload too large) won't get better
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+}
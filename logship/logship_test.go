@@ -0,0 +1,211 @@
+package logship
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stub is a log intake recording received batches, it answers with the queued status codes (then 202)
+type stub struct {
+	lock     sync.Mutex
+	statuses []int
+	batches  [][]json.RawMessage
+	times    []time.Time
+	// Requests wait until release is closed (nil for no waiting)
+	release chan struct{}
+	server  *httptest.Server
+}
+
+func newStub(t *testing.T, statuses ...int) *stub {
+	s := &stub{statuses: statuses}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *stub) handle(w http.ResponseWriter, r *http.Request) {
+	if s.release != nil {
+		<-s.release
+	}
+	batch := []json.RawMessage{}
+	err := json.NewDecoder(r.Body).Decode(&batch)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.times = append(s.times, time.Now())
+	if err != nil || r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	status := http.StatusAccepted
+	if len(s.statuses) > 0 {
+		status = s.statuses[0]
+		s.statuses = s.statuses[1:]
+	}
+	if status < 300 {
+		s.batches = append(s.batches, batch)
+	}
+	w.WriteHeader(status)
+}
+
+func (s *stub) batchSizes() []int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sizes := []int{}
+	for _, batch := range s.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func (s *stub) requests() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.times)
+}
+
+func (s *stub) config() Config {
+	return Config{
+		URL:            s.server.URL,
+		Token:          "token",
+		BatchSize:      3,
+		FlushInterval:  time.Hour,
+		MaxRetries:     3,
+		InitialBackoff: 20 * time.Millisecond,
+		ErrorLog:       log.New(io.Discard, "", 0),
+	}
+}
+
+func writeEntries(t *testing.T, shipper *Shipper, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		if _, err := fmt.Fprintf(shipper, "{\"message\":\"entry %d\"}\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestShipperBatchesAndFlushesOnClose(t *testing.T) {
+	stub := newStub(t)
+	shipper := NewShipper(stub.config())
+	writeEntries(t, shipper, 0, 7)
+	if err := shipper.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Two full batches, the rest is flushed on Close
+	sizes := stub.batchSizes()
+	if fmt.Sprint(sizes) != "[3 3 1]" {
+		t.Errorf("expected batches [3 3 1], got %v", sizes)
+	}
+	writeEntries(t, shipper, 7, 8)
+	stats := shipper.Stats()
+	if stats.Shipped != 7 || stats.Dropped != 1 {
+		t.Errorf("expected 7 shipped and 1 dropped after Close, got %+v", stats)
+	}
+}
+
+func TestShipperRetriesWithBackoff(t *testing.T) {
+	stub := newStub(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	shipper := NewShipper(stub.config())
+	defer shipper.Close()
+	writeEntries(t, shipper, 0, 1)
+	if err := shipper.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	stats := shipper.Stats()
+	if stats.Shipped != 1 || stats.Retries != 2 || stats.Failed != 0 {
+		t.Errorf("expected 1 shipped after 2 retries, got %+v", stats)
+	}
+	stub.lock.Lock()
+	times := stub.times
+	stub.lock.Unlock()
+	if len(times) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(times))
+	}
+	if gap := times[1].Sub(times[0]); gap < 20*time.Millisecond {
+		t.Errorf("first retry after %v, expected at least 20ms", gap)
+	}
+	if gap := times[2].Sub(times[1]); gap < 40*time.Millisecond {
+		t.Errorf("second retry after %v, expected at least 40ms", gap)
+	}
+}
+
+func TestShipperDropsAfterRetriesAndClientErrors(t *testing.T) {
+	stub := newStub(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusBadRequest)
+	config := stub.config()
+	config.MaxRetries = 1
+	shipper := NewShipper(config)
+	defer shipper.Close()
+	// Retried once, then dropped
+	writeEntries(t, shipper, 0, 1)
+	shipper.Sync()
+	// Client errors are not retried
+	writeEntries(t, shipper, 1, 2)
+	shipper.Sync()
+	stats := shipper.Stats()
+	if stats.Shipped != 0 || stats.Failed != 2 || stats.Dropped != 2 || stats.Retries != 1 {
+		t.Errorf("expected 2 failed entries and 1 retry, got %+v", stats)
+	}
+	if stub.requests() != 3 {
+		t.Errorf("expected 3 requests, got %d", stub.requests())
+	}
+}
+
+func TestShipperDropsWhenBufferIsFull(t *testing.T) {
+	stub := newStub(t)
+	stub.release = make(chan struct{})
+	config := stub.config()
+	config.BatchSize = 1
+	config.BufferSize = 2
+	shipper := NewShipper(config)
+	writeEntries(t, shipper, 0, 1)
+	// Wait until the shipper is stuck in the request, then fill the buffer
+	for deadline := time.Now().Add(5 * time.Second); len(shipper.entries) > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("first entry not taken from the buffer")
+		}
+	}
+	writeEntries(t, shipper, 1, 6)
+	if stats := shipper.Stats(); stats.Dropped != 3 {
+		t.Errorf("expected 3 dropped entries, got %+v", stats)
+	}
+	close(stub.release)
+	if err := shipper.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := shipper.Stats(); stats.Shipped != 3 || stats.Dropped != 3 {
+		t.Errorf("expected 3 shipped and 3 dropped entries, got %+v", stats)
+	}
+}
+
+// Every entry written concurrently with Close is either shipped or counted as dropped
+func TestShipperWriteRacingClose(t *testing.T) {
+	stub := newStub(t)
+	config := stub.config()
+	config.BatchSize = 10
+	shipper := NewShipper(config)
+	writers := sync.WaitGroup{}
+	const writersCount, entriesPerWriter = 8, 200
+	for w := 0; w < writersCount; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			writeEntries(t, shipper, w*entriesPerWriter, (w+1)*entriesPerWriter)
+		}(w)
+	}
+	time.Sleep(time.Millisecond)
+	if err := shipper.Close(); err != nil {
+		t.Fatal(err)
+	}
+	writers.Wait()
+	stats := shipper.Stats()
+	if stats.Shipped+stats.Dropped != writersCount*entriesPerWriter {
+		t.Errorf("expected %d entries shipped or dropped, got %+v", writersCount*entriesPerWriter, stats)
+	}
+}
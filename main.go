@@ -6,10 +6,18 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/admin"
 	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/bot"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/logship"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/metrics"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/recording"
 	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/swaggerutil"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -42,12 +50,21 @@ func main() {
 		fallbackLog(fmt.Sprintf("FATAL: Can't initialize zap logger: %v", err))
 		os.Exit(1)
 	}
+	logger, stopLogShipping := configureLogShipping(logger, loggerConfig)
 	defer logger.Sync()
+	defer stopLogShipping()
 
-	stop, found := os.LookupEnv("DNT_PAUSE_APP")
-	if found && stop != "" {
-		logger.Error("MONSTER AI IS PAUSED! Unset DNT_PAUSE_APP env variable to unpause.")
-		os.Exit(0)
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		simulate(logger, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		replay(logger, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fake-server" {
+		runFakeServer(logger, os.Args[2:])
+		return
 	}
 
 	// Read command line arguments OR environment variables
@@ -68,6 +85,10 @@ func main() {
 		cfg.BasePath = "https://docker.tivvit.cz"
 		environment = "dev"
 	}
+	baseURL, found := os.LookupEnv("DNT_BASE_URL")
+	if found && baseURL != "" {
+		cfg.BasePath = baseURL
+	}
 	logger = logger.With(
 		zap.String("environment", environment),
 	)
@@ -84,26 +105,148 @@ func main() {
 	}
 
 	botDispatcher := bot.NewBotDispatcher(client, ctx, logger.Sugar(), environment)
-	backoff := 300 * time.Millisecond
+	configureBotDispatcher(botDispatcher, logger)
+	// Commands are sent with ctx so that they are still delivered during shutdown
+	shutdownCtx := handleShutdownSignals(ctx, logger)
+	stop, found := os.LookupEnv("DNT_PAUSE_APP")
+	if found && stop != "" {
+		// Resumed by the admin API or the pause file
+		botDispatcher.SetPaused(true)
+		logger.Error("MONSTER AI IS PAUSED! Unset DNT_PAUSE_APP env variable to start unpaused.")
+	}
+	pauseFile, found := os.LookupEnv("DNT_PAUSE_FILE")
+	if found && pauseFile != "" {
+		go watchPauseFile(shutdownCtx, pauseFile, pauseFileInterval, botDispatcher, logger)
+	}
+	adminAddr, found := os.LookupEnv("DNT_ADMIN_ADDR")
+	if found && adminAddr != "" {
+		adminServer := admin.New(botDispatcher, os.Getenv("DNT_ADMIN_TOKEN"), logger.Sugar()).Serve(adminAddr)
+		defer adminServer.Close()
+	}
+	metricsAddr, found := os.LookupEnv("DNT_METRICS_ADDR")
+	if found && metricsAddr != "" {
+		metricsServer := metrics.Serve(metricsAddr, logger.Sugar())
+		defer metricsServer.Close()
+	}
+	var recorder *recording.Writer
+	recordPath, found := os.LookupEnv("DNT_RECORD_FILE")
+	if found && recordPath != "" {
+		recorder, err = recording.Create(recordPath)
+		if err != nil {
+			logger.Fatal("Can't create recording",
+				zap.String("path", recordPath),
+				zap.Error(err),
+			)
+		}
+		defer recorder.Close()
+		botDispatcher.Recorder = recorder
+		logger.Warn("Recording game states and commands",
+			zap.String("path", recordPath),
+		)
+	}
+	runMonsterAI(shutdownCtx, client, botDispatcher, recorder, logger, environment)
+	logger.Info("Waiting for commands in flight")
+	if !botDispatcher.WaitForCommands(5 * time.Second) {
+		logger.Warn("Commands in flight not delivered before shutdown")
+	}
+	logger.Info("Monster AI stopped")
+}
+
+// handleShutdownSignals returns a context cancelled on SIGINT or SIGTERM (the current tick is finished)
+// Second signal exits immediately
+func handleShutdownSignals(ctx context.Context, logger *zap.Logger) context.Context {
+	shutdownCtx, cancel := context.WithCancel(ctx)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		logger.Warn("Shutting down after the current tick, send the signal again to exit immediately",
+			zap.String("signal", sig.String()),
+		)
+		cancel()
+		sig = <-signals
+		logger.Error("Exiting immediately",
+			zap.String("signal", sig.String()),
+		)
+		logger.Sync()
+		os.Exit(1)
+	}()
+	return shutdownCtx
+}
+
+const pauseFileInterval = time.Second
+
+// watchPauseFile pauses the bots while the file exists (checked every interval) until ctx is cancelled
+// Only changes of the file are applied so that the bots can be resumed by other means while the file exists
+func watchPauseFile(ctx context.Context, path string, interval time.Duration, botDispatcher *bot.BotDispatcher, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	exists := false
 	for {
+		_, err := os.Stat(path)
+		if (err == nil) != exists {
+			exists = err == nil
+			if botDispatcher.SetPaused(exists) {
+				logger.Warn("Pause file changed",
+					zap.String("path", path),
+					zap.Bool("paused", exists),
+				)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runMonsterAI fetches game state, runs the bots, and waits for the end of the tick until ctx is cancelled
+func runMonsterAI(ctx context.Context, client *swagger.APIClient, botDispatcher *bot.BotDispatcher, recorder *recording.Writer, logger *zap.Logger, environment string) {
+	backoff := 300 * time.Millisecond
+	for ctx.Err() == nil {
 		logger.Info("Fetching game state for NEW TICK ...")
+		fetchStartTime := time.Now()
 		// Use the client to make API requests
 		gameResp, httpResp, err := client.DungeonsAndTrollsApi.DungeonsAndTrollsGame(ctx, nil)
+		metrics.TickFetchDuration.Observe(time.Since(fetchStartTime).Seconds())
+		if err != nil && ctx.Err() != nil {
+			break
+		}
 		if err != nil {
 			swaggerutil.LogError(logger.Sugar(), err, httpResp, "Game", nil)
 			logger.Info("Sleeping before retrying",
 				zap.Duration("duration", backoff),
 			)
-			time.Sleep(backoff)
+			metrics.SetBackoff(backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+			}
 			backoff *= 2
 			continue
 		}
 		backoff = 300 * time.Millisecond
+		metrics.SetBackoff(0)
 		tickStartTime := time.Now()
 		logger.Info("======================= Game state fetched for NEW TICK =======================",
 			zap.Time("tickStartTime", tickStartTime),
 		)
+		// Seed every tick so the decisions can be reproduced from a recording
+		seed := tickStartTime.UnixNano()
+		botDispatcher.Seed = seed
+		if recorder != nil {
+			if err := recorder.StartTick(&gameResp, tickStartTime, seed, environment); err != nil {
+				logger.Error("Can't record tick", zap.Error(err))
+			}
+		}
 		err = botDispatcher.HandleTick(&gameResp, tickStartTime)
+		if recorder != nil {
+			recorder.RecordSettings(recording.DispatcherSettings(botDispatcher))
+			if err := recorder.FinishTick(); err != nil {
+				logger.Error("Can't record tick", zap.Error(err))
+			}
+		}
 		if err != nil {
 			logger.Error("Error when running monster AI",
 				zap.Error(err),
@@ -116,10 +259,134 @@ func main() {
 		emptyCommand := swagger.DungeonsandtrollsCommandsForMonsters{}
 		// Wait until the end of the tick
 		_, httpResp, err = client.DungeonsAndTrollsApi.DungeonsAndTrollsMonstersCommands(ctx, emptyCommand, nil)
+		if ctx.Err() != nil {
+			// Shutting down, no need to wait for the end of the tick
+			break
+		}
 		swaggerutil.LogResponse(loggerResponse.Sugar(), err, httpResp, "MonstersCommands (empty, blocking)", emptyCommand)
 	}
 }
 
+// configureLogShipping ships logs over HTTP in batches when DNT_LOG_SHIP_TOKEN or DNT_LOG_SHIP_URL is set
+// Logs are still written to the standard output
+func configureLogShipping(logger *zap.Logger, loggerConfig zap.Config) (*zap.Logger, func()) {
+	url := os.Getenv("DNT_LOG_SHIP_URL")
+	token := os.Getenv("DNT_LOG_SHIP_TOKEN")
+	if url == "" && token == "" {
+		return logger, func() {}
+	}
+	config := logship.DefaultConfig()
+	if url != "" {
+		config.URL = url
+	}
+	config.Token = token
+	shipper := logship.NewShipper(config)
+	logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, shipper.Core(loggerConfig.EncoderConfig, loggerConfig.Level))
+	}))
+	logger.Info("Shipping logs",
+		zap.String("url", config.URL),
+	)
+	return logger, func() {
+		logger.Info("Stopping log shipping",
+			zap.Any("stats", shipper.Stats()),
+		)
+		if err := shipper.Close(); err != nil {
+			fallbackLog(fmt.Sprintf("ERROR: Can't flush shipped logs: %v", err))
+		}
+	}
+}
+
+// Loads optional config profiles, tuning file and debug settings set by environment variables
+func configureBotDispatcher(botDispatcher *bot.BotDispatcher, logger *zap.Logger) {
+	profilesPath, found := os.LookupEnv("DNT_CONFIG_PROFILES")
+	if found && profilesPath != "" {
+		profiles, err := bot.LoadConfigProfiles(profilesPath)
+		if err != nil {
+			logger.Fatal("Can't load config profiles",
+				zap.String("path", profilesPath),
+				zap.Error(err),
+			)
+		}
+		logger.Info("Config profiles loaded",
+			zap.String("path", profilesPath),
+			zap.Any("defaultProfile", profiles.Default),
+			zap.Any("profiles", profiles.Profiles),
+		)
+		botDispatcher.Profiles = profiles
+	}
+	tuningPath, found := os.LookupEnv("DNT_TUNING_FILE")
+	if found && tuningPath != "" {
+		tuningFile, err := bot.LoadTuningFile(tuningPath)
+		if err != nil {
+			logger.Fatal("Can't load tuning file",
+				zap.String("path", tuningPath),
+				zap.Error(err),
+			)
+		}
+		botDispatcher.TuningFile = tuningFile
+		// SIGHUP forces reload before the next tick (file changes are picked up automatically)
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				logger.Info("SIGHUP received, reloading tuning file before next tick")
+				tuningFile.RequestReload()
+			}
+		}()
+	}
+	debugMonsters, found := os.LookupEnv("DNT_DEBUG_MONSTERS")
+	if found && debugMonsters != "" {
+		botDispatcher.DebugMonsters = strings.Split(debugMonsters, ",")
+		botDispatcher.TraceDir = os.Getenv("DNT_TRACE_DIR")
+		logger.Info("Decision traces enabled",
+			zap.Strings("debugMonsters", botDispatcher.DebugMonsters),
+			zap.String("traceDir", botDispatcher.TraceDir),
+		)
+	}
+	tickDeadline, found := os.LookupEnv("DNT_TICK_DEADLINE")
+	if found && tickDeadline != "" {
+		deadline, err := time.ParseDuration(tickDeadline)
+		if err != nil {
+			logger.Fatal("Invalid tick deadline",
+				zap.String("tickDeadline", tickDeadline),
+				zap.Error(err),
+			)
+		}
+		botDispatcher.TickDeadline = deadline
+	}
+	batching, found := os.LookupEnv("DNT_COMMAND_BATCHING")
+	if found && batching != "" {
+		commandBatching, err := bot.ParseCommandBatching(batching)
+		if err != nil {
+			logger.Fatal("Invalid command batching",
+				zap.Error(err),
+			)
+		}
+		botDispatcher.CommandBatching = commandBatching
+	}
+	retries, found := os.LookupEnv("DNT_COMMAND_RETRIES")
+	if found && retries != "" {
+		n, err := strconv.Atoi(retries)
+		if err != nil || n < 0 {
+			logger.Fatal("Invalid number of command retries",
+				zap.String("retries", retries),
+			)
+		}
+		botDispatcher.CommandRetries = n
+	}
+	workers, found := os.LookupEnv("DNT_WORKERS")
+	if found && workers != "" {
+		n, err := strconv.Atoi(workers)
+		if err != nil || n < 1 {
+			logger.Fatal("Invalid number of workers",
+				zap.String("workers", workers),
+			)
+		}
+		botDispatcher.Workers = n
+	}
+}
+
 func respawn(ctx context.Context, logger *zap.SugaredLogger, client *swagger.APIClient) {
 	dummyPayload := ctx
 	logger.Warn("Respawning ...")
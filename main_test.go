@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/bot"
+	"go.uber.org/zap"
+)
+
+func TestWatchPauseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pause")
+	d := bot.NewBotDispatcher(nil, context.Background(), zap.NewNop().Sugar(), "test")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		watchPauseFile(ctx, path, 10*time.Millisecond, d, zap.NewNop())
+		close(stopped)
+	}()
+
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(t, 2*time.Second, d.IsPaused) {
+		t.Fatal("bots not paused when the pause file was created")
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(t, 2*time.Second, func() bool { return !d.IsPaused() }) {
+		t.Fatal("bots not resumed when the pause file was removed")
+	}
+
+	// Bots paused by other means (e.g. DNT_PAUSE_APP or the admin API) stay paused while the file doesn't change
+	d.SetPaused(true)
+	time.Sleep(50 * time.Millisecond)
+	if !d.IsPaused() {
+		t.Error("watcher resumed bots paused by other means")
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watcher didn't stop after cancel")
+	}
+}
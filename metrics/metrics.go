@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const namespace = "dnt_monsters"
+
+// Metrics are always collected, they are only exposed when Serve is called
+var (
+	Registry = prometheus.NewRegistry()
+
+	TickFetchDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tick_fetch_duration_seconds",
+		Help:      "Time to fetch the game state of a new tick",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+	LevelDecisionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "level_decision_duration_seconds",
+		Help:      "Time for all bots of a level to decide",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+	}, []string{"level"})
+	CommandsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_sent_total",
+		Help:      "Monster commands delivered by command type",
+	}, []string{"type"})
+	CommandDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "command_deliveries_total",
+		Help:      "Monster commands by delivery status (delivered, failed, stale)",
+	}, []string{"status"})
+	APIErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_errors_total",
+		Help:      "Failed API requests by method and status code (none without response)",
+	}, []string{"method", "status_code"})
+	Backoff = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "backoff_seconds",
+		Help:      "Current backoff before retrying to fetch the game state (0 when the last fetch succeeded)",
+	})
+	TrackedBots = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tracked_bots",
+		Help:      "Bots kept by the dispatcher after the last tick",
+	})
+	SkillsUsed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "skills_used_total",
+		Help:      "Skills used by monsters by skill name",
+	}, []string{"skill"})
+	LogEntriesShipped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "log_entries_shipped_total",
+		Help:      "Log entries shipped over HTTP (see logship)",
+	})
+	LogEntriesDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "log_entries_dropped_total",
+		Help:      "Log entries dropped because the buffer was full or shipping failed",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		TickFetchDuration,
+		LevelDecisionDuration,
+		CommandsSent,
+		CommandDeliveries,
+		APIErrors,
+		Backoff,
+		TrackedBots,
+		SkillsUsed,
+		LogEntriesShipped,
+		LogEntriesDropped,
+	)
+}
+
+func ObserveLevelDecision(level int32, duration time.Duration) {
+	LevelDecisionDuration.WithLabelValues(strconv.Itoa(int(level))).Observe(duration.Seconds())
+}
+
+func CountCommand(commandType string) {
+	if commandType == "" {
+		commandType = "Empty"
+	}
+	CommandsSent.WithLabelValues(commandType).Inc()
+}
+
+func CountAPIError(method string, httpResp *http.Response) {
+	statusCode := "none"
+	if httpResp != nil {
+		statusCode = strconv.Itoa(httpResp.StatusCode)
+	}
+	APIErrors.WithLabelValues(method, statusCode).Inc()
+}
+
+func SetBackoff(backoff time.Duration) {
+	Backoff.Set(backoff.Seconds())
+}
+
+// Serve exposes the metrics on /metrics in the background
+func Serve(addr string, logger *zap.SugaredLogger) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		logger.Infow("Serving metrics",
+			"addr", addr,
+		)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorw("Metrics server failed",
+				zap.Error(err),
+			)
+		}
+	}()
+	return server
+}
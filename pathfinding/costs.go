@@ -0,0 +1,75 @@
+package pathfinding
+
+import (
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// TileCosts are added to the base cost of entering a tile
+type TileCosts struct {
+	Base float32 `json:"base"`
+	// Per harmful ground effect (fire, poison, ...) plus damage times HarmfulEffectDamage
+	HarmfulEffect       float32 `json:"harmfulEffect"`
+	HarmfulEffectDamage float32 `json:"harmfulEffectDamage"`
+	// Per monster of the same faction standing on the tile
+	FriendlyMonster float32 `json:"friendlyMonster"`
+	Door            float32 `json:"door"`
+	StairsOrSpawn   float32 `json:"stairsOrSpawn"`
+}
+
+// LevelGrid builds a grid for the level as seen by a character of the faction
+// Walls and other non-free tiles are impassable, tiles missing in the level are free
+func LevelGrid(level *swagger.DungeonsandtrollsLevel, faction string, costs TileCosts) *Grid {
+	tiles := make(map[Position]*swagger.DungeonsandtrollsMapObjects, len(level.Objects))
+	for i := range level.Objects {
+		tiles[*level.Objects[i].Position] = &level.Objects[i]
+	}
+	return TileGrid(level.Width, level.Height, tiles, faction, costs)
+}
+
+// TileGrid is LevelGrid for already indexed level tiles
+func TileGrid(width, height int32, tiles map[Position]*swagger.DungeonsandtrollsMapObjects, faction string, costs TileCosts) *Grid {
+	return &Grid{
+		Width:   width,
+		Height:  height,
+		MinCost: costs.Base,
+		Cost: func(position Position) (float32, bool) {
+			tile, found := tiles[position]
+			if !found {
+				return costs.Base, true
+			}
+			return TileCost(tile, faction, costs)
+		},
+	}
+}
+
+func TileCost(tile *swagger.DungeonsandtrollsMapObjects, faction string, costs TileCosts) (float32, bool) {
+	cost := costs.Base
+	passable := tile.IsFree
+	// Closed doors are not free and block the way like in LevelLayout
+	if tile.IsDoor {
+		cost += costs.Door
+	}
+	if tile.IsStairs || tile.IsSpawn {
+		cost += costs.StairsOrSpawn
+	}
+	for _, effect := range tile.Effects {
+		if damage := EffectDamage(effect); damage > 0 {
+			cost += costs.HarmfulEffect + damage*costs.HarmfulEffectDamage
+		}
+	}
+	for _, monster := range tile.Monsters {
+		if monster.Faction == faction && monster.Faction != "neutral" {
+			cost += costs.FriendlyMonster
+		}
+	}
+	return cost, passable
+}
+
+// EffectDamage returns life lost per tick by standing in the effect (0 for harmless effects)
+func EffectDamage(effect swagger.DungeonsandtrollsEffect) float32 {
+	damage := effect.DamageAmount
+	if effect.Effects != nil && effect.Effects.Life < 0 {
+		damage -= effect.Effects.Life
+	}
+	return damage
+}
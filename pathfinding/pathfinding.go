@@ -0,0 +1,226 @@
+package pathfinding
+
+import (
+	"container/heap"
+	"math"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+type Position = swagger.DungeonsandtrollsPosition
+
+// CostFunc returns the cost of entering the tile (not passable tiles are never entered)
+// Costs must be at least Grid.MinCost for A* to find the cheapest paths
+type CostFunc func(position Position) (cost float32, passable bool)
+
+// Grid is a 4-neighbour tile grid with per-tile movement costs
+type Grid struct {
+	Width   int32
+	Height  int32
+	Cost    CostFunc
+	MinCost float32
+}
+
+// Path excludes the start position and includes the goal
+type Path struct {
+	Positions []Position
+	Cost      float32
+}
+
+func (p Path) Len() int {
+	return len(p.Positions)
+}
+
+// Next returns the first step of the path (nil for empty path)
+func (p Path) Next() *Position {
+	if len(p.Positions) == 0 {
+		return nil
+	}
+	return &p.Positions[0]
+}
+
+func (g *Grid) InBounds(position Position) bool {
+	return position.PositionX >= 0 && position.PositionX < g.Width && position.PositionY >= 0 && position.PositionY < g.Height
+}
+
+func (g *Grid) neighbors(position Position) []Position {
+	candidates := []Position{
+		{PositionX: position.PositionX - 1, PositionY: position.PositionY},
+		{PositionX: position.PositionX + 1, PositionY: position.PositionY},
+		{PositionX: position.PositionX, PositionY: position.PositionY - 1},
+		{PositionX: position.PositionX, PositionY: position.PositionY + 1},
+	}
+	neighbors := make([]Position, 0, len(candidates))
+	for _, candidate := range candidates {
+		if g.InBounds(candidate) {
+			neighbors = append(neighbors, candidate)
+		}
+	}
+	return neighbors
+}
+
+// FindPath returns the cheapest path using A* with manhattan distance heuristic
+// The goal is always enterable (e.g. tile with a player) if it's in bounds
+func (g *Grid) FindPath(from, to Position) (Path, bool) {
+	if !g.InBounds(to) {
+		return Path{}, false
+	}
+	if from == to {
+		return Path{}, true
+	}
+	heuristic := func(position Position) float32 {
+		return float32(manhattanDistance(position, to)) * g.MinCost
+	}
+	costs := map[Position]float32{from: 0}
+	parents := map[Position]Position{}
+	closed := map[Position]bool{}
+	open := &nodeQueue{}
+	heap.Push(open, node{position: from, priority: heuristic(from)})
+	for open.Len() > 0 {
+		current := heap.Pop(open).(node).position
+		if current == to {
+			return buildPath(from, to, parents, costs[to]), true
+		}
+		if closed[current] {
+			continue
+		}
+		closed[current] = true
+		for _, neighbor := range g.neighbors(current) {
+			if closed[neighbor] {
+				continue
+			}
+			cost, passable := g.Cost(neighbor)
+			if !passable && neighbor != to {
+				continue
+			}
+			newCost := costs[current] + cost
+			if oldCost, found := costs[neighbor]; found && oldCost <= newCost {
+				continue
+			}
+			costs[neighbor] = newCost
+			parents[neighbor] = current
+			heap.Push(open, node{position: neighbor, priority: newCost + heuristic(neighbor)})
+		}
+	}
+	return Path{}, false
+}
+
+// Tree holds cheapest paths from one position to all reachable positions (see Dijkstra)
+type Tree struct {
+	From    Position
+	costs   map[Position]float32
+	parents map[Position]Position
+	// Impassable tiles next to reachable tiles, they are reachable only as goals (see PathTo)
+	goals map[Position]bool
+}
+
+// Dijkstra computes cheapest paths from the position to every reachable tile up to maxCost (0 for unlimited)
+// Impassable tiles are reachable as goals (e.g. tiles with players) but paths don't continue through them
+func (g *Grid) Dijkstra(from Position, maxCost float32) *Tree {
+	tree := &Tree{
+		From:    from,
+		costs:   map[Position]float32{from: 0},
+		parents: map[Position]Position{},
+		goals:   map[Position]bool{},
+	}
+	if maxCost <= 0 {
+		maxCost = math.MaxFloat32
+	}
+	closed := map[Position]bool{}
+	open := &nodeQueue{}
+	heap.Push(open, node{position: from, priority: 0})
+	for open.Len() > 0 {
+		current := heap.Pop(open).(node).position
+		if closed[current] {
+			continue
+		}
+		closed[current] = true
+		if current != from {
+			if _, passable := g.Cost(current); !passable {
+				tree.goals[current] = true
+				continue
+			}
+		}
+		for _, neighbor := range g.neighbors(current) {
+			if closed[neighbor] {
+				continue
+			}
+			cost, _ := g.Cost(neighbor)
+			newCost := tree.costs[current] + cost
+			if newCost > maxCost {
+				continue
+			}
+			if oldCost, found := tree.costs[neighbor]; found && oldCost <= newCost {
+				continue
+			}
+			tree.costs[neighbor] = newCost
+			tree.parents[neighbor] = current
+			heap.Push(open, node{position: neighbor, priority: newCost})
+		}
+	}
+	return tree
+}
+
+// Cost returns the cost of the cheapest path to the position
+// Only positions the monster can stand on are reachable (impassable goals are not, see PathTo)
+func (t *Tree) Cost(to Position) (float32, bool) {
+	if t == nil || t.goals[to] {
+		return 0, false
+	}
+	cost, found := t.costs[to]
+	return cost, found
+}
+
+// PathTo returns the cheapest path to the position, the goal may be impassable (e.g. tile with a player)
+func (t *Tree) PathTo(to Position) (Path, bool) {
+	if t == nil {
+		return Path{}, false
+	}
+	cost, found := t.costs[to]
+	if !found {
+		return Path{}, false
+	}
+	return buildPath(t.From, to, t.parents, cost), true
+}
+
+func buildPath(from, to Position, parents map[Position]Position, cost float32) Path {
+	positions := []Position{}
+	for current := to; current != from; current = parents[current] {
+		positions = append(positions, current)
+	}
+	for i, j := 0, len(positions)-1; i < j; i, j = i+1, j-1 {
+		positions[i], positions[j] = positions[j], positions[i]
+	}
+	return Path{Positions: positions, Cost: cost}
+}
+
+func manhattanDistance(a, b Position) int32 {
+	dx := a.PositionX - b.PositionX
+	if dx < 0 {
+		dx = -dx
+	}
+	dy := a.PositionY - b.PositionY
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx + dy
+}
+
+type node struct {
+	position Position
+	priority float32
+}
+
+// nodeQueue is a min-heap of nodes ordered by priority
+type nodeQueue []node
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].priority < q[j].priority }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(node)) }
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
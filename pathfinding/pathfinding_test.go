@@ -0,0 +1,277 @@
+package pathfinding
+
+import (
+	"testing"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+var testCosts = TileCosts{
+	Base:                1,
+	HarmfulEffect:       5,
+	HarmfulEffectDamage: 1,
+	FriendlyMonster:     2,
+	Door:                1,
+	StairsOrSpawn:       3,
+}
+
+// parseLevel builds a level from rows of tiles:
+// '#' wall, 'D' open door, 'd' closed door, 'f' fire (10 damage), 's' stairs, ' ' missing tile, anything else is free
+func parseLevel(rows ...string) *swagger.DungeonsandtrollsLevel {
+	level := &swagger.DungeonsandtrollsLevel{Width: int32(len(rows[0])), Height: int32(len(rows))}
+	for y, row := range rows {
+		for x, c := range row {
+			if c == ' ' {
+				continue
+			}
+			position := Position{PositionX: int32(x), PositionY: int32(y)}
+			tile := swagger.DungeonsandtrollsMapObjects{Position: &position, IsFree: true}
+			switch c {
+			case '#':
+				tile.IsFree = false
+				tile.IsWall = true
+			case 'D':
+				tile.IsDoor = true
+			case 'd':
+				tile.IsDoor = true
+				tile.IsFree = false
+			case 'f':
+				tile.Effects = []swagger.DungeonsandtrollsEffect{{DamageAmount: 10}}
+			case 's':
+				tile.IsStairs = true
+			}
+			level.Objects = append(level.Objects, tile)
+		}
+	}
+	return level
+}
+
+func pos(x, y int32) Position {
+	return Position{PositionX: x, PositionY: y}
+}
+
+func TestFindPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     []string
+		to       Position
+		found    bool
+		length   int
+		cost     float32
+		avoiding []Position
+	}{
+		{
+			name:   "straight line",
+			rows:   []string{"....."},
+			to:     pos(4, 0),
+			found:  true,
+			length: 4,
+			cost:   4,
+		},
+		{
+			name: "around the wall",
+			rows: []string{
+				"..#..",
+				"..#..",
+				".....",
+			},
+			to:       pos(4, 0),
+			found:    true,
+			length:   8,
+			cost:     8,
+			avoiding: []Position{pos(2, 0), pos(2, 1)},
+		},
+		{
+			name: "walled off",
+			rows: []string{
+				"..#..",
+				"..#..",
+			},
+			to: pos(4, 0),
+		},
+		{
+			name: "closed door blocks",
+			rows: []string{
+				"..d..",
+				"..#..",
+			},
+			to: pos(4, 0),
+		},
+		{
+			name: "open door costs extra",
+			rows: []string{
+				"..D..",
+				"..#..",
+			},
+			to:     pos(4, 0),
+			found:  true,
+			length: 4,
+			cost:   5,
+		},
+		{
+			name: "around fire",
+			rows: []string{
+				"..f..",
+				".....",
+			},
+			to:       pos(4, 0),
+			found:    true,
+			length:   6,
+			cost:     6,
+			avoiding: []Position{pos(2, 0)},
+		},
+		{
+			name: "missing tiles are free",
+			rows: []string{
+				"..  .",
+			},
+			to:     pos(4, 0),
+			found:  true,
+			length: 4,
+			cost:   4,
+		},
+		{
+			name: "impassable goal",
+			rows: []string{
+				"...#",
+			},
+			to:     pos(3, 0),
+			found:  true,
+			length: 3,
+			cost:   3,
+		},
+		{
+			name: "out of bounds",
+			rows: []string{"..."},
+			to:   pos(3, 0),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			grid := LevelGrid(parseLevel(test.rows...), "monster", testCosts)
+			path, found := grid.FindPath(pos(0, 0), test.to)
+			if found != test.found {
+				t.Fatalf("expected found %v, got %v (%v)", test.found, found, path.Positions)
+			}
+			if !found {
+				return
+			}
+			if path.Len() != test.length || path.Cost != test.cost {
+				t.Errorf("expected length %d and cost %v, got %d and %v (%v)", test.length, test.cost, path.Len(), path.Cost, path.Positions)
+			}
+			if path.Positions[path.Len()-1] != test.to {
+				t.Errorf("path doesn't end in the goal: %v", path.Positions)
+			}
+			for _, position := range path.Positions {
+				for _, avoided := range test.avoiding {
+					if position == avoided {
+						t.Errorf("path goes through %v: %v", avoided, path.Positions)
+					}
+				}
+			}
+			// Dijkstra agrees with A*
+			tree := grid.Dijkstra(pos(0, 0), 0)
+			treePath, found := tree.PathTo(test.to)
+			if !found || treePath.Cost != path.Cost {
+				t.Errorf("Dijkstra path cost %v (found %v), A* path cost %v", treePath.Cost, found, path.Cost)
+			}
+		})
+	}
+}
+
+func TestDijkstraImpassableTiles(t *testing.T) {
+	grid := LevelGrid(parseLevel(
+		"...#.",
+		"...d.",
+		"...#.",
+	), "monster", testCosts)
+	tree := grid.Dijkstra(pos(0, 0), 0)
+	for _, tile := range []Position{pos(3, 0), pos(3, 1), pos(3, 2)} {
+		if _, reachable := tree.Cost(tile); reachable {
+			t.Errorf("impassable tile %v is reachable", tile)
+		}
+		// Impassable tiles can still be targeted (e.g. tiles with players)
+		if path, found := tree.PathTo(tile); !found || path.Positions[path.Len()-1] != tile {
+			t.Errorf("no path to impassable goal %v", tile)
+		}
+	}
+	for _, tile := range []Position{pos(4, 0), pos(4, 1)} {
+		if _, reachable := tree.Cost(tile); reachable {
+			t.Errorf("tile %v behind the wall is reachable", tile)
+		}
+		if _, found := tree.PathTo(tile); found {
+			t.Errorf("path through the wall to %v", tile)
+		}
+	}
+	if cost, reachable := tree.Cost(pos(2, 2)); !reachable || cost != 4 {
+		t.Errorf("expected free tile reachable with cost 4, got %v %v", cost, reachable)
+	}
+	if cost, reachable := tree.Cost(pos(0, 0)); !reachable || cost != 0 {
+		t.Errorf("expected start reachable with cost 0, got %v %v", cost, reachable)
+	}
+}
+
+func TestDijkstraMaxCost(t *testing.T) {
+	grid := LevelGrid(parseLevel(
+		"..f.....",
+	), "monster", testCosts)
+	tree := grid.Dijkstra(pos(0, 0), 6)
+	tests := []struct {
+		to        Position
+		cost      float32
+		reachable bool
+	}{
+		{pos(1, 0), 1, true},
+		// Fire costs 1 + 5 + 10
+		{pos(2, 0), 0, false},
+		{pos(3, 0), 0, false},
+	}
+	for _, test := range tests {
+		cost, reachable := tree.Cost(test.to)
+		if reachable != test.reachable || cost != test.cost {
+			t.Errorf("%v: expected cost %v reachable %v, got %v %v", test.to, test.cost, test.reachable, cost, reachable)
+		}
+	}
+	tree = grid.Dijkstra(pos(3, 0), 3)
+	for x, reachable := range []bool{false, false, false, true, true, true, true, false} {
+		if _, found := tree.Cost(pos(int32(x), 0)); found != reachable {
+			t.Errorf("x=%d: expected reachable %v with max cost 3", x, reachable)
+		}
+	}
+	// Bots without a path tree (e.g. out of bounds) have no paths
+	var missing *Tree
+	if _, found := missing.PathTo(pos(0, 0)); found {
+		t.Error("nil tree has paths")
+	}
+}
+
+func TestTileCost(t *testing.T) {
+	friendly := swagger.DungeonsandtrollsMonster{Faction: "monster"}
+	hostile := swagger.DungeonsandtrollsMonster{Faction: "player"}
+	neutral := swagger.DungeonsandtrollsMonster{Faction: "neutral"}
+	tests := []struct {
+		name     string
+		tile     swagger.DungeonsandtrollsMapObjects
+		cost     float32
+		passable bool
+	}{
+		{"free", swagger.DungeonsandtrollsMapObjects{IsFree: true}, 1, true},
+		{"wall", swagger.DungeonsandtrollsMapObjects{IsWall: true}, 1, false},
+		{"open door", swagger.DungeonsandtrollsMapObjects{IsFree: true, IsDoor: true}, 2, true},
+		{"closed door", swagger.DungeonsandtrollsMapObjects{IsDoor: true}, 2, false},
+		{"stairs", swagger.DungeonsandtrollsMapObjects{IsFree: true, IsStairs: true}, 4, true},
+		{"spawn", swagger.DungeonsandtrollsMapObjects{IsFree: true, IsSpawn: true}, 4, true},
+		{"fire", swagger.DungeonsandtrollsMapObjects{IsFree: true, Effects: []swagger.DungeonsandtrollsEffect{{DamageAmount: 3}}}, 9, true},
+		{"poison", swagger.DungeonsandtrollsMapObjects{IsFree: true, Effects: []swagger.DungeonsandtrollsEffect{{Effects: &swagger.DungeonsandtrollsAttributes{Life: -2}}}}, 8, true},
+		{"healing ground", swagger.DungeonsandtrollsMapObjects{IsFree: true, Effects: []swagger.DungeonsandtrollsEffect{{Effects: &swagger.DungeonsandtrollsAttributes{Life: 2}}}}, 1, true},
+		{"friendly monsters", swagger.DungeonsandtrollsMapObjects{IsFree: true, Monsters: []swagger.DungeonsandtrollsMonster{friendly, hostile, neutral}}, 3, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cost, passable := TileCost(&test.tile, "monster", testCosts)
+			if cost != test.cost || passable != test.passable {
+				t.Errorf("expected cost %v passable %v, got %v %v", test.cost, test.passable, cost, passable)
+			}
+		})
+	}
+}
@@ -5,7 +5,8 @@ import (
 	"go.uber.org/zap"
 )
 
-func extractCommandType(cmd *swagger.DungeonsandtrollsCommandsBatch) string {
+// CommandType classifies the command batch by its first set command
+func CommandType(cmd *swagger.DungeonsandtrollsCommandsBatch) string {
 	if cmd.Buy != nil {
 		return "Buy"
 	}
@@ -45,7 +46,7 @@ func extractCommand(cmd *swagger.DungeonsandtrollsCommandsBatch) interface{} {
 
 func Command(logger *zap.SugaredLogger, cmd *swagger.DungeonsandtrollsCommandsBatch) {
 	logger.Infow("Command",
-		zap.String("commandType", extractCommandType(cmd)),
+		zap.String("commandType", CommandType(cmd)),
 		zap.Any("command", extractCommand(cmd)),
 	)
 }
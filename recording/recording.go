@@ -0,0 +1,242 @@
+package recording
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/bot"
+)
+
+// Entry is one recorded tick (one line of the gzipped JSONL file)
+type Entry struct {
+	Tick        int32     `json:"tick"`
+	Time        time.Time `json:"time"`
+	Seed        int64     `json:"seed"`
+	Environment string    `json:"environment"`
+
+	GameState *swagger.DungeonsandtrollsGameState               `json:"gameState"`
+	Commands  map[string]swagger.DungeonsandtrollsCommandsBatch `json:"commands"`
+	// Missing in recordings made before the settings were recorded
+	Settings *Settings `json:"settings,omitempty"`
+}
+
+// Settings the dispatcher ran the tick with, replay applies them so that the bots decide the same way
+type Settings struct {
+	Workers  int                 `json:"workers"`
+	Tuning   *bot.Tuning         `json:"tuning"`
+	Profiles *bot.ConfigProfiles `json:"profiles"`
+	// Monsters handled by RunDegraded because they were out of time
+	Degraded []string `json:"degraded"`
+}
+
+// DispatcherSettings returns the settings of the last tick handled by the dispatcher
+func DispatcherSettings(d *bot.BotDispatcher) Settings {
+	return Settings{
+		Workers:  d.Workers,
+		Tuning:   d.Tuning,
+		Profiles: d.Profiles,
+		Degraded: d.LastTickStats.DegradedMonsters,
+	}
+}
+
+// Apply makes the dispatcher run the next tick with the recorded settings
+func (s Settings) Apply(d *bot.BotDispatcher) {
+	// Tuning and profiles are not reloaded from files
+	d.TuningFile = nil
+	d.Tuning = s.Tuning
+	d.Profiles = s.Profiles
+	d.Workers = s.Workers
+	d.ForceDegraded = map[string]bool{}
+	for _, monsterId := range s.Degraded {
+		d.ForceDegraded[monsterId] = true
+	}
+}
+
+// Writer records ticks to a gzipped JSONL file
+// Commands can be recorded from multiple goroutines
+type Writer struct {
+	lock    sync.Mutex
+	file    *os.File
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+	current *Entry
+}
+
+func Create(path string) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating recording: %w", err)
+	}
+	gz := gzip.NewWriter(file)
+	return &Writer{
+		file:    file,
+		gzip:    gz,
+		encoder: json.NewEncoder(gz),
+	}, nil
+}
+
+// StartTick starts a new entry (an unfinished previous entry is written first)
+func (w *Writer) StartTick(gameState *swagger.DungeonsandtrollsGameState, tickStartTime time.Time, seed int64, environment string) error {
+	if err := w.FinishTick(); err != nil {
+		return err
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.current = &Entry{
+		Tick:        gameState.Tick,
+		Time:        tickStartTime,
+		Seed:        seed,
+		Environment: environment,
+		GameState:   gameState,
+		Commands:    map[string]swagger.DungeonsandtrollsCommandsBatch{},
+	}
+	return nil
+}
+
+func (w *Writer) RecordMonsterCommand(monsterId string, cmd swagger.DungeonsandtrollsCommandsBatch) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.current == nil {
+		return
+	}
+	w.current.Commands[monsterId] = cmd
+}
+
+// RecordSettings stores the settings of the current tick (see DispatcherSettings)
+func (w *Writer) RecordSettings(settings Settings) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.current == nil {
+		return
+	}
+	w.current.Settings = &settings
+}
+
+// FinishTick writes the current entry and flushes it so the recording survives crashes
+func (w *Writer) FinishTick() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.current == nil {
+		return nil
+	}
+	entry := w.current
+	w.current = nil
+	if err := w.encoder.Encode(entry); err != nil {
+		return fmt.Errorf("writing recording: %w", err)
+	}
+	if err := w.gzip.Flush(); err != nil {
+		return fmt.Errorf("flushing recording: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) Close() error {
+	if err := w.FinishTick(); err != nil {
+		return err
+	}
+	if err := w.gzip.Close(); err != nil {
+		return fmt.Errorf("closing recording: %w", err)
+	}
+	return w.file.Close()
+}
+
+// Reader reads entries from a recording written by Writer
+type Reader struct {
+	file    *os.File
+	gzip    *gzip.Reader
+	decoder *json.Decoder
+}
+
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening recording: %w", err)
+	}
+	gz, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("opening recording: %w", err)
+	}
+	return &Reader{
+		file:    file,
+		gzip:    gz,
+		decoder: json.NewDecoder(gz),
+	}, nil
+}
+
+// Next returns io.EOF after the last entry
+// A truncated last entry (e.g. process killed while writing) is reported as io.EOF too
+func (r *Reader) Next() (*Entry, error) {
+	entry := Entry{}
+	err := r.decoder.Decode(&entry)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading recording: %w", err)
+	}
+	return &entry, nil
+}
+
+func (r *Reader) Close() error {
+	r.gzip.Close()
+	return r.file.Close()
+}
+
+// Difference between recorded and replayed command for one monster
+// Missing commands are nil
+type Difference struct {
+	MonsterId string                                  `json:"monsterId"`
+	Recorded  *swagger.DungeonsandtrollsCommandsBatch `json:"recorded"`
+	Replayed  *swagger.DungeonsandtrollsCommandsBatch `json:"replayed"`
+}
+
+// Diff compares commands (sorted by monster ID)
+// Yells are ignored because they are only sent in dev environment
+func Diff(recorded, replayed map[string]swagger.DungeonsandtrollsCommandsBatch) []Difference {
+	ids := map[string]bool{}
+	for id := range recorded {
+		ids[id] = true
+	}
+	for id := range replayed {
+		ids[id] = true
+	}
+	sortedIds := []string{}
+	for id := range ids {
+		sortedIds = append(sortedIds, id)
+	}
+	sort.Strings(sortedIds)
+
+	empty := swagger.DungeonsandtrollsCommandsBatch{}
+	diffs := []Difference{}
+	for _, id := range sortedIds {
+		rec, recFound := recorded[id]
+		rep, repFound := replayed[id]
+		rec.Yell = nil
+		rep.Yell = nil
+		recFound = recFound && !reflect.DeepEqual(rec, empty)
+		repFound = repFound && !reflect.DeepEqual(rep, empty)
+		if recFound == repFound && (!recFound || reflect.DeepEqual(rec, rep)) {
+			continue
+		}
+		diff := Difference{MonsterId: id}
+		if recFound {
+			diff.Recorded = &rec
+		}
+		if repFound {
+			diff.Replayed = &rep
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
@@ -0,0 +1,256 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/bot"
+	"go.uber.org/zap"
+)
+
+func move(x, y int32) swagger.DungeonsandtrollsCommandsBatch {
+	return swagger.DungeonsandtrollsCommandsBatch{Move: &swagger.DungeonsandtrollsPosition{PositionX: x, PositionY: y}}
+}
+
+func skill(skillId, targetId string) swagger.DungeonsandtrollsCommandsBatch {
+	return swagger.DungeonsandtrollsCommandsBatch{Skill: &swagger.DungeonsandtrollsSkillUse{SkillId: skillId, TargetId: targetId}}
+}
+
+func TestDiff(t *testing.T) {
+	yell := skill("slash", "player-1")
+	yell.Yell = &swagger.DungeonsandtrollsMessage{Text: "I'm coming for you"}
+	tests := []struct {
+		name     string
+		recorded map[string]swagger.DungeonsandtrollsCommandsBatch
+		replayed map[string]swagger.DungeonsandtrollsCommandsBatch
+		diffs    []string
+	}{
+		{
+			name:     "same commands",
+			recorded: map[string]swagger.DungeonsandtrollsCommandsBatch{"m1": move(1, 2), "m2": skill("slash", "player-1")},
+			replayed: map[string]swagger.DungeonsandtrollsCommandsBatch{"m1": move(1, 2), "m2": skill("slash", "player-1")},
+		},
+		{
+			name:     "yells are ignored",
+			recorded: map[string]swagger.DungeonsandtrollsCommandsBatch{"m1": yell},
+			replayed: map[string]swagger.DungeonsandtrollsCommandsBatch{"m1": skill("slash", "player-1")},
+		},
+		{
+			name:     "empty command is no command",
+			recorded: map[string]swagger.DungeonsandtrollsCommandsBatch{"m1": {}},
+			replayed: map[string]swagger.DungeonsandtrollsCommandsBatch{},
+		},
+		{
+			name:     "different commands sorted by monster",
+			recorded: map[string]swagger.DungeonsandtrollsCommandsBatch{"m2": move(1, 2), "m1": skill("slash", "player-1"), "m3": move(3, 3)},
+			replayed: map[string]swagger.DungeonsandtrollsCommandsBatch{"m2": move(2, 2), "m1": skill("slash", "player-2"), "m3": move(3, 3)},
+			diffs:    []string{"m1", "m2"},
+		},
+		{
+			name:     "missing commands",
+			recorded: map[string]swagger.DungeonsandtrollsCommandsBatch{"m1": move(1, 2)},
+			replayed: map[string]swagger.DungeonsandtrollsCommandsBatch{"m2": move(1, 2)},
+			diffs:    []string{"m1", "m2"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diffs := Diff(test.recorded, test.replayed)
+			if len(diffs) != len(test.diffs) {
+				t.Fatalf("expected diffs for %v, got %+v", test.diffs, diffs)
+			}
+			for i, diff := range diffs {
+				if diff.MonsterId != test.diffs[i] {
+					t.Errorf("expected diff %d for %s, got %s", i, test.diffs[i], diff.MonsterId)
+				}
+				_, recorded := test.recorded[diff.MonsterId]
+				_, replayed := test.replayed[diff.MonsterId]
+				if (diff.Recorded != nil) != recorded || (diff.Replayed != nil) != replayed {
+					t.Errorf("%s: expected recorded %v and replayed %v, got %+v", diff.MonsterId, recorded, replayed, diff)
+				}
+			}
+		})
+	}
+}
+
+func loadArena(t *testing.T) *swagger.DungeonsandtrollsGameState {
+	t.Helper()
+	data, err := os.ReadFile("../fixtures/arena.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := swagger.DungeonsandtrollsGameState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	return &state
+}
+
+type nopSender struct{}
+
+func (nopSender) SendMonsterCommands(cmds swagger.DungeonsandtrollsCommandsForMonsters, logger *zap.SugaredLogger) error {
+	return nil
+}
+
+// commandSender keeps the commands of the last tick
+type commandSender struct {
+	commands map[string]swagger.DungeonsandtrollsCommandsBatch
+}
+
+func (s *commandSender) SendMonsterCommands(cmds swagger.DungeonsandtrollsCommandsForMonsters, logger *zap.SugaredLogger) error {
+	for monsterId, cmd := range cmds.Commands {
+		s.commands[monsterId] = cmd
+	}
+	return nil
+}
+
+func newDispatcher() *bot.BotDispatcher {
+	d := bot.NewBotDispatcher(nil, context.Background(), zap.NewNop().Sugar(), "test")
+	d.Sender = nopSender{}
+	d.CommandTTL = 0
+	d.Workers = 1
+	d.PlannerExpansions = bot.DeterministicPlannerExpansions
+	return d
+}
+
+// record runs the bots with settings different from the defaults and records the ticks
+func record(t *testing.T, path string, ticks int32) {
+	t.Helper()
+	writer, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newDispatcher()
+	d.Recorder = writer
+	tuning := bot.DefaultTuning()
+	tuning.Behavior.EngageDistance = 1
+	d.Tuning = &tuning
+	profiles, err := bot.ParseConfigProfiles([]byte(`{"healer": {"fleeVitals": 1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Profiles = profiles
+	for tick := int32(1); tick <= ticks; tick++ {
+		state := loadArena(t)
+		state.Tick = tick
+		d.Seed = int64(tick)
+		// The monsters are out of time in the first tick
+		d.MinMonsterBudget = bot.DefaultMinMonsterBudget
+		if tick == 1 {
+			d.MinMonsterBudget = time.Hour
+		}
+		if err := writer.StartTick(state, time.Now(), d.Seed, "test"); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.HandleTick(state, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		writer.RecordSettings(DispatcherSettings(d))
+		if tick == 1 && len(d.LastTickStats.DegradedMonsters) != 2 {
+			t.Fatalf("expected both monsters degraded, got %v", d.LastTickStats.DegradedMonsters)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// replay returns the number of ticks with commands different from the recorded ones
+func replay(t *testing.T, path string, applySettings bool) (int, int) {
+	t.Helper()
+	reader, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	d := newDispatcher()
+	d.TickDeadline = 0
+	sender := &commandSender{}
+	d.Sender = sender
+	ticks, ticksWithDiffs := 0, 0
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Settings == nil {
+			t.Fatalf("tick %d recorded without settings", entry.Tick)
+		}
+		if applySettings {
+			entry.Settings.Apply(d)
+		}
+		d.Seed = entry.Seed
+		sender.commands = map[string]swagger.DungeonsandtrollsCommandsBatch{}
+		if err := d.HandleTick(entry.GameState, entry.Time); err != nil {
+			t.Fatal(err)
+		}
+		if diffs := Diff(entry.Commands, sender.commands); len(diffs) > 0 {
+			ticksWithDiffs++
+		}
+		ticks++
+	}
+	return ticks, ticksWithDiffs
+}
+
+func TestReplayAppliesRecordedSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ticks.jsonl.gz")
+	record(t, path, 3)
+	ticks, ticksWithDiffs := replay(t, path, true)
+	if ticks != 3 || ticksWithDiffs != 0 {
+		t.Errorf("expected 3 ticks replayed without diffs, got %d ticks and %d with diffs", ticks, ticksWithDiffs)
+	}
+	// Default settings change the decisions
+	if _, ticksWithDiffs := replay(t, path, false); ticksWithDiffs == 0 {
+		t.Error("replay with default settings reproduced the recording")
+	}
+}
+
+func TestReaderStopsAtTruncatedEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ticks.jsonl.gz")
+	writer, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for tick := int32(1); tick <= 2; tick++ {
+		if err := writer.StartTick(&swagger.DungeonsandtrollsGameState{Tick: tick}, time.Now(), 42, "test"); err != nil {
+			t.Fatal(err)
+		}
+		writer.RecordMonsterCommand("m1", move(tick, tick))
+	}
+	// The second tick is written on Close
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Crash while writing the last entry
+	if err := os.Truncate(path, info.Size()-30); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	entry, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Tick != 1 || entry.Seed != 42 || entry.Commands["m1"].Move.PositionX != 1 || entry.Settings != nil {
+		t.Errorf("unexpected first entry %+v", entry)
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("expected EOF for the truncated entry, got %v", err)
+	}
+}
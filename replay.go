@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/bot"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/recording"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/simulator"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// replay feeds a recording (see DNT_RECORD_FILE) through the bots and diffs produced commands against the recorded ones
+// USAGE: ./dungeons-and-trolls-monsters-ai replay RECORDING_JSONL_GZ [MAX_TICKS]
+func replay(logger *zap.Logger, args []string) {
+	if len(args) < 1 {
+		logger.Fatal("USAGE: ./dungeons-and-trolls-monsters-ai replay RECORDING_JSONL_GZ [MAX_TICKS]")
+	}
+	maxTicks := -1
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			logger.Fatal("Invalid number of ticks", zap.Error(err))
+		}
+		maxTicks = n
+	}
+	reader, err := recording.Open(args[0])
+	if err != nil {
+		logger.Fatal("Can't open recording", zap.Error(err))
+	}
+	defer reader.Close()
+
+	var botDispatcher *bot.BotDispatcher
+	collector := simulator.NewCollector()
+	ticks := 0
+	ticksWithDiffs := 0
+	for maxTicks < 0 || ticks < maxTicks {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logger.Fatal("Can't read recording", zap.Error(err))
+		}
+		if botDispatcher == nil {
+			// Bots log a lot on info level, keep only warnings
+			botLogger := logger.WithOptions(zap.IncreaseLevel(zapcore.WarnLevel))
+			botDispatcher = bot.NewBotDispatcher(nil, context.Background(), botLogger.Sugar(), entry.Environment)
+			configureBotDispatcher(botDispatcher, logger)
+			botDispatcher.Sender = collector
+			// Recorded tick start times are in the past
+			botDispatcher.TickDeadline = 0
+			botDispatcher.CommandTTL = 0
+			botDispatcher.PlannerExpansions = bot.DeterministicPlannerExpansions
+			// Recordings without settings run the bots one by one
+			botDispatcher.Workers = 1
+			if entry.Settings == nil {
+				logger.Warn("Recording without dispatcher settings, using the current tuning and profiles")
+			}
+		}
+		collector.Reset()
+		botDispatcher.Seed = entry.Seed
+		if entry.Settings != nil {
+			entry.Settings.Apply(botDispatcher)
+		}
+		if err := botDispatcher.HandleTick(entry.GameState, entry.Time); err != nil {
+			logger.Error("Error when running monster AI",
+				zap.Int32("tick", entry.Tick),
+				zap.Error(err),
+			)
+		}
+		diffs := recording.Diff(entry.Commands, collector.Commands().Commands)
+		if len(diffs) > 0 {
+			ticksWithDiffs++
+			logger.Warn("Replayed commands differ from recording",
+				zap.Int32("tick", entry.Tick),
+				zap.Any("diffs", diffs),
+			)
+		}
+		ticks++
+	}
+	logger.Info("Replay done",
+		zap.Int("ticks", ticks),
+		zap.Int("ticksWithDiffs", ticksWithDiffs),
+	)
+}
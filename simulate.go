@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/bot"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/simulator"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// simulate runs the monster AI against scripted players in the offline simulator
+// USAGE: ./dungeons-and-trolls-monsters-ai simulate GAME_STATE_JSON [TICKS] [PLAYER_SCRIPT] [SEED]
+func simulate(logger *zap.Logger, args []string) {
+	if len(args) < 1 {
+		logger.Fatal("USAGE: ./dungeons-and-trolls-monsters-ai simulate GAME_STATE_JSON [TICKS] [idle|aggressive|fleeing] [SEED]")
+	}
+	ticks := 100
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			logger.Fatal("Invalid number of ticks", zap.Error(err))
+		}
+		ticks = n
+	}
+	script := simulator.AggressivePlayer
+	if len(args) > 2 {
+		switch args[2] {
+		case "idle":
+			script = simulator.IdlePlayer
+		case "aggressive":
+			script = simulator.AggressivePlayer
+		case "fleeing":
+			script = simulator.FleeingPlayer
+		default:
+			logger.Fatal("Unknown player script", zap.String("script", args[2]))
+		}
+	}
+	seed := int64(1)
+	if len(args) > 3 {
+		n, err := strconv.ParseInt(args[3], 10, 64)
+		if err != nil {
+			logger.Fatal("Invalid seed", zap.Error(err))
+		}
+		seed = n
+	}
+
+	state, err := simulator.LoadGameState(args[0])
+	if err != nil {
+		logger.Fatal("Can't load game state", zap.Error(err))
+	}
+	sim, err := simulator.New(state, seed, logger.Sugar())
+	if err != nil {
+		logger.Fatal("Can't create simulator", zap.Error(err))
+	}
+	sim.DefaultPlayerScript = script
+
+	// Bots log a lot on info level, keep only warnings for long simulations
+	botLogger := logger.WithOptions(zap.IncreaseLevel(zapcore.WarnLevel))
+	botDispatcher := bot.NewBotDispatcher(nil, context.Background(), botLogger.Sugar(), "simulator")
+	configureBotDispatcher(botDispatcher, logger)
+	collector := simulator.NewCollector()
+	botDispatcher.Sender = collector
+	// The simulator waits for all commands
+	botDispatcher.CommandTTL = 0
+	botDispatcher.Seed = seed
+
+	if err := sim.Run(botDispatcher, collector, ticks); err != nil {
+		logger.Fatal("Simulation failed", zap.Error(err))
+	}
+	logger.Info("Simulation done",
+		zap.Int32("tick", sim.State.Tick),
+		zap.Int("aliveMonsters", sim.AliveMonsters()),
+		zap.Int("alivePlayers", sim.AlivePlayers()),
+	)
+}
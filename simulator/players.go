@@ -0,0 +1,96 @@
+package simulator
+
+import (
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// PlayerScript decides commands for a scripted player (nil -> do nothing)
+type PlayerScript func(s *Simulator, player Character) *swagger.DungeonsandtrollsCommandsBatch
+
+// IdlePlayer never does anything (useful as a training dummy)
+func IdlePlayer(s *Simulator, player Character) *swagger.DungeonsandtrollsCommandsBatch {
+	return nil
+}
+
+// AggressivePlayer attacks the closest monster with the first usable damage skill
+// and walks towards it when nothing is in range
+func AggressivePlayer(s *Simulator, player Character) *swagger.DungeonsandtrollsCommandsBatch {
+	self := s.actorById(player.Id)
+	if self == nil {
+		return nil
+	}
+	target := s.closestHostile(self)
+	if target == nil {
+		return nil
+	}
+	attrs := *self.attributes()
+	for _, skill := range self.skills() {
+		fillSkill(&skill)
+		if skill.Flags.Passive || attributesValue(attrs, skill.DamageAmount) <= 0 || !hasResources(attrs, *skill.Cost) {
+			continue
+		}
+		if manhattanDistance(self.position, target.position) > int32(attributesValue(attrs, skill.Range_)) {
+			continue
+		}
+		if skill.Flags.RequiresLineOfSight && !s.lineOfSight(self.level, self.position, target.position) {
+			continue
+		}
+		use := &swagger.DungeonsandtrollsSkillUse{SkillId: skill.Id}
+		switch *skill.Target {
+		case swagger.CHARACTER_SkillTarget:
+			use.TargetId = target.id
+		case swagger.POSITION_SkillTarget:
+			position := target.position
+			use.Position = &position
+		}
+		return &swagger.DungeonsandtrollsCommandsBatch{Skill: use}
+	}
+	position := target.position
+	return &swagger.DungeonsandtrollsCommandsBatch{Move: &position}
+}
+
+// FleeingPlayer keeps running away from the closest monster
+func FleeingPlayer(s *Simulator, player Character) *swagger.DungeonsandtrollsCommandsBatch {
+	self := s.actorById(player.Id)
+	if self == nil {
+		return nil
+	}
+	threat := s.closestHostile(self)
+	if threat == nil {
+		return nil
+	}
+	best := self.position
+	bestDistance := manhattanDistance(self.position, threat.position)
+	for _, neighbor := range neighbors(self.position) {
+		if !s.isFree(self.level, neighbor) {
+			continue
+		}
+		if distance := manhattanDistance(neighbor, threat.position); distance > bestDistance {
+			best = neighbor
+			bestDistance = distance
+		}
+	}
+	if best == self.position {
+		return nil
+	}
+	return &swagger.DungeonsandtrollsCommandsBatch{Move: &best}
+}
+
+func (s *Simulator) closestHostile(self *actor) *actor {
+	var closest *actor
+	closestDistance := int32(-1)
+	for _, a := range s.actors {
+		if a == self || !a.alive() || a.level != self.level || a.faction() == "neutral" || a.faction() == self.faction() {
+			continue
+		}
+		if self.isPlayer() && a.isPlayer() {
+			continue
+		}
+		distance := manhattanDistance(self.position, a.position)
+		if closest == nil || distance < closestDistance {
+			closest = a
+			closestDistance = distance
+		}
+	}
+	return closest
+}
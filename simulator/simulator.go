@@ -0,0 +1,257 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"go.uber.org/zap"
+)
+
+// Simulator is a simplified local model of the D&T server
+// It applies monster and scripted player commands to a game state and advances ticks
+//
+// Simplifications compared to the server:
+//   - characters move one tile per tick along the shortest path (4-neighbourhood)
+//   - skill attributes with duration are applied every tick as effects (buffs are reverted when they expire)
+//   - stunned characters skip one tick and are immune to stun for the next one
+//   - there is no shop, items, fog of war, or respawning
+type Simulator struct {
+	State   *swagger.DungeonsandtrollsGameState
+	Rand    *rand.Rand
+	Players map[string]PlayerScript
+	// Default script for players without an entry in Players (nil -> players idle)
+	DefaultPlayerScript PlayerScript
+
+	Logger *zap.SugaredLogger
+
+	actors []*actor
+	events []swagger.DungeonsandtrollsEvent
+}
+
+// TickHandler is implemented by bot.BotDispatcher
+type TickHandler interface {
+	HandleTick(gameState *swagger.DungeonsandtrollsGameState, tickStartTime time.Time) error
+}
+
+// New deep copies the game state so the original is never modified
+func New(state *swagger.DungeonsandtrollsGameState, seed int64, logger *zap.SugaredLogger) (*Simulator, error) {
+	stateCopy, err := copyGameState(state)
+	if err != nil {
+		return nil, err
+	}
+	if stateCopy.Map_ == nil {
+		stateCopy.Map_ = &swagger.DungeonsandtrollsMap{}
+	}
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+	return &Simulator{
+		State:   stateCopy,
+		Rand:    rand.New(rand.NewSource(seed)),
+		Players: map[string]PlayerScript{},
+		Logger:  logger,
+	}, nil
+}
+
+func LoadGameState(path string) (*swagger.DungeonsandtrollsGameState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading game state: %w", err)
+	}
+	state := swagger.DungeonsandtrollsGameState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parsing game state: %w", err)
+	}
+	return &state, nil
+}
+
+func copyGameState(state *swagger.DungeonsandtrollsGameState) (*swagger.DungeonsandtrollsGameState, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("copying game state: %w", err)
+	}
+	stateCopy := swagger.DungeonsandtrollsGameState{}
+	if err := json.Unmarshal(data, &stateCopy); err != nil {
+		return nil, fmt.Errorf("copying game state: %w", err)
+	}
+	return &stateCopy, nil
+}
+
+// Snapshot returns a deep copy of the current state (safe to hand to the bots)
+func (s *Simulator) Snapshot() *swagger.DungeonsandtrollsGameState {
+	state, err := copyGameState(s.State)
+	if err != nil {
+		s.Logger.Errorw("Can't snapshot simulator state",
+			zap.Error(err),
+		)
+		return s.State
+	}
+	return state
+}
+
+// Step applies commands from monsters and scripted players and advances the state by one tick
+func (s *Simulator) Step(monsterCommands swagger.DungeonsandtrollsCommandsForMonsters) {
+	s.events = []swagger.DungeonsandtrollsEvent{}
+	s.extractActors()
+
+	commands := map[string]swagger.DungeonsandtrollsCommandsBatch{}
+	for id, cmd := range monsterCommands.Commands {
+		commands[id] = cmd
+	}
+	for _, a := range s.actors {
+		if !a.isPlayer() {
+			continue
+		}
+		script := s.Players[a.id]
+		if script == nil {
+			script = s.DefaultPlayerScript
+		}
+		if script == nil {
+			continue
+		}
+		if cmd := script(s, a.view()); cmd != nil {
+			commands[a.id] = *cmd
+		}
+	}
+
+	// Actors act in random order (the server doesn't guarantee any order either)
+	order := s.Rand.Perm(len(s.actors))
+	for _, i := range order {
+		a := s.actors[i]
+		if !a.alive() {
+			continue
+		}
+		stun := a.stun()
+		if stun.IsStunned {
+			stun.IsStunned = false
+			stun.IsImmune = true
+			continue
+		}
+		stun.IsImmune = false
+		cmd, found := commands[a.id]
+		if !found {
+			continue
+		}
+		if cmd.Yell != nil && cmd.Yell.Text != "" {
+			s.addEvent(swagger.MESSAGE_DungeonsandtrollsEventType, a, cmd.Yell.Text)
+		}
+		if cmd.Skill != nil {
+			s.useSkill(a, *cmd.Skill)
+		} else if cmd.Move != nil {
+			s.moveTowards(a, *cmd.Move)
+		}
+	}
+
+	s.applyEffects()
+	for _, a := range s.actors {
+		a.afterTick()
+		if !a.alive() && !a.dead {
+			a.dead = true
+			s.addEvent(swagger.DEATH_DungeonsandtrollsEventType, a, a.name()+" died")
+		}
+	}
+	s.injectActors()
+	s.State.Events = s.events
+	s.State.Tick++
+}
+
+// Run feeds the state to the handler, collects the commands it sends, and steps the simulation
+// The handler must send commands through the collector (e.g. bot.BotDispatcher with Sender set to it)
+func (s *Simulator) Run(handler TickHandler, collector *Collector, ticks int) error {
+	for i := 0; i < ticks; i++ {
+		collector.Reset()
+		if err := handler.HandleTick(s.Snapshot(), time.Now()); err != nil {
+			return fmt.Errorf("tick %d: %w", s.State.Tick, err)
+		}
+		s.Step(collector.Commands())
+		if s.AliveMonsters() == 0 || s.AlivePlayers() == 0 {
+			s.Logger.Infow("Simulation finished early",
+				"tick", s.State.Tick,
+				"aliveMonsters", s.AliveMonsters(),
+				"alivePlayers", s.AlivePlayers(),
+			)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) AliveMonsters() int {
+	return s.countCharacters(false)
+}
+
+func (s *Simulator) AlivePlayers() int {
+	return s.countCharacters(true)
+}
+
+func (s *Simulator) countCharacters(players bool) int {
+	count := 0
+	for _, level := range s.State.Map_.Levels {
+		for _, object := range level.Objects {
+			if players {
+				count += len(object.Players)
+				continue
+			}
+			for _, monster := range object.Monsters {
+				if monster.Faction != "neutral" {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+func (s *Simulator) addEvent(eventType swagger.DungeonsandtrollsEventType, a *actor, message string) {
+	coords := a.coordinates()
+	s.events = append(s.events, swagger.DungeonsandtrollsEvent{
+		Type_:       &eventType,
+		Message:     message,
+		Coordinates: &coords,
+		PlayerId:    a.id,
+	})
+}
+
+// Collector gathers commands sent by the dispatcher (implements bot.CommandSender)
+type Collector struct {
+	lock     sync.Mutex
+	commands map[string]swagger.DungeonsandtrollsCommandsBatch
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		commands: map[string]swagger.DungeonsandtrollsCommandsBatch{},
+	}
+}
+
+func (c *Collector) SendMonsterCommands(cmds swagger.DungeonsandtrollsCommandsForMonsters, logger *zap.SugaredLogger) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for id, cmd := range cmds.Commands {
+		c.commands[id] = cmd
+	}
+	return nil
+}
+
+func (c *Collector) Commands() swagger.DungeonsandtrollsCommandsForMonsters {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	commands := swagger.DungeonsandtrollsCommandsForMonsters{
+		Commands: map[string]swagger.DungeonsandtrollsCommandsBatch{},
+	}
+	for id, cmd := range c.commands {
+		commands.Commands[id] = cmd
+	}
+	return commands
+}
+
+func (c *Collector) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.commands = map[string]swagger.DungeonsandtrollsCommandsBatch{}
+}
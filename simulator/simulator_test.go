@@ -0,0 +1,189 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/bot"
+	"go.uber.org/zap"
+)
+
+// recordingHandler keeps the monster commands of every tick
+type recordingHandler struct {
+	dispatcher *bot.BotDispatcher
+	collector  *Collector
+	commands   []swagger.DungeonsandtrollsCommandsForMonsters
+}
+
+func (h *recordingHandler) HandleTick(gameState *swagger.DungeonsandtrollsGameState, tickStartTime time.Time) error {
+	err := h.dispatcher.HandleTick(gameState, tickStartTime)
+	h.commands = append(h.commands, h.collector.Commands())
+	return err
+}
+
+func loadArena(t *testing.T) *swagger.DungeonsandtrollsGameState {
+	t.Helper()
+	state, err := LoadGameState("../fixtures/arena.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func runArena(t *testing.T, script PlayerScript, seed int64, ticks int) (*Simulator, *recordingHandler) {
+	t.Helper()
+	return runState(t, loadArena(t), script, seed, ticks)
+}
+
+func runState(t *testing.T, state *swagger.DungeonsandtrollsGameState, script PlayerScript, seed int64, ticks int) (*Simulator, *recordingHandler) {
+	t.Helper()
+	sim, err := New(state, seed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.DefaultPlayerScript = script
+	collector := NewCollector()
+	dispatcher := bot.NewBotDispatcher(nil, context.Background(), zap.NewNop().Sugar(), "simulator")
+	dispatcher.Sender = collector
+	dispatcher.Seed = seed
+	// Decisions must not depend on the wall clock or on the order of the workers
+	dispatcher.TickDeadline = 0
+	dispatcher.CommandTTL = 0
+	dispatcher.Workers = 1
+	dispatcher.PlannerExpansions = bot.DeterministicPlannerExpansions
+	handler := &recordingHandler{dispatcher: dispatcher, collector: collector}
+	if err := sim.Run(handler, collector, ticks); err != nil {
+		t.Fatal(err)
+	}
+	return sim, handler
+}
+
+func playerLife(sim *Simulator) (float32, bool) {
+	for _, level := range sim.State.Map_.Levels {
+		for _, object := range level.Objects {
+			for _, player := range object.Players {
+				if player.Id == "player-1" {
+					return player.Attributes.Life, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestArenaMonstersEngageIdlePlayer(t *testing.T) {
+	sim, handler := runArena(t, IdlePlayer, 1, 30)
+	skillsOnPlayer := 0
+	for _, tick := range handler.commands {
+		for _, cmd := range tick.Commands {
+			if cmd.Skill != nil && cmd.Skill.TargetId == "player-1" {
+				skillsOnPlayer++
+			}
+		}
+	}
+	if skillsOnPlayer == 0 {
+		t.Error("monsters never used a skill on the player")
+	}
+	// Dead players are removed from the map
+	if life, found := playerLife(sim); found && life >= 100 {
+		t.Errorf("player took no damage in %d ticks (life %v)", len(handler.commands), life)
+	}
+	if sim.AliveMonsters() != 2 {
+		t.Errorf("idle player killed monsters (%d alive)", sim.AliveMonsters())
+	}
+}
+
+func TestArenaIsReproducibleWithSeed(t *testing.T) {
+	_, first := runArena(t, AggressivePlayer, 7, 20)
+	_, second := runArena(t, AggressivePlayer, 7, 20)
+	firstJSON, _ := json.Marshal(first.commands)
+	secondJSON, _ := json.Marshal(second.commands)
+	if string(firstJSON) != string(secondJSON) {
+		t.Errorf("same seed produced different commands:\n%s\n%s", firstJSON, secondJSON)
+	}
+}
+
+// Level numbers are not indexes in Map_.Levels (the arena is level 1 and the other level has no monsters)
+func TestLevelNumbersAreNotIndexes(t *testing.T) {
+	state := loadArena(t)
+	state.Map_.Levels = append(state.Map_.Levels, swagger.DungeonsandtrollsLevel{Level: 4, Width: 3, Height: 3})
+	sim, handler := runState(t, state, IdlePlayer, 1, 10)
+	skillsOnPlayer := 0
+	for _, tick := range handler.commands {
+		for _, cmd := range tick.Commands {
+			if cmd.Skill != nil && cmd.Skill.TargetId == "player-1" {
+				skillsOnPlayer++
+			}
+		}
+	}
+	if skillsOnPlayer == 0 {
+		t.Error("monsters never used a skill on the player")
+	}
+	for _, event := range sim.State.Events {
+		if event.Coordinates == nil || event.Coordinates.Level != 1 {
+			t.Errorf("expected event on level 1, got %+v", event)
+		}
+	}
+	for _, object := range sim.State.Map_.Levels[0].Objects {
+		for _, player := range object.Players {
+			if player.Coordinates == nil || player.Coordinates.Level != 1 {
+				t.Errorf("expected player on level 1, got %+v", player.Coordinates)
+			}
+		}
+	}
+	if objects := sim.State.Map_.Levels[1].Objects; len(objects) != 0 {
+		t.Errorf("characters moved to the other level: %+v", objects)
+	}
+}
+
+func TestCasterGroundEffect(t *testing.T) {
+	position := swagger.DungeonsandtrollsPosition{PositionX: 1, PositionY: 1}
+	skill := swagger.DungeonsandtrollsSkill{
+		Id:       "aura",
+		Name:     "Aura",
+		Duration: &swagger.DungeonsandtrollsAttributes{Constant: 3},
+		CasterEffects: &swagger.DungeonsandtrollsSkillEffect{
+			Attributes: &swagger.DungeonsandtrollsSkillAttributes{Life: &swagger.DungeonsandtrollsAttributes{Constant: 2}},
+			Flags:      &swagger.DungeonsandtrollsSkillSpecificFlags{GroundEffect: true},
+		},
+		TargetEffects: &swagger.DungeonsandtrollsSkillEffect{
+			Attributes: &swagger.DungeonsandtrollsSkillAttributes{Life: &swagger.DungeonsandtrollsAttributes{Constant: -5}},
+		},
+	}
+	state := &swagger.DungeonsandtrollsGameState{
+		Map_: &swagger.DungeonsandtrollsMap{Levels: []swagger.DungeonsandtrollsLevel{{
+			Level:  2,
+			Width:  3,
+			Height: 3,
+			Objects: []swagger.DungeonsandtrollsMapObjects{{
+				Position: &position,
+				IsFree:   true,
+				Monsters: []swagger.DungeonsandtrollsMonster{{
+					Id:            "monster-1",
+					Faction:       "monster",
+					Attributes:    &swagger.DungeonsandtrollsAttributes{Life: 10},
+					EquippedItems: []swagger.DungeonsandtrollsItem{{Skills: []swagger.DungeonsandtrollsSkill{skill}}},
+				}},
+			}},
+		}}},
+	}
+	sim, err := New(state, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Step(swagger.DungeonsandtrollsCommandsForMonsters{Commands: map[string]swagger.DungeonsandtrollsCommandsBatch{
+		"monster-1": {Skill: &swagger.DungeonsandtrollsSkillUse{SkillId: "aura"}},
+	}})
+	var effects []swagger.DungeonsandtrollsEffect
+	for _, object := range sim.State.Map_.Levels[0].Objects {
+		if *object.Position == position {
+			effects = object.Effects
+		}
+	}
+	if len(effects) != 1 || effects[0].Effects.Life != 2 {
+		t.Errorf("expected ground effect with the caster effects, got %+v", effects)
+	}
+}
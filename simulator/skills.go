@@ -0,0 +1,426 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+const outOfCombatTurns = int32(2)
+
+func (s *Simulator) useSkill(caster *actor, use swagger.DungeonsandtrollsSkillUse) {
+	skill, found := findSkill(caster.skills(), use.SkillId)
+	if !found {
+		s.addEvent(swagger.ERROR__DungeonsandtrollsEventType, caster, fmt.Sprintf("unknown skill %q", use.SkillId))
+		return
+	}
+	fillSkill(&skill)
+	casterAttrs := *caster.attributes()
+	value := func(attrs *swagger.DungeonsandtrollsAttributes) float32 {
+		return attributesValue(casterAttrs, attrs)
+	}
+
+	if !hasResources(casterAttrs, *skill.Cost) {
+		s.addEvent(swagger.ERROR__DungeonsandtrollsEventType, caster, "not enough resources for "+skill.Name)
+		return
+	}
+	if skill.Flags.RequiresOutOfCombat && *caster.lastDamageTaken() <= outOfCombatTurns {
+		s.addEvent(swagger.ERROR__DungeonsandtrollsEventType, caster, skill.Name+" requires out of combat")
+		return
+	}
+
+	targetPosition := caster.position
+	var target *actor
+	switch *skill.Target {
+	case swagger.CHARACTER_SkillTarget:
+		target = s.actorById(use.TargetId)
+		if target == nil || target.level != caster.level {
+			s.addEvent(swagger.ERROR__DungeonsandtrollsEventType, caster, fmt.Sprintf("unknown target %q", use.TargetId))
+			return
+		}
+		targetPosition = target.position
+	case swagger.POSITION_SkillTarget:
+		if use.Position == nil {
+			s.addEvent(swagger.ERROR__DungeonsandtrollsEventType, caster, skill.Name+" requires position")
+			return
+		}
+		targetPosition = *use.Position
+	}
+	if manhattanDistance(caster.position, targetPosition) > int32(value(skill.Range_)) {
+		s.addEvent(swagger.ERROR__DungeonsandtrollsEventType, caster, skill.Name+": target out of range")
+		return
+	}
+	if skill.Flags.RequiresLineOfSight && !s.lineOfSight(caster.level, caster.position, targetPosition) {
+		s.addEvent(swagger.ERROR__DungeonsandtrollsEventType, caster, skill.Name+": target not in line of sight")
+		return
+	}
+
+	// Pay
+	attrs := caster.attributes()
+	attrs.Life -= skill.Cost.Life
+	attrs.Stamina -= skill.Cost.Stamina
+	attrs.Mana -= skill.Cost.Mana
+
+	s.addEvent(swagger.SKILL_DungeonsandtrollsEventType, caster, skill.Name)
+	s.events[len(s.events)-1].SkillName = skill.Name
+
+	duration := int32(value(skill.Duration))
+	radius := int32(value(skill.Radius))
+	damage := value(skill.DamageAmount)
+
+	// Caster effects
+	s.applySkillEffect(caster, caster, skill.CasterEffects, &skill, casterAttrs, duration, 0)
+	if skill.CasterEffects.Flags.Movement && s.isFree(caster.level, targetPosition) {
+		caster.position = targetPosition
+		s.addEvent(swagger.MOVE_DungeonsandtrollsEventType, caster, "")
+	}
+	if skill.CasterEffects.Flags.GroundEffect {
+		s.addGroundEffect(caster.level, caster.position, radius, skill.CasterEffects, &skill, casterAttrs, duration, damage)
+		return
+	}
+
+	// Target effects
+	if skill.TargetEffects.Flags.GroundEffect {
+		s.addGroundEffect(caster.level, targetPosition, radius, skill.TargetEffects, &skill, casterAttrs, duration, damage)
+		return
+	}
+	targets := []*actor{}
+	if target != nil && radius <= 0 {
+		targets = append(targets, target)
+	} else if *skill.Target != swagger.NONE_SkillTarget || radius > 0 {
+		for _, t := range s.actorsInRadius(caster.level, targetPosition, radius) {
+			if t == caster && *skill.Target == swagger.NONE_SkillTarget {
+				continue
+			}
+			targets = append(targets, t)
+		}
+	}
+	for _, t := range targets {
+		s.applySkillEffect(caster, t, skill.TargetEffects, &skill, casterAttrs, duration, damage)
+		if skill.TargetEffects.Flags.Knockback {
+			s.push(t, caster.position, 1)
+		}
+		if skill.TargetEffects.Flags.Movement {
+			s.pull(t, caster.position)
+		}
+	}
+	for _, summon := range skill.TargetEffects.Summons {
+		s.summon(caster, targetPosition, summon)
+	}
+}
+
+// applySkillEffect applies attributes, damage and stun of a skill effect to the target
+func (s *Simulator) applySkillEffect(caster, target *actor, effect *swagger.DungeonsandtrollsSkillEffect, skill *swagger.DungeonsandtrollsSkill, casterAttrs swagger.DungeonsandtrollsAttributes, duration int32, damage float32) {
+	if effect.Attributes == nil {
+		effect.Attributes = &swagger.DungeonsandtrollsSkillAttributes{}
+	}
+	gains := skillAttributesValue(casterAttrs, *effect.Attributes)
+	if duration > 0 {
+		newEffect := swagger.DungeonsandtrollsEffect{
+			DamageAmount: damage,
+			DamageType:   skill.DamageType,
+			Effects:      &gains,
+			Duration:     duration,
+			CasterId:     caster.id,
+		}
+		addBuffs(target.attributes(), gains, 1)
+		*target.effects() = append(*target.effects(), newEffect)
+	} else {
+		applyVitals(target.attributes(), gains)
+		s.damage(caster, target, damage, *skill.DamageType)
+	}
+	if effect.Flags.Stun && !target.stun().IsImmune {
+		target.stun().IsStunned = true
+	}
+}
+
+// applyEffects applies character and ground effects for one tick and expires them
+func (s *Simulator) applyEffects() {
+	for _, a := range s.actors {
+		if !a.alive() {
+			continue
+		}
+		effects := *a.effects()
+		remaining := effects[:0]
+		for _, effect := range effects {
+			if effect.Effects == nil {
+				effect.Effects = &swagger.DungeonsandtrollsAttributes{}
+			}
+			applyVitals(a.attributes(), *effect.Effects)
+			s.damageFromEffect(a, effect)
+			effect.Duration--
+			if effect.Duration > 0 {
+				remaining = append(remaining, effect)
+			} else {
+				addBuffs(a.attributes(), *effect.Effects, -1)
+			}
+		}
+		*a.effects() = remaining
+	}
+	for l := range s.State.Map_.Levels {
+		level := &s.State.Map_.Levels[l]
+		for o := range level.Objects {
+			object := &level.Objects[o]
+			if len(object.Effects) == 0 {
+				continue
+			}
+			remaining := object.Effects[:0]
+			for _, effect := range object.Effects {
+				for _, a := range s.actorsAt(level.Level, *object.Position) {
+					if effect.Effects != nil {
+						applyVitals(a.attributes(), *effect.Effects)
+					}
+					s.damageFromEffect(a, effect)
+				}
+				effect.Duration--
+				if effect.Duration > 0 {
+					remaining = append(remaining, effect)
+				}
+			}
+			object.Effects = remaining
+		}
+	}
+}
+
+func (s *Simulator) damageFromEffect(target *actor, effect swagger.DungeonsandtrollsEffect) {
+	if effect.DamageAmount == 0 {
+		return
+	}
+	damageType := swagger.NONE_DungeonsandtrollsDamageType
+	if effect.DamageType != nil {
+		damageType = *effect.DamageType
+	}
+	caster := s.actorById(effect.CasterId)
+	if caster == nil {
+		caster = target
+	}
+	s.damage(caster, target, effect.DamageAmount, damageType)
+}
+
+// Same formula as the bots use in calculateDamage()
+func (s *Simulator) damage(caster, target *actor, amount float32, damageType swagger.DungeonsandtrollsDamageType) {
+	if amount <= 0 {
+		return
+	}
+	resist := resistForDamageType(*target.attributes(), damageType)
+	dealt := float32(float64(amount*10) / (10 + math.Max(float64(resist), -5)))
+	target.attributes().Life -= dealt
+	*target.lastDamageTaken() = 0
+	coords := target.coordinates()
+	eventType := swagger.DAMAGE_DungeonsandtrollsEventType
+	s.events = append(s.events, swagger.DungeonsandtrollsEvent{
+		Type_:       &eventType,
+		Message:     fmt.Sprintf("%s hit %s for %.1f", caster.name(), target.name(), dealt),
+		Coordinates: &coords,
+		Damage:      dealt,
+		PlayerId:    caster.id,
+	})
+}
+
+func (s *Simulator) addGroundEffect(level int32, center swagger.DungeonsandtrollsPosition, radius int32, effect *swagger.DungeonsandtrollsSkillEffect, skill *swagger.DungeonsandtrollsSkill, casterAttrs swagger.DungeonsandtrollsAttributes, duration int32, damage float32) {
+	if effect.Attributes == nil {
+		effect.Attributes = &swagger.DungeonsandtrollsSkillAttributes{}
+	}
+	if duration <= 0 {
+		duration = 1
+	}
+	gains := skillAttributesValue(casterAttrs, *effect.Attributes)
+	if radius < 0 {
+		radius = 0
+	}
+	for y := center.PositionY - radius; y <= center.PositionY+radius; y++ {
+		for x := center.PositionX - radius; x <= center.PositionX+radius; x++ {
+			pos := swagger.DungeonsandtrollsPosition{PositionX: x, PositionY: y}
+			if euclidDistance(pos, center) > radius || !s.isFree(level, pos) {
+				continue
+			}
+			object := s.objectAt(level, pos, true)
+			gainsCopy := gains
+			object.Effects = append(object.Effects, swagger.DungeonsandtrollsEffect{
+				DamageAmount: damage,
+				DamageType:   skill.DamageType,
+				Effects:      &gainsCopy,
+				Duration:     duration,
+			})
+		}
+	}
+}
+
+// push moves the target away from the source (stops at obstacles)
+func (s *Simulator) push(target *actor, source swagger.DungeonsandtrollsPosition, distance int) {
+	dx := sign(target.position.PositionX - source.PositionX)
+	dy := sign(target.position.PositionY - source.PositionY)
+	if dx == 0 && dy == 0 {
+		return
+	}
+	for i := 0; i < distance; i++ {
+		next := swagger.DungeonsandtrollsPosition{
+			PositionX: target.position.PositionX + dx,
+			PositionY: target.position.PositionY + dy,
+		}
+		if !s.isFree(target.level, next) {
+			return
+		}
+		target.position = next
+	}
+}
+
+// pull moves the target next to the destination
+func (s *Simulator) pull(target *actor, destination swagger.DungeonsandtrollsPosition) {
+	for manhattanDistance(target.position, destination) > 1 {
+		next, found := s.nextStep(target.level, target.position, destination)
+		if !found || next == destination {
+			return
+		}
+		target.position = next
+	}
+}
+
+func (s *Simulator) summon(caster *actor, position swagger.DungeonsandtrollsPosition, summon swagger.DungeonsandtrollsDroppable) {
+	if summon.Monster == nil {
+		return
+	}
+	monster := *summon.Monster
+	if monster.Id == "" {
+		monster.Id = fmt.Sprintf("summon-%d-%d", s.State.Tick, s.Rand.Int63())
+	}
+	if monster.Faction == "" {
+		monster.Faction = caster.faction()
+	}
+	if monster.Attributes == nil {
+		monster.Attributes = &swagger.DungeonsandtrollsAttributes{Life: 1}
+	}
+	s.actors = append(s.actors, &actor{
+		id:       monster.Id,
+		level:    caster.level,
+		position: position,
+		monster:  &monster,
+	})
+}
+
+func findSkill(skills []swagger.DungeonsandtrollsSkill, id string) (swagger.DungeonsandtrollsSkill, bool) {
+	for _, skill := range skills {
+		if skill.Id == id {
+			return skill, true
+		}
+	}
+	return swagger.DungeonsandtrollsSkill{}, false
+}
+
+// fillSkill replaces nil pointers so the skill can be applied without checks
+func fillSkill(skill *swagger.DungeonsandtrollsSkill) {
+	if skill.Target == nil {
+		target := swagger.NONE_SkillTarget
+		skill.Target = &target
+	}
+	if skill.DamageType == nil {
+		damageType := swagger.NONE_DungeonsandtrollsDamageType
+		skill.DamageType = &damageType
+	}
+	for _, attrs := range []**swagger.DungeonsandtrollsAttributes{&skill.Cost, &skill.Range_, &skill.Radius, &skill.Duration, &skill.DamageAmount} {
+		if *attrs == nil {
+			*attrs = &swagger.DungeonsandtrollsAttributes{}
+		}
+	}
+	for _, effect := range []**swagger.DungeonsandtrollsSkillEffect{&skill.CasterEffects, &skill.TargetEffects} {
+		if *effect == nil {
+			*effect = &swagger.DungeonsandtrollsSkillEffect{}
+		}
+		if (*effect).Flags == nil {
+			(*effect).Flags = &swagger.DungeonsandtrollsSkillSpecificFlags{}
+		}
+	}
+	if skill.Flags == nil {
+		skill.Flags = &swagger.DungeonsandtrollsSkillGenericFlags{}
+	}
+}
+
+func hasResources(attrs, cost swagger.DungeonsandtrollsAttributes) bool {
+	return attrs.Life >= cost.Life && attrs.Stamina >= cost.Stamina && attrs.Mana >= cost.Mana
+}
+
+// Same formula as the bots use in calculateAttributesValue()
+func attributesValue(myAttrs swagger.DungeonsandtrollsAttributes, attrs *swagger.DungeonsandtrollsAttributes) float32 {
+	if attrs == nil {
+		return 0
+	}
+	return myAttrs.Strength*attrs.Strength +
+		myAttrs.Dexterity*attrs.Dexterity +
+		myAttrs.Intelligence*attrs.Intelligence +
+		myAttrs.Willpower*attrs.Willpower +
+		myAttrs.Constitution*attrs.Constitution +
+		myAttrs.SlashResist*attrs.SlashResist +
+		myAttrs.PierceResist*attrs.PierceResist +
+		myAttrs.FireResist*attrs.FireResist +
+		myAttrs.PoisonResist*attrs.PoisonResist +
+		myAttrs.ElectricResist*attrs.ElectricResist +
+		myAttrs.Life*attrs.Life +
+		myAttrs.Stamina*attrs.Stamina +
+		myAttrs.Mana*attrs.Mana +
+		attrs.Constant
+}
+
+func skillAttributesValue(casterAttrs swagger.DungeonsandtrollsAttributes, attrs swagger.DungeonsandtrollsSkillAttributes) swagger.DungeonsandtrollsAttributes {
+	return swagger.DungeonsandtrollsAttributes{
+		Strength:       attributesValue(casterAttrs, attrs.Strength),
+		Dexterity:      attributesValue(casterAttrs, attrs.Dexterity),
+		Intelligence:   attributesValue(casterAttrs, attrs.Intelligence),
+		Willpower:      attributesValue(casterAttrs, attrs.Willpower),
+		Constitution:   attributesValue(casterAttrs, attrs.Constitution),
+		SlashResist:    attributesValue(casterAttrs, attrs.SlashResist),
+		PierceResist:   attributesValue(casterAttrs, attrs.PierceResist),
+		FireResist:     attributesValue(casterAttrs, attrs.FireResist),
+		PoisonResist:   attributesValue(casterAttrs, attrs.PoisonResist),
+		ElectricResist: attributesValue(casterAttrs, attrs.ElectricResist),
+		Life:           attributesValue(casterAttrs, attrs.Life),
+		Stamina:        attributesValue(casterAttrs, attrs.Stamina),
+		Mana:           attributesValue(casterAttrs, attrs.Mana),
+	}
+}
+
+func applyVitals(attrs *swagger.DungeonsandtrollsAttributes, gains swagger.DungeonsandtrollsAttributes) {
+	attrs.Life += gains.Life
+	attrs.Stamina += gains.Stamina
+	attrs.Mana += gains.Mana
+}
+
+// addBuffs adds (sign 1) or removes (sign -1) non-vital attributes
+func addBuffs(attrs *swagger.DungeonsandtrollsAttributes, gains swagger.DungeonsandtrollsAttributes, sign float32) {
+	attrs.Strength += sign * gains.Strength
+	attrs.Dexterity += sign * gains.Dexterity
+	attrs.Intelligence += sign * gains.Intelligence
+	attrs.Willpower += sign * gains.Willpower
+	attrs.Constitution += sign * gains.Constitution
+	attrs.SlashResist += sign * gains.SlashResist
+	attrs.PierceResist += sign * gains.PierceResist
+	attrs.FireResist += sign * gains.FireResist
+	attrs.PoisonResist += sign * gains.PoisonResist
+	attrs.ElectricResist += sign * gains.ElectricResist
+}
+
+func resistForDamageType(attrs swagger.DungeonsandtrollsAttributes, damageType swagger.DungeonsandtrollsDamageType) float32 {
+	switch damageType {
+	case swagger.SLASH_DungeonsandtrollsDamageType:
+		return attrs.SlashResist
+	case swagger.PIERCE_DungeonsandtrollsDamageType:
+		return attrs.PierceResist
+	case swagger.FIRE_DungeonsandtrollsDamageType:
+		return attrs.FireResist
+	case swagger.POISON_DungeonsandtrollsDamageType:
+		return attrs.PoisonResist
+	case swagger.ELECTRIC_DungeonsandtrollsDamageType:
+		return attrs.ElectricResist
+	}
+	return 0
+}
+
+func sign(x int32) int32 {
+	if x > 0 {
+		return 1
+	}
+	if x < 0 {
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,382 @@
+package simulator
+
+import (
+	"math"
+
+	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+)
+
+// actor is a monster or a player taken out of the map objects for the duration of a tick
+type actor struct {
+	id string
+	// Level number (DungeonsandtrollsLevel.Level), not the index in Map_.Levels
+	level    int32
+	position swagger.DungeonsandtrollsPosition
+	monster  *swagger.DungeonsandtrollsMonster
+	player   *swagger.DungeonsandtrollsCharacter
+	dead     bool
+}
+
+// Character is a read-only view of an actor passed to player scripts
+type Character struct {
+	Id       string
+	Level    int32
+	Position swagger.DungeonsandtrollsPosition
+	Player   *swagger.DungeonsandtrollsCharacter
+	Monster  *swagger.DungeonsandtrollsMonster
+}
+
+func (a *actor) isPlayer() bool {
+	return a.player != nil
+}
+
+func (a *actor) name() string {
+	if a.isPlayer() {
+		return a.player.Name
+	}
+	return a.monster.Name
+}
+
+func (a *actor) faction() string {
+	if a.isPlayer() {
+		return "player"
+	}
+	return a.monster.Faction
+}
+
+func (a *actor) attributes() *swagger.DungeonsandtrollsAttributes {
+	if a.isPlayer() {
+		if a.player.Attributes == nil {
+			a.player.Attributes = &swagger.DungeonsandtrollsAttributes{}
+		}
+		return a.player.Attributes
+	}
+	if a.monster.Attributes == nil {
+		a.monster.Attributes = &swagger.DungeonsandtrollsAttributes{}
+	}
+	return a.monster.Attributes
+}
+
+func (a *actor) maxAttributes() *swagger.DungeonsandtrollsAttributes {
+	if a.isPlayer() {
+		if a.player.MaxAttributes == nil {
+			a.player.MaxAttributes = &swagger.DungeonsandtrollsAttributes{}
+		}
+		return a.player.MaxAttributes
+	}
+	if a.monster.MaxAttributes == nil {
+		a.monster.MaxAttributes = &swagger.DungeonsandtrollsAttributes{}
+	}
+	return a.monster.MaxAttributes
+}
+
+func (a *actor) stun() *swagger.DungeonsandtrollsStun {
+	if a.isPlayer() {
+		if a.player.Stun == nil {
+			a.player.Stun = &swagger.DungeonsandtrollsStun{}
+		}
+		return a.player.Stun
+	}
+	if a.monster.Stun == nil {
+		a.monster.Stun = &swagger.DungeonsandtrollsStun{}
+	}
+	return a.monster.Stun
+}
+
+func (a *actor) effects() *[]swagger.DungeonsandtrollsEffect {
+	if a.isPlayer() {
+		return &a.player.Effects
+	}
+	return &a.monster.Effects
+}
+
+func (a *actor) lastDamageTaken() *int32 {
+	if a.isPlayer() {
+		return &a.player.LastDamageTaken
+	}
+	return &a.monster.LastDamageTaken
+}
+
+func (a *actor) skills() []swagger.DungeonsandtrollsSkill {
+	var items []swagger.DungeonsandtrollsItem
+	if a.isPlayer() {
+		items = a.player.Equip
+	} else {
+		items = a.monster.EquippedItems
+	}
+	skills := []swagger.DungeonsandtrollsSkill{}
+	for _, item := range items {
+		skills = append(skills, item.Skills...)
+	}
+	return skills
+}
+
+func (a *actor) alive() bool {
+	return a.attributes().Life > 0
+}
+
+func (a *actor) coordinates() swagger.DungeonsandtrollsCoordinates {
+	return swagger.DungeonsandtrollsCoordinates{
+		Level:     a.level,
+		PositionX: a.position.PositionX,
+		PositionY: a.position.PositionY,
+	}
+}
+
+func (a *actor) view() Character {
+	return Character{
+		Id:       a.id,
+		Level:    a.level,
+		Position: a.position,
+		Player:   a.player,
+		Monster:  a.monster,
+	}
+}
+
+// afterTick updates derived values (life percentage, out of combat counter, vitals bounds)
+func (a *actor) afterTick() {
+	*a.lastDamageTaken() += 1
+	attrs := a.attributes()
+	maxAttrs := a.maxAttributes()
+	attrs.Life = clampVital(attrs.Life, maxAttrs.Life)
+	attrs.Stamina = clampVital(attrs.Stamina, maxAttrs.Stamina)
+	attrs.Mana = clampVital(attrs.Mana, maxAttrs.Mana)
+	if a.isPlayer() {
+		a.player.Coordinates = &swagger.DungeonsandtrollsCoordinates{
+			Level:     a.level,
+			PositionX: a.position.PositionX,
+			PositionY: a.position.PositionY,
+		}
+		return
+	}
+	if maxAttrs.Life > 0 {
+		a.monster.LifePercentage = attrs.Life / maxAttrs.Life
+	}
+}
+
+func clampVital(value, maxValue float32) float32 {
+	if maxValue > 0 && value > maxValue {
+		return maxValue
+	}
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
+// Takes all characters out of map objects so they can be updated freely
+func (s *Simulator) extractActors() {
+	s.actors = []*actor{}
+	for l := range s.State.Map_.Levels {
+		level := &s.State.Map_.Levels[l]
+		for o := range level.Objects {
+			object := &level.Objects[o]
+			for i := range object.Monsters {
+				monster := object.Monsters[i]
+				s.actors = append(s.actors, &actor{
+					id:       monster.Id,
+					level:    level.Level,
+					position: *object.Position,
+					monster:  &monster,
+				})
+			}
+			for i := range object.Players {
+				player := object.Players[i]
+				s.actors = append(s.actors, &actor{
+					id:       player.Id,
+					level:    level.Level,
+					position: *object.Position,
+					player:   &player,
+				})
+			}
+			object.Monsters = nil
+			object.Players = nil
+		}
+	}
+	for _, a := range s.actors {
+		a.dead = !a.alive()
+	}
+}
+
+// Puts living characters back into map objects and drops empty objects
+func (s *Simulator) injectActors() {
+	for _, a := range s.actors {
+		if !a.alive() {
+			continue
+		}
+		object := s.objectAt(a.level, a.position, true)
+		if a.isPlayer() {
+			object.Players = append(object.Players, *a.player)
+		} else {
+			object.Monsters = append(object.Monsters, *a.monster)
+		}
+	}
+	for l := range s.State.Map_.Levels {
+		level := &s.State.Map_.Levels[l]
+		objects := level.Objects[:0]
+		for _, object := range level.Objects {
+			if isEmptyObject(object) {
+				continue
+			}
+			objects = append(objects, object)
+		}
+		level.Objects = objects
+	}
+	s.actors = nil
+}
+
+func isEmptyObject(object swagger.DungeonsandtrollsMapObjects) bool {
+	return object.IsFree && !object.IsStairs && !object.IsSpawn && !object.IsDoor && object.Portal == nil &&
+		len(object.Monsters) == 0 && len(object.Players) == 0 && len(object.Effects) == 0 &&
+		len(object.Items) == 0 && len(object.Decorations) == 0
+}
+
+// objectAt returns map objects on the position (creates a free tile if create is set)
+func (s *Simulator) objectAt(level int32, position swagger.DungeonsandtrollsPosition, create bool) *swagger.DungeonsandtrollsMapObjects {
+	lvl := s.levelByNumber(level)
+	if lvl == nil {
+		return nil
+	}
+	for i := range lvl.Objects {
+		if lvl.Objects[i].Position != nil && *lvl.Objects[i].Position == position {
+			return &lvl.Objects[i]
+		}
+	}
+	if !create {
+		return nil
+	}
+	pos := position
+	lvl.Objects = append(lvl.Objects, swagger.DungeonsandtrollsMapObjects{
+		Position: &pos,
+		IsFree:   true,
+	})
+	return &lvl.Objects[len(lvl.Objects)-1]
+}
+
+// levelByNumber returns nil for levels missing in the map
+func (s *Simulator) levelByNumber(level int32) *swagger.DungeonsandtrollsLevel {
+	for l := range s.State.Map_.Levels {
+		if s.State.Map_.Levels[l].Level == level {
+			return &s.State.Map_.Levels[l]
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) inBounds(level int32, position swagger.DungeonsandtrollsPosition) bool {
+	lvl := s.levelByNumber(level)
+	return lvl != nil && position.PositionX >= 0 && position.PositionX < lvl.Width && position.PositionY >= 0 && position.PositionY < lvl.Height
+}
+
+// Same rule as the bots use: tiles without data are free
+func (s *Simulator) isFree(level int32, position swagger.DungeonsandtrollsPosition) bool {
+	if !s.inBounds(level, position) {
+		return false
+	}
+	object := s.objectAt(level, position, false)
+	return object == nil || object.IsFree
+}
+
+func (s *Simulator) actorById(id string) *actor {
+	for _, a := range s.actors {
+		if a.id == id && a.alive() {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) actorsAt(level int32, position swagger.DungeonsandtrollsPosition) []*actor {
+	actors := []*actor{}
+	for _, a := range s.actors {
+		if a.level == level && a.position == position && a.alive() {
+			actors = append(actors, a)
+		}
+	}
+	return actors
+}
+
+func (s *Simulator) actorsInRadius(level int32, position swagger.DungeonsandtrollsPosition, radius int32) []*actor {
+	actors := []*actor{}
+	for _, a := range s.actors {
+		if a.level == level && a.alive() && euclidDistance(a.position, position) <= radius {
+			actors = append(actors, a)
+		}
+	}
+	return actors
+}
+
+// moveTowards moves the actor one tile along the shortest path
+func (s *Simulator) moveTowards(a *actor, target swagger.DungeonsandtrollsPosition) {
+	if a.position == target {
+		return
+	}
+	next, found := s.nextStep(a.level, a.position, target)
+	if !found {
+		return
+	}
+	a.position = next
+	s.addEvent(swagger.MOVE_DungeonsandtrollsEventType, a, "")
+}
+
+func (s *Simulator) nextStep(level int32, from, to swagger.DungeonsandtrollsPosition) (swagger.DungeonsandtrollsPosition, bool) {
+	if !s.isFree(level, to) {
+		return from, false
+	}
+	// BFS from the target so the first visited neighbour of `from` is the next step
+	visited := map[swagger.DungeonsandtrollsPosition]bool{to: true}
+	queue := []swagger.DungeonsandtrollsPosition{to}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, neighbor := range neighbors(node) {
+			if neighbor == from {
+				return node, true
+			}
+			if visited[neighbor] || !s.isFree(level, neighbor) {
+				continue
+			}
+			visited[neighbor] = true
+			queue = append(queue, neighbor)
+		}
+	}
+	return from, false
+}
+
+// lineOfSight walks the cells between the two positions and fails on the first non-free cell
+func (s *Simulator) lineOfSight(level int32, from, to swagger.DungeonsandtrollsPosition) bool {
+	x0, y0 := float64(from.PositionX)+0.5, float64(from.PositionY)+0.5
+	x1, y1 := float64(to.PositionX)+0.5, float64(to.PositionY)+0.5
+	steps := int(math.Ceil(math.Max(math.Abs(x1-x0), math.Abs(y1-y0)) * 2))
+	for i := 1; i < steps; i++ {
+		t := float64(i) / float64(steps)
+		pos := swagger.DungeonsandtrollsPosition{
+			PositionX: int32(x0 + (x1-x0)*t),
+			PositionY: int32(y0 + (y1-y0)*t),
+		}
+		if pos == from || pos == to {
+			continue
+		}
+		if !s.isFree(level, pos) {
+			return false
+		}
+	}
+	return true
+}
+
+func neighbors(pos swagger.DungeonsandtrollsPosition) []swagger.DungeonsandtrollsPosition {
+	return []swagger.DungeonsandtrollsPosition{
+		{PositionX: pos.PositionX - 1, PositionY: pos.PositionY},
+		{PositionX: pos.PositionX + 1, PositionY: pos.PositionY},
+		{PositionX: pos.PositionX, PositionY: pos.PositionY - 1},
+		{PositionX: pos.PositionX, PositionY: pos.PositionY + 1},
+	}
+}
+
+func manhattanDistance(a, b swagger.DungeonsandtrollsPosition) int32 {
+	return int32(math.Abs(float64(a.PositionX-b.PositionX)) + math.Abs(float64(a.PositionY-b.PositionY)))
+}
+
+func euclidDistance(a, b swagger.DungeonsandtrollsPosition) int32 {
+	return int32(math.Floor(math.Sqrt(math.Pow(float64(a.PositionX-b.PositionX), 2) + math.Pow(float64(a.PositionY-b.PositionY), 2))))
+}
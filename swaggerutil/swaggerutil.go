@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	swagger "github.com/gdg-garage/dungeons-and-trolls-go-client"
+	"github.com/gdg-garage/dungeons-and-trolls-monsters-ai/metrics"
 	"go.uber.org/zap"
 )
 
@@ -24,6 +25,7 @@ func LogSuccess(logger *zap.SugaredLogger, httpResp *http.Response, method strin
 }
 
 func LogError(logger *zap.SugaredLogger, err error, httpResp *http.Response, method string, request interface{}) {
+	metrics.CountAPIError(method, httpResp)
 	loggerWErr := logger.With(
 		zap.Error(err),
 		"method", method,